@@ -4,7 +4,13 @@ export default {
   // refreshToken: () => request.post('/auth/refreshToken', null, { noNeedTip: true }),
   report: () => request.post('/report'), // 上报用户信息
   getHomeInfo: () => request.get('/home'), // 获取首页信息
-  login: ({ username, password }) => request.post('/login', { username, password }, { noNeedToken: true }),
+  login: data => request.post('/login', data, { noNeedToken: true }),
+  getCaptcha: () => request.get('/captcha', { noNeedToken: true }), // 图形验证码
+  loginTwoFactor: data => request.post('/login/2fa', data, { noNeedToken: true }), // 两步验证登录
+  loginTwoFactorSetup: challenge => request.post('/login/2fa/setup', { challenge }, { noNeedToken: true }), // 登录时设置两步验证
+  getOAuthProviders: () => request.get('/oauth/providers', { noNeedToken: true }), // 第三方登录方式
+  oauthAuthorize: (provider, redirect) => request.get(`/oauth/${provider}/authorize`, { params: { redirect }, noNeedToken: true }), // 发起第三方登录
+  oauthLogin: code => request.post('/oauth/login', { code }, { noNeedToken: true }), // 使用回调中的登录码完成第三方登录
   logout: () => request.get('/logout'),
 
   // 文章相关接口
@@ -14,7 +20,7 @@ export default {
   deleteArticle: (data = []) => request.delete('/article', { data }), // 物理删除
   softDeleteArticle: (ids, is_delete) => request.put('/article/soft-delete', { ids, is_delete }), // 软删除
   updateArticleTop: (id, is_top) => request.put('/article/top', { id, is_top }), // 修改文章置顶
-  exportArticles: (data = []) => request.post('/article/export', data), // 导出文章
+  exportArticles: (ids = [], withImages = false) => request.post('/article/export', ids, { params: { with_images: withImages }, responseType: 'blob' }), // 导出文章 (ZIP)
   importArticles: data => request.post('/article/import', data), // 导入文章
 
   // 分类相关接口
@@ -51,6 +57,14 @@ export default {
   getUserInfo: () => request.get('/user/info'),
   updateCurrent: data => request.put('/user/current', data), // 更新当前用户信息
   updateCurrentPassword: data => request.put('/user/current/password', data), // 修改当前用户密码
+  getTwoFactor: () => request.get('/user/2fa'), // 两步验证状态
+  setupTwoFactor: () => request.post('/user/2fa/setup'), // 生成两步验证密钥
+  enableTwoFactor: code => request.post('/user/2fa/enable', { code }), // 开启两步验证
+  disableTwoFactor: data => request.post('/user/2fa/disable', data), // 关闭两步验证
+  regenerateRecoveryCodes: code => request.post('/user/2fa/recovery-codes', { code }), // 重新生成恢复码
+  getUserOAuth: () => request.get('/user/oauth'), // 第三方账号绑定情况
+  linkOAuth: (provider, redirect) => request.post(`/user/oauth/${provider}`, null, { params: { redirect } }), // 绑定第三方账号
+  unlinkOAuth: provider => request.delete(`/user/oauth/${provider}`), // 解绑第三方账号
   getUsers: (params = {}) => request.get('/user/list', { params }),
   updateUser: data => request.put('/user', data),
   updateUserDisable: (id, is_disable) => request.put('/user/disable', {
@@ -59,6 +73,7 @@ export default {
   }),
   getOnlineUsers: (params = { keyword: '' }) => request.get('/user/online', { params }), // 在线用户列表
   forceOfflineUser: id => request.post(`/user/offline/${id}`), // 强制离线
+  unlockUser: id => request.post(`/user/unlock/${id}`), // 解除登录锁定
 
   // 博客设置相关接口
   getConfig: () => request.get('/config'),
@@ -81,11 +96,16 @@ export default {
   deleteResource: id => request.delete(`/resource/${id}`),
   updateResourceAnonymous: data => request.put('/resource/anonymous', data),
   getResourceOption: () => request.get('/resource/option'),
+  syncResources: () => request.post('/resource/sync'),
   // 角色
   getRoles: (params = {}) => request.get('/role/list', { params }),
   saveOrUpdateRole: data => request.post('/role', data),
   deleteRole: (data = []) => request.delete('/role', { data }),
   getRoleOption: () => request.get('/role/option'),
+  explainPermission: (params = {}) => request.get('/role/explain', { params }),
+  simulateMenu: (params = {}) => request.get('/role/menu', { params }),
+  exportRBAC: () => request.get('/role/export'), // 导出权限配置 (YAML)
+  importRBAC: (data, params = {}) => request.post('/role/import', data, { params }), // 导入权限配置, params: dry_run, prune
 
   // 页面相关接口
   getPages: () => request.get('/page/list'),
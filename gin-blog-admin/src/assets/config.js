@@ -17,6 +17,8 @@ export const loginTypeMap = {
   1: { name: '邮箱', tag: 'success' },
   2: { name: 'QQ', tag: 'info' },
   3: { name: '微博', tag: 'warning' },
+  4: { name: 'GitHub', tag: 'default' },
+  5: { name: 'OIDC', tag: 'primary' },
 }
 
 // 文章类型选项
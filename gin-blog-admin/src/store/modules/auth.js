@@ -7,14 +7,16 @@ import api from '@/api'
 export const useAuthStore = defineStore('auth', {
   persist: {
     key: 'gvb_admin_auth',
-    paths: ['token'],
+    paths: ['token', 'refreshToken'],
   },
   state: () => ({
     token: null,
+    refreshToken: null, // 用于 token 过期后获取新的 token, 每次刷新都会更换
   }),
   actions: {
-    setToken(token) {
+    setToken(token, refreshToken) {
       this.token = token
+      this.refreshToken = refreshToken
     },
     toLogin() {
       const currentRoute = unref(router.currentRoute)
@@ -34,16 +34,60 @@ request.interceptors.request.use(
   },
 )
 
+// 正在进行的 token 刷新, 多个请求同时过期时只刷新一次
+let refreshing = null
+
+/**
+ * 使用 refresh token 获取新的 token
+ * 不经过响应拦截器, 刷新失败时返回 rejected Promise
+ */
+function refreshToken() {
+  const authStore = useAuthStore()
+  refreshing ??= axios.post('/refresh', { refresh_token: authStore.refreshToken }, {
+    baseURL: import.meta.env.VITE_BASE_API,
+    timeout: 12000,
+  })
+    .then(({ data: resp }) => {
+      if (resp.code !== 0) {
+        return Promise.reject(resp)
+      }
+      authStore.setToken(resp.data.token, resp.data.refresh_token)
+    })
+    .finally(() => refreshing = null)
+  return refreshing
+}
+
 // 响应拦截器
 request.interceptors.response.use(
   // 响应成功拦截
-  (response) => {
+  function onResponse(response) {
+    // 下载文件: 成功时直接返回 Blob, 失败时后端返回的是 JSON, 解析后按普通响应处理
+    if (response.config.responseType === 'blob' && response.data instanceof Blob) {
+      if (!response.data.type.includes('application/json')) {
+        return Promise.resolve(response.data)
+      }
+      return response.data.text().then(text => onResponse({ ...response, data: JSON.parse(text) }))
+    }
+
     // 业务信息：从响应中提取数据
     const responseData = response.data
     const { code, message, data } = responseData
 
     // 判断响应中的业务状态码，如果不等于 0 说明业务失败
     if (code !== 0) {  // 这里的 `0` 是后端约定的成功状态码
+      const authStore = useAuthStore() // 获取认证状态
+
+      // 如果返回的 code 为 1202，说明 Token 过期，使用 refresh token 刷新后重新发送请求，刷新失败时强制下线
+      if (code === 1202 && authStore.refreshToken && !response.config.isRetry) {
+        return refreshToken().then(
+          () => request({ ...response.config, isRetry: true }),
+          () => {
+            authStore.forceOffline()
+            return Promise.reject(responseData)
+          },
+        )
+      }
+
       // 如果存在 data，且 message 和 data 不相等，则拼接错误信息
       if (data && message !== data) {
         window.$message.error(`${message} ${data}`)  // 使用 UI 库弹出错误消息
@@ -54,16 +98,14 @@ request.interceptors.response.use(
       // 在控制台打印错误信息，便于调试
       console.error(responseData)
 
-      const authStore = useAuthStore() // 获取认证状态
-
       // 如果返回的 code 为 1201，则说明 Token 存在问题，跳转到登录页
       if (code === 1201) {
         authStore.toLogin() // 跳转到登录页面
         return
       }
 
-      // 如果返回的 code 为 1202、1203 或 1207，说明 Token 过期或者被强制下线，执行强制下线操作
-      if (code === 1202 || code === 1203 || code === 1207) {
+      // 如果返回的 code 为 1202、1203、1207 或 1211，说明 Token 过期、被强制下线或者已失效，执行强制下线操作
+      if (code === 1202 || code === 1203 || code === 1207 || code === 1211) {
         authStore.forceOffline() // 强制用户下线
         return
       }
@@ -2,8 +2,24 @@ import { baseRequest, request } from '@/utils/http'
 
 export default {
   login: (data = {}) => baseRequest.post('/login', data),
+  /** 图形验证码 */
+  getCaptcha: () => baseRequest.get('/captcha'),
+  /** 两步验证登录 */
+  loginTwoFactor: (data = {}) => baseRequest.post('/login/2fa', data),
+  /** 第三方登录方式 */
+  getOAuthProviders: () => baseRequest.get('/oauth/providers'),
+  /** 发起第三方登录, 返回授权地址 */
+  oauthAuthorize: (provider: string, redirect: string) => baseRequest.get(`/oauth/${provider}/authorize`, { params: { redirect } }),
+  /** 使用回调中的登录码完成第三方登录 */
+  oauthLogin: (code: string) => baseRequest.post('/oauth/login', { code }),
   register: (data = {}) => baseRequest.post('/register', data),
-  logout: () => baseRequest.get('/logout'),
+  /** 重新发送注册验证邮件 */
+  resendEmail: (data = {}) => baseRequest.post('/email/resend', data),
+  /** 申请重置密码 */
+  forgotPassword: (data = {}) => baseRequest.post('/password/forgot', data),
+  /** 使用邮件中的链接重置密码 */
+  resetPassword: (data = {}) => baseRequest.post('/password/reset', data),
+  logout: () => baseRequest.get('/logout', { needToken: true }),
   /** 发送验证码 */
   sendCode: params => baseRequest.get('/code', { params }),
 
@@ -16,7 +32,9 @@ export default {
   /** 首页文章列表 */
   getArticles: params => request.get('/article/list', { params }),
   /** 文章详情 */
-  getArticleDetail: id => request.get(`/article/${id}`),
+  getArticleDetail: (id, token = '') => request.get(`/article/${id}`, { headers: token ? { 'X-Article-Token': token } : {} }),
+  /** 使用密码解锁私密文章, 返回访问凭证 */
+  unlockArticle: (id, password) => request.post(`/article/${id}/unlock`, { password }),
   /** 文章归档 */
   getArchives: (params = {}) => request.get('/article/archive', { params }),
   /** 文章搜索 */
@@ -93,6 +93,14 @@ const basicRoutes = [
       title: '个人中心',
     },
   },
+  {
+    name: 'ResetPassword',
+    path: '/reset-password',
+    component: () => import('@/views/reset-password/index.vue'),
+    meta: {
+      title: '重置密码',
+    },
+  },
   {
     name: '404',
     path: '/404',
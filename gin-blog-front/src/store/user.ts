@@ -15,6 +15,7 @@ interface UserState {
     commentLikeSet: any[];
   };
   token: string | null;
+  refreshToken: string | null; // 用于 token 过期后获取新的 token, 每次刷新都会更换
 }
 
 export const useUserStore = defineStore('user', {
@@ -30,6 +31,7 @@ export const useUserStore = defineStore('user', {
       commentLikeSet: [],
     },
     token: null,
+    refreshToken: null,
   }),
   getters: {
     userId: state => state.userInfo.id ?? '',
@@ -42,8 +44,9 @@ export const useUserStore = defineStore('user', {
     commentLikeSet: state => state.userInfo.commentLikeSet || [],
   },
   actions: {
-    setToken(token:string) {
+    setToken(token: string, refreshToken: string) {
       this.token = token
+      this.refreshToken = refreshToken
     },
     resetLoginState() {
       this.$reset()
@@ -52,6 +52,29 @@ function requestFail(error: any) {
 
 
 
+// 正在进行的 token 刷新, 多个请求同时过期时只刷新一次
+let refreshing: Promise<void> | null = null
+
+/**
+ * 使用 refresh token 获取新的 token
+ * 不经过响应拦截器, 刷新失败时返回 rejected Promise
+ */
+function refreshToken() {
+    const userStore = useUserStore()
+    refreshing ??= axios.post('/refresh', { refresh_token: userStore.refreshToken }, {
+        baseURL: import.meta.env.VITE_API,
+        timeout: 12000,
+    })
+        .then(({ data: resp }) => {
+            if (resp.code !== 0) {
+                return Promise.reject(resp)
+            }
+            userStore.setToken(resp.data.token, resp.data.refresh_token)
+        })
+        .finally(() => refreshing = null)
+    return refreshing
+}
+
 /**
  * 响应成功拦截
  * @param {import('axios').AxiosResponse} response
@@ -61,9 +84,25 @@ function responseSuccess(response: any) {
     const { code, message } = responseData
     console.log(message)
     if (code !== 0) { // 与后端约定业务状态码
-        if (code === 1203) {
+        // token 过期, 使用 refresh token 刷新后重新发送请求
+        const userStore = useUserStore()
+        if (code === 1202 && userStore.refreshToken && !response.config.isRetry) {
+            return refreshToken().then(
+                () => {
+                    // 请求配置中已经有 Authorization, 需要替换为新的 token
+                    const config = { ...response.config, isRetry: true }
+                    config.headers.Authorization = `Bearer ${userStore.token}`
+                    return axios.request(config).then(responseSuccess)
+                },
+                () => {
+                    userStore.resetLoginState()
+                    ;(window as any).$message.error(message)
+                    return Promise.reject(responseData)
+                },
+            )
+        }
+        if (code === 1202 || code === 1203 || code === 1211) {
             // 移除 token
-            const userStore = useUserStore()
             userStore.resetLoginState()
         }
         (window as any).$message.error(message)
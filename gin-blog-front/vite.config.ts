@@ -31,6 +31,11 @@ export default defineConfig((configEnv) => {
           target: env.VITE_BACKEND_URL,
           changeOrigin: true,
         },
+        // robots.txt, sitemap 由后台生成
+        '^/(robots\\.txt|sitemap\\.xml|sitemap/)': {
+          target: env.VITE_BACKEND_URL,
+          changeOrigin: true,
+        },
       },
     },
     // https://cn.vitejs.dev/guide/api-javascript.html#build
@@ -23,6 +23,7 @@
                             </tr>
                             </tbody>
                         </table>
+                        <p>⏰&nbsp; 链接 15 分钟内有效，过期后可以在注册页面重新发送验证邮件。</p>
                         <p>🕹&nbsp; 激活后，您将完成访问！</p>
                         <p>💃&nbsp; 按钮没反应？尝试将此 URL 粘贴到您的浏览器中：<a class='long-url'>{{.URL}}</a></p>
                         <p>😉&nbsp; 我们期待着您的到来！</p>
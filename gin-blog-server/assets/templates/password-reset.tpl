@@ -0,0 +1,35 @@
+{{template "base" .}}
+{{define "preheader"}}我们收到了您重置密码的申请{{end}}
+{{define "content"}}
+    <tr>
+        <td class="wrapper">
+            <table role="presentation" border="0" cellpadding="0" cellspacing="0">
+                <tr>
+                    <td>
+                        <p>👋&nbsp; 你好~ {{.UserName}} ~ </p>
+                        <p>🔑&nbsp; 我们收到了您重置账户密码的申请。</p>
+                        <p>📬&nbsp; 点击以下按钮设置新密码：</p>
+                        <table role="presentation" border="0" cellpadding="0" cellspacing="0" class="btn btn-primary">
+                            <tbody>
+                            <tr>
+                                <td align="center">
+                                    <table role="presentation" border="0" cellpadding="0" cellspacing="0">
+                                        <tbody>
+                                        <tr>
+                                            <td><a href="{{.URL}}" target="_blank">重置密码</a></td>
+                                        </tr>
+                                        </tbody>
+                                    </table>
+                                </td>
+                            </tr>
+                            </tbody>
+                        </table>
+                        <p>⏰&nbsp; 链接 30 分钟内有效，只能使用一次。重置后所有设备上的登录都会失效。</p>
+                        <p>💃&nbsp; 按钮没反应？尝试将此 URL 粘贴到您的浏览器中：<a class='long-url'>{{.URL}}</a></p>
+                        <p>🛡&nbsp; 如果不是您本人的操作，请忽略这封邮件，您的密码不会改变。</p>
+                    </td>
+                </tr>
+            </table>
+        </td>
+    </tr>
+{{end}}
@@ -1,10 +1,13 @@
 package global
 
 import (
+	"errors"
 	"fmt"
 	"github.com/spf13/viper"
 	"log"
+	"math"
 	"strings"
+	"time"
 )
 
 // Config 结构体包含应用程序的配置信息
@@ -15,6 +18,7 @@ type Config struct {
 		DbType        string // 数据库类型 mysql | sqlite
 		DbAutoMigrate bool   // 是否自动迁移数据库表结构
 		DbLogMode     string // 数据库日志模式 silent | error | warn | info
+		SiteURL       string // 前台网站地址（协议://域名:端口）, 用于邮件中的链接和第三方登录完成后的跳转, 不使用请求中的 Host
 	}
 	Log struct {
 		Level     string // 日志级别 debug | info | warn | error
@@ -23,9 +27,17 @@ type Config struct {
 		Directory string // 日志存放目录
 	}
 	JWT struct {
-		Secret string // JWT 密钥
-		Expire int64  // JWT 过期时间（小时）
-		Issuer string // JWT 签发者
+		Secret         string // JWT 密钥, 用于 HS256 签名和私密文章的访问凭证
+		Alg            string // 签名算法 HS256 | RS256 | EdDSA, 默认 HS256; RS256, EdDSA 的密钥自动生成并保存在数据库中
+		RotateInterval int64  // RS256, EdDSA 签名密钥的轮换间隔（小时）, 0 表示不自动轮换
+		Expire         int64  // refresh token 过期时间（小时）, 超过该时间没有刷新需要重新登录
+		AccessExpire   int64  // access token (JWT) 过期时间（分钟）, 默认 15
+		Issuer         string // JWT 签发者
+		KeySecret      string // 加密数据库中 RS256, EdDSA 签名私钥的密钥, 为空时使用 Secret, 不能使用默认值; 建议通过环境变量 JWT_KEYSECRET 设置
+	}
+	Auth struct {
+		Unregistered string // 没有对应资源的接口的访问策略 allow | deny | authenticated-only
+		RegisterRole string // 新注册用户的默认角色 (角色 label), 默认为 user
 	}
 	Mysql struct {
 		Host     string // MySQL 服务器地址
@@ -57,7 +69,19 @@ type Config struct {
 	}
 	Captcha struct {
 		SendEmail  bool // 是否通过邮箱发送验证码
-		ExpireTime int  // 验证码过期时间（秒）
+		ExpireTime int  // 验证码过期时间（分钟）, 默认 5
+	}
+	Login struct {
+		CaptchaAfter int // 同一账号或 IP 登录失败多少次后需要图形验证码, 默认 3, 小于 0 表示不需要
+		LockAfter    int // 同一账号登录失败多少次后临时锁定, 默认 5
+		FailWindow   int // 登录失败次数的统计时间（分钟）, 默认 60
+		LockTime     int // 首次锁定时长（分钟）, 之后每次锁定时长翻倍, 默认 5
+		MaxLockTime  int // 最长锁定时长（分钟）, 默认 1440
+	}
+	OAuth struct {
+		CallbackURL     string          // 回调地址前缀, 回调地址为 <CallbackURL>/oauth/<Name>/callback, 为空时为 <Server.SiteURL>/api; 需要与前端访问的接口同域名, 回调时校验 Cookie
+		RedirectOrigins []string        // 登录完成后允许跳转的前端地址（协议://域名:端口）, Server.SiteURL 默认允许
+		Providers       []OAuthProvider // 第三方登录方式, 为空表示不开启
 	}
 	Upload struct {
 		Size      int    // 文件上传最大大小（单位：字节）
@@ -76,6 +100,20 @@ type Config struct {
 	}
 }
 
+// OAuthProvider 第三方登录方式的配置
+type OAuthProvider struct {
+	Name         string   // 唯一名称, 不超过 30 个字符, 用于接口路径和账号绑定, 修改后已绑定的账号失效
+	Title        string   // 登录按钮上显示的名称, 默认为 Name
+	Type         string   // 类型 github | oidc
+	ClientId     string   // 在第三方平台注册应用获得
+	ClientSecret string   // 在第三方平台注册应用获得
+	Issuer       string   // OIDC 签发者, 端点通过 <Issuer>/.well-known/openid-configuration 获取
+	AuthURL      string   // 授权端点, 为空时使用默认值
+	TokenURL     string   // token 端点, 为空时使用默认值
+	UserInfoURL  string   // 用户信息端点, 为空时使用默认值
+	Scopes       []string // 授权范围, 为空时使用默认值
+}
+
 // Conf 存储应用配置的全局变量
 var Conf *Config
 
@@ -110,6 +148,31 @@ func ReadConfig(path string) *Config {
 	return Conf
 }
 
+// 没有对应资源 (Resource) 的接口的访问策略
+const (
+	POLICY_ALLOW         = "allow"              // 允许访问, 不需要登录
+	POLICY_DENY          = "deny"               // 拒绝访问
+	POLICY_AUTHENTICATED = "authenticated-only" // 登录后即可访问, 不校验角色权限
+)
+
+// UnregisteredPolicy 返回没有对应资源的接口的访问策略, 未设置或设置错误时拒绝访问
+func (*Config) UnregisteredPolicy() string {
+	switch Conf.Auth.Unregistered {
+	case POLICY_ALLOW, POLICY_AUTHENTICATED:
+		return Conf.Auth.Unregistered
+	default:
+		return POLICY_DENY
+	}
+}
+
+// RegisterRole 返回新注册用户的默认角色 label
+func (*Config) RegisterRole() string {
+	if Conf.Auth.RegisterRole == "" {
+		return "user"
+	}
+	return Conf.Auth.RegisterRole
+}
+
 // DbType 返回数据库类型，如果未设置，则默认为 sqlite
 func (*Config) DbType() string {
 	if Conf.Server.DbType == "" {
@@ -140,3 +203,114 @@ func (*Config) DbDSN() string {
 		return Conf.SQLite.Dsn
 	}
 }
+
+// AccessExpire 返回 access token 的有效期, 未设置时为 15 分钟
+func (*Config) AccessExpire() time.Duration {
+	if Conf.JWT.AccessExpire <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(Conf.JWT.AccessExpire) * time.Minute
+}
+
+// RefreshExpire 返回 refresh token 的有效期, 未设置时为 24 小时
+func (*Config) RefreshExpire() time.Duration {
+	if Conf.JWT.Expire <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(Conf.JWT.Expire) * time.Hour
+}
+
+// SiteURL 返回前台网站地址, 没有结尾的 "/", 未配置时为空
+func (*Config) SiteURL() string {
+	return strings.TrimSuffix(Conf.Server.SiteURL, "/")
+}
+
+// DEFAULT_JWT_SECRET config.yml 中公开的默认 JWT.Secret, 不能用于加密签名私钥
+const DEFAULT_JWT_SECRET = "abc123321"
+
+// ErrKeySecret 没有配置加密签名私钥的密钥, 或者使用的是公开的默认值
+var ErrKeySecret = errors.New("未配置 JWT.KeySecret (环境变量 JWT_KEYSECRET), 或者使用的是默认密钥")
+
+// KeySecret 返回加密签名私钥的密钥, 未设置时使用 JWT.Secret
+// 都未设置或者是公开的默认值时返回 ErrKeySecret, 此时不能生成和加载 RS256, EdDSA 签名密钥
+func (*Config) KeySecret() (string, error) {
+	secret := Conf.JWT.KeySecret
+	if secret == "" {
+		secret = Conf.JWT.Secret
+	}
+	if secret == "" || secret == DEFAULT_JWT_SECRET {
+		return "", ErrKeySecret
+	}
+	return secret, nil
+}
+
+// SigningAlg 返回 JWT 签名算法, 未设置或设置错误时使用 HS256
+func (*Config) SigningAlg() string {
+	switch Conf.JWT.Alg {
+	case "RS256", "EdDSA":
+		return Conf.JWT.Alg
+	default:
+		return "HS256"
+	}
+}
+
+// CaptchaExpire 返回图形验证码的有效期, 未设置时为 5 分钟
+func (*Config) CaptchaExpire() time.Duration {
+	if Conf.Captcha.ExpireTime <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(Conf.Captcha.ExpireTime) * time.Minute
+}
+
+// CaptchaAfter 返回登录失败多少次后需要图形验证码, 未设置时为 3, 小于 0 时不需要
+func (*Config) CaptchaAfter() int64 {
+	switch {
+	case Conf.Login.CaptchaAfter < 0:
+		return math.MaxInt64
+	case Conf.Login.CaptchaAfter == 0:
+		return 3
+	}
+	return int64(Conf.Login.CaptchaAfter)
+}
+
+// LockAfter 返回登录失败多少次后锁定账号, 未设置时为 5
+func (*Config) LockAfter() int64 {
+	if Conf.Login.LockAfter <= 0 {
+		return 5
+	}
+	return int64(Conf.Login.LockAfter)
+}
+
+// FailWindow 返回登录失败次数的统计时间, 未设置时为 1 小时
+func (*Config) FailWindow() time.Duration {
+	if Conf.Login.FailWindow <= 0 {
+		return time.Hour
+	}
+	return time.Duration(Conf.Login.FailWindow) * time.Minute
+}
+
+// LockDuration 返回第 n 次锁定的时长, 从 LockTime 开始每次翻倍, 不超过 MaxLockTime
+func (*Config) LockDuration(n int64) time.Duration {
+	base, max := 5*time.Minute, 24*time.Hour
+	if Conf.Login.LockTime > 0 {
+		base = time.Duration(Conf.Login.LockTime) * time.Minute
+	}
+	if Conf.Login.MaxLockTime > 0 {
+		max = time.Duration(Conf.Login.MaxLockTime) * time.Minute
+	}
+	d := base
+	for i := int64(1); i < n && d < max; i++ {
+		d *= 2
+	}
+	return min(d, max)
+}
+
+// OAuthProvider 返回名称对应的第三方登录配置
+func (*Config) OAuthProvider(name string) (OAuthProvider, bool) {
+	for _, p := range Conf.OAuth.Providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return OAuthProvider{}, false
+}
@@ -0,0 +1,32 @@
+package global
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKeySecret(t *testing.T) {
+	Conf = &Config{}
+
+	// 没有配置, 或者使用公开的默认值
+	_, err := Conf.KeySecret()
+	assert.ErrorIs(t, err, ErrKeySecret)
+	Conf.JWT.Secret = DEFAULT_JWT_SECRET
+	_, err = Conf.KeySecret()
+	assert.ErrorIs(t, err, ErrKeySecret)
+	Conf.JWT.KeySecret = DEFAULT_JWT_SECRET
+	_, err = Conf.KeySecret()
+	assert.ErrorIs(t, err, ErrKeySecret)
+
+	// 优先使用 KeySecret, 未设置时使用修改过的 Secret
+	Conf.JWT.KeySecret = "key-secret"
+	secret, err := Conf.KeySecret()
+	assert.Nil(t, err)
+	assert.Equal(t, "key-secret", secret)
+
+	Conf.JWT.KeySecret = ""
+	Conf.JWT.Secret = "changed"
+	secret, err = Conf.KeySecret()
+	assert.Nil(t, err)
+	assert.Equal(t, "changed", secret)
+}
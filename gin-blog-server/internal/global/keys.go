@@ -9,15 +9,50 @@ const (
 
 	KEY_UNIQUE_VISITOR_SET = "unique_visitor" // 唯一用户记录 set
 
-	ARTICLE_USER_LIKE_SET = "article_user_like:" // 文章点赞 Set
-	ARTICLE_LIKE_COUNT    = "article_like_count" // 文章点赞数
-	ARTICLE_VIEW_COUNT    = "article_view_count" // 文章查看数
+	ARTICLE_USER_LIKE_SET = "article_user_like:"       // 文章点赞 Set
+	ARTICLE_LIKE_COUNT    = "article_like_count"       // 文章点赞数
+	ARTICLE_VIEW_COUNT    = "article_view_count"       // 文章查看数
+	ARTICLE_CACHE         = "article_cache:"           // 依赖前台可见文章的缓存 (前缀), 文章变化时统一删除
+	ARTICLE_RENDER        = ARTICLE_CACHE + "render:"  // 文章正文渲染结果: render:<id>:<更新时间>
+	ARTICLE_FEED          = ARTICLE_CACHE + "feed:"    // 订阅源: feed:<格式>:<分类id>:<标签id>
+	ARTICLE_SITEMAP       = ARTICLE_CACHE + "sitemap:" // sitemap: sitemap:<页码>, 0 为 sitemap.xml
+	ARTICLE_ROBOTS        = ARTICLE_CACHE + "robots"   // robots.txt
 
 	COMMENT_USER_LIKE_SET = "comment_user_like:" // 评论点赞 Set
 	COMMENT_LIKE_COUNT    = "comment_like_count" // 评论点赞数
 
 	PAGE   = "page"   // 页面封面
 	CONFIG = "config" // 博客配置
+
+	POLICY_CHANNEL = "policy_change" // 权限变化的发布/订阅频道, 通知各实例重新加载权限
+
+	REFRESH_TOKEN     = "refresh_token:"     // refresh token: refresh_token:<摘要> => 用户 id, 令牌族
+	TOKEN_FAMILY      = "token_family:"      // 令牌族当前可用的 refresh token: token_family:<令牌族> => 摘要
+	USER_TOKEN_FAMILY = "user_token_family:" // 用户的令牌族 Set: user_token_family:<用户 id>
+	REVOKED_TOKEN     = "revoked_token:"     // 已吊销的 access token: revoked_token:<jti>
+	REVOKED_FAMILY    = "revoked_family:"    // 已吊销的令牌族: revoked_family:<令牌族>
+
+	PENDING_REGISTER = "pending_register:" // 待验证的注册账号: pending_register:<邮箱> => 邮箱, 密码哈希, 当前验证 token 的摘要
+	EMAIL_TOKEN      = "email_token:"      // 邮箱验证 token: email_token:<摘要> => 邮箱
+
+	PASSWORD_RESET      = "password_reset:"      // 重置密码 token: password_reset:<摘要> => 用户 id
+	USER_PASSWORD_RESET = "user_password_reset:" // 用户当前可用的重置密码 token: user_password_reset:<用户 id> => 摘要
+
+	RATE_LIMIT = "rate_limit:" // 限流计数: rate_limit:<场景>:<邮箱或 IP>
+
+	LOGIN_CHALLENGE = "login_challenge:" // 两步验证登录的 challenge: login_challenge:<摘要> => 用户 id, 失败次数
+	TOTP_SETUP      = "totp_setup:"      // 待确认的 TOTP 密钥: totp_setup:<用户 id>
+	TOTP_USED       = "totp_used:"       // 用户最后使用的验证码时间步: totp_used:<用户 id>, 防止验证码重放
+
+	LOGIN_FAIL       = "login_fail:"       // 登录失败次数: login_fail:user:<用户名> | login_fail:ip:<IP>
+	LOGIN_LOCK       = "login_lock:"       // 临时锁定的账号: login_lock:<用户名>, 过期后自动解锁
+	LOGIN_LOCK_LEVEL = "login_lock_level:" // 账号最近被锁定的次数: login_lock_level:<用户名>, 用于计算锁定时长
+	CAPTCHA          = "captcha:"          // 图形验证码: captcha:<id> => 答案
+
+	OAUTH_STATE = "oauth_state:" // 第三方登录的授权参数: oauth_state:<state> => 登录方式, PKCE verifier, nonce, 跳转地址, 绑定的用户 id
+	OAUTH_LOGIN = "oauth_login:" // 第三方登录成功后的一次性登录码: oauth_login:<摘要> => 用户 id
+
+	ROLE_GRANT_CHECKED = "role_grant_checked" // 上次检查角色授权到期的时间 (毫秒时间戳), 重启后从这里继续检查
 )
 
 // Gin Context Key | Session Key
@@ -30,6 +65,11 @@ const (
 // Config Key
 
 const (
+	CONFIG_WEBSITE_NAME      = "website_name"
+	CONFIG_WEBSITE_AUTHOR    = "website_author"
+	CONFIG_WEBSITE_INTRO     = "website_intro"
+	CONFIG_WEBSITE_URL       = "website_url" // 前台网站地址, 用于生成订阅源, sitemap 中的绝对链接
+	CONFIG_ROBOTS_TXT        = "robots_txt"
 	CONFIG_ARTICLE_COVER     = "article_cover"
 	CONFIG_IS_COMMENT_REVIEW = "is_comment_review"
 	CONFIG_ABOUT             = "about"
@@ -60,6 +60,8 @@ var (
 	ErrDbOp     = RegisterResult(9004, "数据库操作异常")
 	ErrRedisOp  = RegisterResult(9005, "Redis 操作异常")
 	ErrUserAuth = RegisterResult(9006, "用户认证异常")
+	ErrTooMany  = RegisterResult(9007, "请求过于频繁 请稍后再试")
+	ErrSiteURL  = RegisterResult(9008, "未配置网站地址")
 
 	ErrPassword     = RegisterResult(1002, "密码错误")
 	ErrUserNotExist = RegisterResult(1003, "该用户不存在")
@@ -73,12 +75,42 @@ var (
 	ErrPermission       = RegisterResult(1206, "权限不足")
 	ErrForceOffline     = RegisterResult(1207, "您已被强制下线")
 	ErrForceOfflineSelf = RegisterResult(1208, "不能强制下线自己")
+	ErrUnregistered     = RegisterResult(1209, "接口未注册，禁止访问")
+	ErrDataScope        = RegisterResult(1210, "没有操作该数据的权限")
+	ErrTokenRevoked     = RegisterResult(1211, "TOKEN 已失效，请重新登陆")
+	ErrRefreshToken     = RegisterResult(1212, "登录已过期，请重新登陆")
+	ErrTwoFactorCode    = RegisterResult(1213, "两步验证码错误")
+	ErrTwoFactorExpired = RegisterResult(1214, "两步验证已过期，请重新登陆")
+	ErrTwoFactorState   = RegisterResult(1215, "请先获取两步验证密钥")
+	ErrTwoFactorEnabled = RegisterResult(1216, "已经开启两步验证")
+	ErrTwoFactorOff     = RegisterResult(1217, "没有开启两步验证")
+	ErrTwoFactorRequire = RegisterResult(1218, "您的角色要求开启两步验证，不能关闭")
+	ErrUserLocked       = RegisterResult(1219, "登录失败次数过多，账号已临时锁定")
+	ErrCaptchaRequired  = RegisterResult(1220, "请输入图形验证码")
+	ErrCaptcha          = RegisterResult(1221, "图形验证码错误或已过期")
+	ErrOAuthProvider    = RegisterResult(1222, "不支持该第三方登录方式")
+	ErrOAuthState       = RegisterResult(1223, "第三方登录已过期，请重新登录")
+	ErrOAuth            = RegisterResult(1224, "第三方登录失败")
+	ErrOAuthLinked      = RegisterResult(1225, "该第三方账号已绑定其他用户")
+	ErrOAuthEmailExist  = RegisterResult(1226, "该邮箱已注册，请使用原账号登录后绑定第三方账号")
+	ErrOAuthUnlink      = RegisterResult(1227, "账号没有设置密码，不能解绑唯一的登录方式")
+	ErrOAuthRedirect    = RegisterResult(1228, "不允许跳转到该地址")
+	ErrOAuthBound       = RegisterResult(1229, "已经绑定了该平台的其他账号，请先解绑")
+	ErrUserDisabled     = RegisterResult(1230, "该用户已被禁用")
 
 	ErrFileUpload  = RegisterResult(9100, "文件上传失败")
 	ErrFileReceive = RegisterResult(9101, "文件接收失败")
 
-	ErrTagHasArt  = RegisterResult(4003, "删除失败，标签下存在文章")
-	ErrCateHasArt = RegisterResult(3003, "删除失败，分类下存在文章")
+	ErrArticleExport           = RegisterResult(2001, "文章导出失败")
+	ErrArticleNotExist         = RegisterResult(2002, "该文章不存在")
+	ErrArticleRevisionNotExist = RegisterResult(2003, "该文章版本不存在")
+	ErrArticlePassword         = RegisterResult(2004, "文章密码错误")
+	ErrArticleRender           = RegisterResult(2005, "文章渲染失败")
+
+	ErrTagHasArt    = RegisterResult(4003, "删除失败，标签下存在文章")
+	ErrCateHasArt   = RegisterResult(3003, "删除失败，分类下存在文章")
+	ErrTagNotExist  = RegisterResult(4004, "该标签不存在")
+	ErrCateNotExist = RegisterResult(3004, "该分类不存在")
 
 	ErrResourceNotExist    = RegisterResult(6002, "该资源不存在")
 	ErrResourceUsedByRole  = RegisterResult(6003, "该资源正在被角色使用，无法删除")
@@ -86,9 +118,16 @@ var (
 	ErrMenuNotExist        = RegisterResult(6006, "该菜单不存在")
 	ErrMenuUsedByRole      = RegisterResult(6007, "该菜单正在被角色使用，无法删除")
 	ErrMenuHasChildren     = RegisterResult(6008, "该菜单下存在子菜单，无法删除")
+	ErrRoleNotExist        = RegisterResult(6009, "该角色不存在")
+	ErrRoleCycle           = RegisterResult(6010, "角色继承关系不能形成循环")
+	ErrRBACImport          = RegisterResult(6011, "权限配置导入失败")
 
 	ErrSendEmail      = RegisterResult(6101, "发送邮件失败")
 	ErrCodeNoexit     = RegisterResult(6102, "Code不存在 请重新注册")
 	ErrParseEmailCode = RegisterResult(6103, "解析邮件Code失败 请重试")
 	ErrUserExist      = RegisterResult(6104, "该邮箱已经注册 请重新注册")
+	ErrEmailToken     = RegisterResult(6105, "验证链接无效或已使用")
+	ErrEmailExpired   = RegisterResult(6106, "验证链接已过期 请重新发送验证邮件")
+	ErrEmailFrequent  = RegisterResult(6107, "邮件发送过于频繁 请稍后再试")
+	ErrResetToken     = RegisterResult(6108, "重置密码链接无效或已过期 请重新申请")
 )
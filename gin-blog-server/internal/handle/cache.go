@@ -6,6 +6,7 @@ import (
 	"gin-blog-server/internal/global"
 	"gin-blog-server/internal/model"
 	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -29,19 +30,13 @@ func getConfigCache(rdb *redis.Client) (cache map[string]string, err error) {
 	return rdb.HGetAll(rctx, global.CONFIG).Result()
 }
 
-// SetMailInfo 将邮箱信息存储到 rdb 中
-func SetMailInfo(rdb *redis.Client, info string, expire time.Duration) error {
-	return rdb.Set(rctx, info, true, expire).Err()
-}
-
-// GetMailInfo 检测 rdb 中是否存在邮箱信息
-func GetMailInfo(rdb *redis.Client, info string) (bool, error) {
-	return rdb.Get(rctx, info).Bool()
-}
-
-// DeleteMailInfo 从 rdb 中删除相关的邮箱信息
-func DeleteMailInfo(rdb *redis.Client, info string) error {
-	return rdb.Del(rctx, info).Err()
+// getConfigMap 获取博客配置, 优先使用 Redis 中的缓存
+func getConfigMap(db *gorm.DB, rdb *redis.Client) (map[string]string, error) {
+	cache, err := getConfigCache(rdb)
+	if err == nil && len(cache) > 0 {
+		return cache, nil
+	}
+	return model.GetConfigMap(db)
 }
 
 // addPageCache 将页面列表缓存到 Redis 中
@@ -71,3 +66,42 @@ func getPageCache(rdb *redis.Client) (cache []model.Page, err error) {
 func removePageCache(rdb *redis.Client) error {
 	return rdb.Del(rctx, global.PAGE).Err()
 }
+
+// RemoveArticleCache 删除所有依赖前台可见文章的缓存 (global.ARTICLE_CACHE 前缀)
+// 文章 新增/编辑/删除, 定时 发布/下线, 以及 分类/标签/页面/博客配置 变化后调用 (订阅源, sitemap 依赖这些数据)
+func RemoveArticleCache(rdb *redis.Client) error {
+	iter := rdb.Scan(rctx, 0, global.ARTICLE_CACHE+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(rctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return rdb.Del(rctx, keys...).Err()
+}
+
+// addDocumentCache 缓存生成的 订阅源/sitemap 等文档, key 需要以 global.ARTICLE_CACHE 为前缀, 文章变化时统一删除
+func addDocumentCache(rdb *redis.Client, key string, doc *cachedDocument) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return rdb.Set(rctx, key, data, time.Hour).Err()
+}
+
+// getDocumentCache 获取缓存的文档, 不存在时返回 redis.Nil 错误
+func getDocumentCache(rdb *redis.Client, key string) (*cachedDocument, error) {
+	data, err := rdb.Get(rctx, key).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var doc cachedDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
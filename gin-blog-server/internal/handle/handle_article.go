@@ -1,13 +1,27 @@
 package handle
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
 	"gin-blog-server/internal/global"
 	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils"
+	"gin-blog-server/internal/utils/markdown"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"io"
 	"log/slog"
 	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Article struct{}
@@ -35,6 +49,11 @@ type AddOrEditArticleReq struct {
 	Status      int    `json:"status" binding:"required,min=1,max=3"` // 类型: 1-公开 2-私密 3-评论可见
 	IsTop       bool   `json:"is_top"`
 	OriginalUrl string `json:"original_url"`
+	Slug        string `json:"slug"`     // 为空时根据标题生成
+	Password    string `json:"password"` // 私密文章的访问密码, 为空时不修改
+
+	PublishAt   *time.Time `json:"publish_at"`   // 定时发布时间, 为空表示不定时
+	UnpublishAt *time.Time `json:"unpublish_at"` // 定时下线时间, 为空表示不定时
 
 	TagNames     []string `json:"tag_names"`
 	CategoryName string   `json:"category_name"`
@@ -59,6 +78,34 @@ type SoftDeleteReq struct {
 	IsDelete bool  `json:"is_delete"`
 }
 
+// ExportArticleQuery 导出文章的查询参数, 文章 id 列表通过请求体传入
+type ExportArticleQuery struct {
+	WithImages bool `form:"with_images"` // 是否打包文章中的图片
+}
+
+// ImportResultVO 导入文章时单个文件的导入结果
+type ImportResultVO struct {
+	FileName  string `json:"file_name"`
+	Success   bool   `json:"success"`
+	ArticleId int    `json:"article_id"`
+	Title     string `json:"title"`
+	Message   string `json:"message"` // 失败原因, 或者导入成功时对文章的修改
+}
+
+// ArticleFrontMatter 导出文章时写入 Markdown 文件头部的 front matter
+type ArticleFrontMatter struct {
+	Title       string   `yaml:"title"`
+	Slug        string   `yaml:"slug"`
+	Desc        string   `yaml:"desc"`
+	Category    string   `yaml:"category"`
+	Tags        []string `yaml:"tags"`
+	Type        string   `yaml:"type"`
+	Status      string   `yaml:"status"`
+	OriginalUrl string   `yaml:"original_url"`
+	CreatedAt   string   `yaml:"created_at"`
+	Img         string   `yaml:"img"`
+}
+
 // GetList 获取文章列表
 func (*Article) GetList(c *gin.Context) {
 	var query ArticleQuery
@@ -70,7 +117,12 @@ func (*Article) GetList(c *gin.Context) {
 	db := GetDB(c)
 	rdb := GetRDB(c)
 
-	list, total, err := model.GetArticleList(db, query.Page, query.Size, query.Title, query.IsDelete, query.Status, query.Type, query.CategoryId, query.TagId)
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+
+	list, total, err := model.GetArticleList(db, scope, query.Page, query.Size, query.Title, query.IsDelete, query.Status, query.Type, query.CategoryId, query.TagId)
 	if err != nil || list == nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
@@ -113,9 +165,42 @@ func (*Article) SaveOrUpdate(c *gin.Context) {
 		return
 	}
 
+	if req.PublishAt != nil && req.UnpublishAt != nil && !req.UnpublishAt.After(*req.PublishAt) {
+		ReturnError(c, global.ErrRequest, "下线时间必须晚于发布时间")
+		return
+	}
+
 	db := GetDB(c)
 	auth, _ := CurrentUserAuth(c)
 
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+
+	// 私密文章必须设置访问密码
+	if req.Status == model.STATUS_SECRET && req.Password == "" {
+		has, err := model.HasArticlePassword(db, req.ID)
+		if err != nil {
+			ReturnError(c, global.ErrDbOp, err)
+			return
+		}
+		if !has {
+			ReturnError(c, global.ErrRequest, "私密文章需要设置访问密码")
+			return
+		}
+	}
+
+	var password string
+	if req.Password != "" {
+		hash, err := utils.BcryptHash(req.Password)
+		if err != nil {
+			ReturnError(c, global.FailResult, err)
+			return
+		}
+		password = hash
+	}
+
 	if req.Img == "" {
 		req.Img = model.GetConfig(db, global.CONFIG_ARTICLE_COVER) // 默认图片
 	}
@@ -123,25 +208,46 @@ func (*Article) SaveOrUpdate(c *gin.Context) {
 	article := model.Article{
 		Model:       model.Model{ID: req.ID},
 		Title:       req.Title,
+		Slug:        req.Slug,
 		Desc:        req.Desc,
 		Content:     req.Content,
 		Img:         req.Img,
 		Type:        req.Type,
 		Status:      req.Status,
+		Password:    password,
 		OriginalUrl: req.OriginalUrl,
 		IsTop:       req.IsTop,
-		UserId:      auth.UserInfoId,
+		PublishAt:   req.PublishAt,
+		UnpublishAt: req.UnpublishAt,
+		UserId:      auth.ID,
 	}
 
-	err := model.SaveOrUpdateArticle(db, &article, req.CategoryName, req.TagNames)
+	err := model.SaveOrUpdateArticle(db, scope, &article, req.CategoryName, req.TagNames, auth.ID)
 	if err != nil {
-		ReturnError(c, global.ErrDbOp, err)
+		returnDataError(c, err)
+		return
+	}
+
+	refreshArticleIndex(db, article.ID)
+
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
 		return
 	}
 
 	ReturnSuccess(c, article)
 }
 
+// RebuildSearchIndex 重建文章全文索引, 返回索引的文章数量
+func (*Article) RebuildSearchIndex(c *gin.Context) {
+	count, err := BuildArticleIndex(GetDB(c))
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	ReturnSuccess(c, count)
+}
+
 // UpdateTop 修改置顶信息
 func (*Article) UpdateTop(c *gin.Context) {
 	var req UpdateArticleTopReq
@@ -150,11 +256,21 @@ func (*Article) UpdateTop(c *gin.Context) {
 		return
 	}
 
-	err := model.UpdateArticleTop(GetDB(c), req.ID, req.IsTop)
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+
+	err := model.UpdateArticleTop(GetDB(c), scope, req.ID, req.IsTop)
 	if err != nil {
-		ReturnError(c, global.ErrDbOp, err)
+		returnDataError(c, err)
 		return
 	}
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
 	ReturnSuccess(c, nil)
 }
 
@@ -166,8 +282,17 @@ func (*Article) GetDetail(c *gin.Context) {
 		return
 	}
 
-	article, err := model.GetArticle(GetDB(c), id)
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+
+	article, err := model.GetArticle(GetDB(c), scope, id)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ReturnError(c, global.ErrArticleNotExist, nil)
+			return
+		}
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
@@ -183,9 +308,21 @@ func (*Article) UpdateSoftDelete(c *gin.Context) {
 		return
 	}
 
-	rows, err := model.UpdateArticleSoftDelete(GetDB(c), req.Ids, req.IsDelete)
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+
+	rows, err := model.UpdateArticleSoftDelete(GetDB(c), scope, req.Ids, req.IsDelete)
 	if err != nil {
-		ReturnError(c, global.ErrDbOp, err)
+		returnDataError(c, err)
+		return
+	}
+
+	refreshArticleIndex(GetDB(c), req.Ids...)
+
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
 		return
 	}
 
@@ -200,67 +337,479 @@ func (*Article) Delete(c *gin.Context) {
 		return
 	}
 
-	rows, err := model.DeleteArticle(GetDB(c), ids)
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+
+	rows, err := model.DeleteArticle(GetDB(c), scope, ids)
 	if err != nil {
-		ReturnError(c, global.ErrDbOp, err)
+		returnDataError(c, err)
+		return
+	}
+
+	refreshArticleIndex(GetDB(c), ids...)
+
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
 		return
 	}
 
 	ReturnSuccess(c, rows)
 }
 
-// Export 导出文章: 获取导出后的资源链接列表
-// TODO: 目前是前端导出
+// Export 导出文章: 将选中的文章打包成 ZIP 下载
+// 每篇文章导出为一个带 YAML front matter 的 Markdown 文件
+// 请求参数 with_images=true 时, 会将封面和正文引用的图片一起打包, 并将文章中的链接替换为相对路径
 func (*Article) Export(c *gin.Context) {
-	ReturnSuccess(c, nil)
+	var ids []int
+	if err := c.ShouldBindJSON(&ids); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	var query ExportArticleQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+
+	list, err := model.GetArticleListByIds(GetDB(c), scope, ids)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	// 压缩包直接写入响应, 不在内存中保存整个压缩包
+	fileName := "articles-" + time.Now().Format("20060102150405") + ".zip"
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Header("Content-Type", "application/zip")
+	if err := writeArticleZip(c.Writer, list, query.WithImages); err != nil {
+		// 已经开始写入时无法再返回错误信息, 客户端收到的是不完整的压缩包
+		if c.Writer.Written() {
+			slog.Error("导出文章失败, 响应已中断", "ids", ids, "err", err)
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Del("Content-Disposition")
+		c.Writer.Header().Del("Content-Type")
+		ReturnError(c, global.ErrArticleExport, err)
+	}
+}
+
+// writeArticleZip 将文章列表写入 ZIP 压缩包
+func writeArticleZip(w io.Writer, list []model.Article, withImages bool) error {
+	zw := zip.NewWriter(w)
+
+	names := make(map[string]int) // 防止文件重名
+	for _, article := range list {
+		name := exportFileName(article)
+		if n := names[name]; n > 0 {
+			name = fmt.Sprintf("%s-%d", name, n)
+		}
+		names[name]++
+
+		content, img := article.Content, article.Img
+		if withImages {
+			var err error
+			content, img, err = packArticleImages(zw, article.ID, content, img)
+			if err != nil {
+				return err
+			}
+		}
+
+		data, err := markdown.MarshalFrontMatter(newArticleFrontMatter(article, img), content)
+		if err != nil {
+			return err
+		}
+
+		f, err := zw.Create(name + ".md")
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// newArticleFrontMatter 根据文章生成 front matter, img 为 (可能已被替换为相对路径的) 封面地址
+func newArticleFrontMatter(article model.Article, img string) ArticleFrontMatter {
+	matter := ArticleFrontMatter{
+		Title:       article.Title,
+		Slug:        article.Slug,
+		Desc:        article.Desc,
+		Tags:        make([]string, 0),
+		Type:        model.ArticleTypeNames[article.Type],
+		Status:      model.ArticleStatusNames[article.Status],
+		OriginalUrl: article.OriginalUrl,
+		CreatedAt:   article.CreatedAt.Format(time.DateTime),
+		Img:         img,
+	}
+	if article.Category != nil {
+		matter.Category = article.Category.Name
+	}
+	for _, tag := range article.Tags {
+		matter.Tags = append(matter.Tags, tag.Name)
+	}
+	return matter
+}
+
+// packArticleImages 将文章封面和正文中的图片写入压缩包的 images/{文章id}/ 目录下
+// 返回替换为相对路径后的正文和封面, 获取失败的图片保留原链接
+func packArticleImages(zw *zip.Writer, articleId int, content, img string) (string, string, error) {
+	links := markdown.ImageLinks(content)
+	if img != "" {
+		links = append(links, img)
+	}
+
+	replacer := make(map[string]string)
+	for _, link := range links {
+		if _, ok := replacer[link]; ok {
+			continue
+		}
+
+		data, err := fetchArticleImage(link)
+		if err != nil {
+			slog.Warn("导出文章时获取图片失败", slog.String("link", link), slog.String("err", err.Error()))
+			continue
+		}
+
+		ext := path.Ext(strings.SplitN(path.Base(link), "?", 2)[0])
+		if ext == "" || len(ext) > 5 {
+			ext = ".png"
+		}
+		name := fmt.Sprintf("images/%d/%s%s", articleId, utils.MD5(link), ext)
+
+		f, err := zw.Create(name)
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			return "", "", err
+		}
+		replacer[link] = name
+	}
+
+	if newImg, ok := replacer[img]; ok {
+		img = newImg
+	}
+	return markdown.ReplaceImageLinks(content, replacer), img, nil
+}
+
+// 导出图片的大小上限
+const maxExportImageSize = 20 << 20
+
+var errExportImageSize = errors.New("图片超过导出大小上限")
+
+// imageClient 下载外链图片, 文章内容可能由其他用户编写, 只允许访问公网地址
+var imageClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: utils.IP.PublicDialControl}).DialContext,
+	},
 }
 
-// Import 导入文章：题目 + 内容
+// fetchArticleImage 获取图片内容: 本地上传的图片直接从存储目录读取, 外链图片通过 HTTP 下载
+func fetchArticleImage(link string) ([]byte, error) {
+	conf := global.GetConfig().Upload
+	if prefix := strings.TrimPrefix(conf.Path, "."); prefix != "" && strings.Contains(link, prefix+"/") {
+		name := link[strings.Index(link, prefix+"/")+len(prefix)+1:]
+		return os.ReadFile(filepath.Join(conf.StorePath, filepath.Clean("/"+name)))
+	}
+
+	if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+		return nil, errors.New("不支持的图片链接")
+	}
+
+	resp, err := imageClient.Get(link)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("图片下载失败, 状态码: %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxExportImageSize {
+		return nil, errExportImageSize
+	}
+	// 多读一个字节判断是否超过上限, 超过时跳过该图片, 而不是打包截断的内容
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxExportImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxExportImageSize {
+		return nil, errExportImageSize
+	}
+	return data, nil
+}
+
+// exportFileName 生成导出文件名: {id}-{标题}, 去除文件名中的非法字符
+func exportFileName(article model.Article) string {
+	title := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(article.Title))
+
+	if runes := []rune(title); len(runes) > 80 {
+		title = string(runes[:80])
+	}
+	return fmt.Sprintf("%d-%s", article.ID, title)
+}
+
+// Import 导入文章: 支持上传多个 .md/.markdown 文件, 或包含多个 Markdown 文件的 .zip 压缩包
+// 会解析文件头部的 YAML/TOML front matter, 缺失的字段使用默认值, 返回每个文件的导入结果
 func (*Article) Import(c *gin.Context) {
 	db := GetDB(c)
 	auth, _ := CurrentUserAuth(c)
 
-	_, fileHeader, err := c.Request.FormFile("file")
-	if err != nil {
-		ReturnError(c, global.ErrFileReceive, err)
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+	if !scope.CanCreate() {
+		ReturnError(c, global.ErrDataScope, nil)
 		return
 	}
 
-	fileName := fileHeader.Filename
-	// 获取文章题目
-	title := fileName[:len(fileName)-3]
-	// 获取文章内容
-	content, err := readFromFileHeader(fileHeader)
+	form, err := c.MultipartForm()
 	if err != nil {
 		ReturnError(c, global.ErrFileReceive, err)
 		return
 	}
+	fileHeaders := form.File["file"]
+	if len(fileHeaders) == 0 {
+		ReturnError(c, global.ErrFileReceive, "没有上传文件")
+		return
+	}
 
-	// 获取默认文章封面
-	defaultImg := model.GetConfig(db, global.CONFIG_ARTICLE_COVER)
-	err = model.ImportArticle(db, auth.ID, title, content, defaultImg, "学习", "后端开发")
-	if err != nil {
-		ReturnError(c, global.ErrDbOp, err)
+	importer := articleImporter{
+		db:         db,
+		scope:      scope,
+		userAuthId: auth.ID,
+		defaultImg: model.GetConfig(db, global.CONFIG_ARTICLE_COVER),
+	}
+
+	results := make([]ImportResultVO, 0)
+	for _, fileHeader := range fileHeaders {
+		data, err := readFromFileHeader(fileHeader)
+		if err != nil {
+			results = append(results, ImportResultVO{FileName: fileHeader.Filename, Message: err.Error()})
+			continue
+		}
+
+		switch strings.ToLower(path.Ext(fileHeader.Filename)) {
+		case ".zip":
+			results = append(results, importer.importZip(fileHeader.Filename, data)...)
+		case ".md", ".markdown":
+			results = append(results, importer.importFile(fileHeader.Filename, data))
+		default:
+			results = append(results, ImportResultVO{FileName: fileHeader.Filename, Message: "不支持的文件类型"})
+		}
+	}
+
+	importedIds := make([]int, 0, len(results))
+	for _, result := range results {
+		if result.Success {
+			importedIds = append(importedIds, result.ArticleId)
+		}
+	}
+	refreshArticleIndex(db, importedIds...)
+
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
 		return
 	}
 
-	ReturnSuccess(c, nil)
+	ReturnSuccess(c, results)
+}
+
+// 导入文章时 front matter 缺失分类, 标签时使用的默认值
+const (
+	defaultImportCategory = "学习"
+	defaultImportTag      = "后端开发"
+)
+
+// 压缩包中单个文件的大小上限, 以及文件数量的上限 (包括目录和其他文件)
+const (
+	maxImportFileSize   = 10 << 20
+	maxImportZipEntries = 1000
+)
+
+// articleImporter 导入文章, 保存导入过程中共用的参数
+type articleImporter struct {
+	db         *gorm.DB
+	scope      model.DataScope
+	userAuthId int
+	defaultImg string // 默认文章封面
+}
+
+// importZip 导入压缩包中的所有 Markdown 文件, 忽略其他文件
+func (im *articleImporter) importZip(zipName string, data []byte) []ImportResultVO {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return []ImportResultVO{{FileName: zipName, Message: "压缩包解析失败: " + err.Error()}}
+	}
+
+	if len(zr.File) > maxImportZipEntries {
+		return []ImportResultVO{{FileName: zipName, Message: fmt.Sprintf("压缩包中的文件过多, 最多 %d 个", maxImportZipEntries)}}
+	}
+
+	results := make([]ImportResultVO, 0)
+	for _, f := range zr.File {
+		ext := strings.ToLower(path.Ext(f.Name))
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || (ext != ".md" && ext != ".markdown") {
+			continue
+		}
+
+		name := zipName + "/" + f.Name
+		if f.UncompressedSize64 > maxImportFileSize {
+			results = append(results, ImportResultVO{FileName: name, Message: "文件过大"})
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			results = append(results, ImportResultVO{FileName: name, Message: err.Error()})
+			continue
+		}
+		// 压缩包中记录的大小可能是伪造的, 多读一个字节判断实际大小
+		content, err := io.ReadAll(io.LimitReader(rc, maxImportFileSize+1))
+		rc.Close()
+		if err != nil {
+			results = append(results, ImportResultVO{FileName: name, Message: err.Error()})
+			continue
+		}
+		if len(content) > maxImportFileSize {
+			results = append(results, ImportResultVO{FileName: name, Message: "文件过大"})
+			continue
+		}
+
+		results = append(results, im.importFile(name, content))
+	}
+	return results
+}
+
+// importFile 导入单个 Markdown 文件
+// front matter 支持的字段: title, desc, category, tags, img, status, type, date, original_url
+// 同时兼容 Hexo/Hugo 的常见写法: categories, description, cover, draft 等
+func (im *articleImporter) importFile(fileName string, data []byte) ImportResultVO {
+	result := ImportResultVO{FileName: fileName}
+
+	matter, content, err := markdown.ParseFrontMatter(data)
+	if err != nil {
+		result.Message = "front matter 解析失败: " + err.Error()
+		return result
+	}
+
+	article := model.Article{
+		Title:       matter.String("title"),
+		Slug:        matter.String("slug"),
+		Desc:        matter.String("desc", "description", "summary"),
+		Content:     content,
+		Img:         matter.String("img", "cover", "image", "thumbnail"),
+		Type:        parseArticleEnum(matter.String("type"), model.ArticleTypeNames, model.TYPE_ORIGINAL),
+		Status:      parseArticleEnum(matter.String("status"), model.ArticleStatusNames, model.STATUS_DRAFT),
+		OriginalUrl: matter.String("original_url", "originalUrl"),
+		UserId:      im.userAuthId,
+	}
+
+	// 文件名作为默认标题
+	if article.Title == "" {
+		base := path.Base(fileName)
+		article.Title = strings.TrimSuffix(base, path.Ext(base))
+	}
+	if article.Img == "" {
+		article.Img = im.defaultImg
+	}
+	// Hexo, Hugo 使用 draft 标记草稿
+	if draft, ok := matter.Bool("draft"); ok && matter.String("status") == "" {
+		if draft {
+			article.Status = model.STATUS_DRAFT
+		} else {
+			article.Status = model.STATUS_PUBLIC
+		}
+	}
+	if date, ok := matter.Time("date", "created_at"); ok {
+		article.CreatedAt = date
+	}
+	// hexo-blog-encrypt 等插件使用 password 设置文章密码
+	if password := matter.String("password"); password != "" {
+		hash, err := utils.BcryptHash(password)
+		if err != nil {
+			result.Message = err.Error()
+			return result
+		}
+		article.Password = hash
+	}
+	// 没有密码的私密文章无法解锁, 导入为草稿
+	if article.Status == model.STATUS_SECRET && article.Password == "" {
+		article.Status = model.STATUS_DRAFT
+		result.Message = "私密文章没有设置密码, 已导入为草稿"
+	}
+
+	categoryName := matter.String("category", "categories")
+	if categoryName == "" {
+		categoryName = defaultImportCategory
+	}
+	tagNames := matter.Strings("tags", "tag")
+	if len(tagNames) == 0 {
+		tagNames = []string{defaultImportTag}
+	}
+
+	if err := model.ImportArticle(im.db, im.scope, &article, categoryName, tagNames, im.userAuthId); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Success = true
+	result.ArticleId = article.ID
+	result.Title = article.Title
+	return result
+}
+
+// parseArticleEnum 解析文章的类型/状态, 支持名称 (public) 或数字 (1), 无法解析时返回默认值
+func parseArticleEnum(val string, names map[int]string, defaultVal int) int {
+	if val == "" {
+		return defaultVal
+	}
+	for k, name := range names {
+		if strings.EqualFold(name, val) {
+			return k
+		}
+	}
+	if n, err := strconv.Atoi(val); err == nil {
+		if _, ok := names[n]; ok {
+			return n
+		}
+	}
+	return defaultVal
 }
 
-// 获取文章内容
-func readFromFileHeader(file *multipart.FileHeader) (string, error) {
+// 获取上传文件的内容
+func readFromFileHeader(file *multipart.FileHeader) ([]byte, error) {
 	open, err := file.Open()
 	if err != nil {
-		slog.Error("文件读取，目标地址错误：", err)
-		return "", err
+		slog.Error("文件读取，目标地址错误：", slog.String("err", err.Error()))
+		return nil, err
 	}
 	defer open.Close()
 
 	all, err := io.ReadAll(open)
 	if err != nil {
-		slog.Error("文件读取失败：", err)
-		return "", err
+		slog.Error("文件读取失败：", slog.String("err", err.Error()))
+		return nil, err
 	}
 
-	return string(all), nil
+	return all, nil
 }
@@ -0,0 +1,185 @@
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// ArticleRevisionQuery 文章版本列表查询
+type ArticleRevisionQuery struct {
+	PageQuery
+	ArticleId int `form:"article_id" binding:"required"`
+}
+
+// ArticleRevisionDiffQuery 比较两个版本, from 为旧版本, to 为新版本
+type ArticleRevisionDiffQuery struct {
+	From int `form:"from" binding:"required"`
+	To   int `form:"to" binding:"required"`
+}
+
+// ArticleRevisionDiffVO 两个版本的差异
+// From, To 只包含版本的基本信息, 正文差异在 Lines 和 Unified 中
+type ArticleRevisionDiffVO struct {
+	From    model.ArticleRevision `json:"from"`
+	To      model.ArticleRevision `json:"to"`
+	Lines   []utils.DiffLine      `json:"lines"`   // 正文逐行差异
+	Unified string                `json:"unified"` // 正文 unified 格式差异
+}
+
+// GetRevisionList 获取文章的版本列表
+func (*Article) GetRevisionList(c *gin.Context) {
+	var query ArticleRevisionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+
+	list, total, err := model.GetArticleRevisionList(GetDB(c), scope, query.ArticleId, query.Page, query.Size)
+	if err != nil {
+		returnDataError(c, err)
+		return
+	}
+
+	ReturnSuccess(c, PageResult[model.ArticleRevision]{
+		Size:  query.Size,
+		Page:  query.Page,
+		Total: total,
+		List:  list,
+	})
+}
+
+// GetRevision 获取文章版本详情
+func (*Article) GetRevision(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	revision, err := getArticleRevision(c, GetDB(c), id)
+	if err != nil {
+		return
+	}
+
+	ReturnSuccess(c, revision)
+}
+
+// DiffRevision 比较文章的两个版本
+func (*Article) DiffRevision(c *gin.Context) {
+	var query ArticleRevisionDiffQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	db := GetDB(c)
+
+	from, err := getArticleRevision(c, db, query.From)
+	if err != nil {
+		return
+	}
+	to, err := getArticleRevision(c, db, query.To)
+	if err != nil {
+		return
+	}
+	if from.ArticleId != to.ArticleId {
+		ReturnError(c, global.ErrRequest, errors.New("不能比较不同文章的版本"))
+		return
+	}
+
+	unified, err := utils.UnifiedDiff(from.Content, to.Content,
+		fmt.Sprintf("revision-%d", from.ID), fmt.Sprintf("revision-%d", to.ID), 3)
+	if err != nil {
+		ReturnError(c, global.FailResult, err)
+		return
+	}
+
+	data := ArticleRevisionDiffVO{
+		Lines:   utils.LineDiff(from.Content, to.Content),
+		Unified: unified,
+	}
+	data.From, data.To = *from, *to
+	data.From.Content, data.To.Content = "", ""
+
+	ReturnSuccess(c, data)
+}
+
+// RestoreRevision 将文章恢复到指定版本, 恢复操作本身也会记录一个新版本
+func (*Article) RestoreRevision(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	db := GetDB(c)
+	auth, _ := CurrentUserAuth(c)
+
+	revision, err := getArticleRevision(c, db, id)
+	if err != nil {
+		return
+	}
+
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return
+	}
+
+	article, err := model.GetArticle(db, scope, revision.ArticleId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ReturnError(c, global.ErrArticleNotExist, nil)
+			return
+		}
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	// 避免 Updates 时同时更新关联数据
+	article.Category, article.Tags, article.User = nil, nil, nil
+
+	err = model.RestoreArticleRevision(db, scope, article, revision, auth.ID)
+	if err != nil {
+		returnDataError(c, err)
+		return
+	}
+
+	refreshArticleIndex(db, article.ID)
+
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	ReturnSuccess(c, article)
+}
+
+// getArticleRevision 获取文章版本, 文章需要在数据权限范围内, 出错时直接返回错误响应
+func getArticleRevision(c *gin.Context, db *gorm.DB, id int) (*model.ArticleRevision, error) {
+	scope, ok := dataScope(c, model.DATA_ARTICLE)
+	if !ok {
+		return nil, model.ErrOutOfScope
+	}
+
+	revision, err := model.GetArticleRevision(db, scope, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ReturnError(c, global.ErrArticleRevisionNotExist, nil)
+			return nil, err
+		}
+		returnDataError(c, err)
+		return nil, err
+	}
+	return revision, nil
+}
@@ -0,0 +1,60 @@
+package handle
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"gin-blog-server/internal/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestImportZipLimits(t *testing.T) {
+	var im articleImporter
+
+	// 文件数量超过上限
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i <= maxImportZipEntries; i++ {
+		_, err := zw.Create(fmt.Sprintf("dir%d/", i))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zw.Close())
+	results := im.importZip("many.zip", buf.Bytes())
+	assert.Len(t, results, 1)
+	assert.False(t, results[0].Success)
+	assert.Contains(t, results[0].Message, "文件过多")
+
+	// 头部记录的大小小于实际大小, 不会截断后导入
+	buf.Reset()
+	zw = zip.NewWriter(&buf)
+	content := []byte(strings.Repeat("a", maxImportFileSize+10))
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "big.md",
+		Method:             zip.Store,
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: 10,
+	})
+	assert.Nil(t, err)
+	_, err = w.Write(content)
+	assert.Nil(t, err)
+	assert.Nil(t, zw.Close())
+	results = im.importZip("big.zip", buf.Bytes())
+	assert.Len(t, results, 1)
+	assert.False(t, results[0].Success)
+}
+
+func TestWriteArticleZip(t *testing.T) {
+	list := []model.Article{
+		{Model: model.Model{ID: 1}, Title: "a/b", Content: "content"},
+		{Model: model.Model{ID: 2}, Title: "a/b", Content: "content"},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, writeArticleZip(&buf, list, false))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Len(t, zr.File, 2)
+	assert.NotEqual(t, zr.File[0].Name, zr.File[1].Name)
+}
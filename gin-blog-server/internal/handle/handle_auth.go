@@ -5,22 +5,25 @@ import (
 	"gin-blog-server/internal/global"
 	"gin-blog-server/internal/model"
 	"gin-blog-server/internal/utils"
-	"gin-blog-server/internal/utils/jwt"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"html"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
-	"time"
 )
 
 type UserAuth struct{}
 
 // LoginReq 发送的登陆请求
 type LoginReq struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username  string `json:"username" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	CaptchaId string `json:"captcha_id"` // 登录失败次数过多时需要图形验证码, 通过 /captcha 获取
+	Captcha   string `json:"captcha"`
 }
 
 type RegisterReq struct {
@@ -35,12 +38,20 @@ type LoginVO struct {
 	// 点赞 Set： 用于记录用户点赞过的文章，评论
 	ArticleLikeSet []string `json:"article_like_set"`
 	CommentLikeSet []string `json:"comment_like_set"`
-	Token          string   `json:"token"`
+	TokenVO
+
+	TwoFactor     *TwoFactorVO `json:"two_factor,omitempty"`     // 需要两步验证时只返回 challenge, 不返回用户信息和 token
+	RecoveryCodes []string     `json:"recovery_codes,omitempty"` // 登录时开启两步验证生成的恢复码
+}
+
+// RefreshTokenReq 刷新 token 的请求
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // Login 完成登陆操作
 // @Summary 登录
-// @Description 登录
+// @Description 登录失败次数过多时需要图形验证码, 继续失败会临时锁定账号
 // @Tags UserAuth
 // @Param form body LoginReq true "登录"
 // @Accept json
@@ -62,12 +73,17 @@ func (*UserAuth) Login(c *gin.Context) {
 	db := GetDB(c)
 	rdb := GetRDB(c)
 
+	// 账号被锁定时直接拒绝, 失败次数过多时校验图形验证码
+	if !checkLoginAttempt(c, rdb, req.Username, req.CaptchaId, req.Captcha) {
+		return
+	}
+
 	// 查询数据库，获取用户的身份信息（UserAuth）
 	userAuth, err := model.GetUserAuthInfoByName(db, req.Username)
 	if err != nil {
-		// 如果没有找到用户，返回用户不存在的错误
+		// 如果没有找到用户，返回用户不存在的错误, 同样计入失败次数
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			ReturnError(c, global.ErrUserNotExist, nil)
+			loginFailed(c, rdb, req.Username, global.ErrUserNotExist)
 			return
 		}
 		// 如果查询发生数据库操作错误，返回错误
@@ -77,11 +93,45 @@ func (*UserAuth) Login(c *gin.Context) {
 
 	// 检查传入的密码与数据库中存储的密码是否匹配
 	if !utils.BcryptCheck(req.Password, userAuth.Password) {
-		// 如果密码不匹配，返回密码错误
-		ReturnError(c, global.ErrPassword, nil)
+		// 如果密码不匹配，记录失败次数并返回密码错误
+		loginFailed(c, rdb, req.Username, global.ErrPassword)
+		return
+	}
+
+	loginVerified(c, userAuth)
+}
+
+// loginVerified 第一步身份验证 (密码, 第三方登录) 通过后登录
+// 被禁用的用户不能登录; 开启了两步验证, 或者角色要求两步验证时, 返回 challenge, 通过 /login/2fa 完成登录
+func loginVerified(c *gin.Context, userAuth *model.UserAuth) {
+	if userAuth.IsDisable {
+		ReturnError(c, global.ErrUserDisabled, nil)
+		return
+	}
+
+	required, err := model.IsTwoFactorRequired(GetDB(c), userAuth.ID)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	if userAuth.TotpEnabled || required {
+		challenge, err := newLoginChallenge(GetRDB(c), userAuth.ID, !userAuth.TotpEnabled)
+		if err != nil {
+			ReturnError(c, global.ErrRedisOp, err)
+			return
+		}
+		ReturnSuccess(c, LoginVO{TwoFactor: challenge})
 		return
 	}
 
+	completeLogin(c, userAuth, nil)
+}
+
+// completeLogin 身份验证通过后完成登录: 签发 token, 记录登录信息, 返回用户信息
+func completeLogin(c *gin.Context, userAuth *model.UserAuth, recoveryCodes []string) {
+	db := GetDB(c)
+	rdb := GetRDB(c)
+
 	// 获取请求中的 IP 地址和 IP 来源信息
 	// FIXME: 可能无法正确读取 IP 地址，这需要解决
 	ipAddress := utils.IP.GetIpAddress(c)
@@ -124,11 +174,8 @@ func (*UserAuth) Login(c *gin.Context) {
 		return
 	}
 
-	// 登录信息验证通过后，生成 JWT Token
-	// UUID 生成方法：可以使用 ip 地址、浏览器信息和操作系统信息来生成唯一标识符（具体实现可调整）
-	// 这里使用 jwt.GenToken 来生成 Token
-	conf := global.GetConfig().JWT
-	token, err := jwt.GenToken(conf.Secret, conf.Issuer, int(conf.Expire), userAuth.ID, roleIds)
+	// 登录信息验证通过后，生成 access token 和 refresh token, 属于一个新的令牌族
+	token, err := issueTokens(db, rdb, userAuth.ID, roleIds)
 	if err != nil {
 		// Token 生成失败，返回错误
 		ReturnError(c, global.ErrTokenCreate, err)
@@ -155,18 +202,91 @@ func (*UserAuth) Login(c *gin.Context) {
 	offlineKey := global.OFFLINE_USER + strconv.Itoa(userAuth.ID)
 	rdb.Del(rctx, offlineKey).Result()
 
-	// 返回成功响应，携带用户信息、文章点赞记录、评论点赞记录和 JWT Token
+	// 清除登录失败次数
+	clearLoginFailure(rdb, userAuth.Username)
+
+	// 返回成功响应，携带用户信息、文章点赞记录、评论点赞记录和 Token
 	ReturnSuccess(c, LoginVO{
 		UserInfo:       *userInfo,      // 返回用户信息
 		ArticleLikeSet: articleLikeSet, // 返回用户的文章点赞记录
 		CommentLikeSet: commentLikeSet, // 返回用户的评论点赞记录
-		Token:          token,          // 返回生成的 JWT Token
+		TokenVO:        token,          // 返回生成的 Token
+		RecoveryCodes:  recoveryCodes,  // 登录时开启两步验证生成的恢复码
 	})
 }
 
+// RefreshToken 使用 refresh token 获取新的 access token, 同时更换 refresh token
+// @Summary 刷新 token
+// @Description 每个 refresh token 只能使用一次, 重复使用会吊销同一次登录得到的所有 token
+// @Tags UserAuth
+// @Param form body RefreshTokenReq true "refresh token"
+// @Accept json
+// @Produce json
+// @Success 0 {object} Response[TokenVO]
+// @Router /refresh [post]
+func (*UserAuth) RefreshToken(c *gin.Context) {
+	var req RefreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	db := GetDB(c)
+	rdb := GetRDB(c)
+
+	record, hash, err := getRefreshToken(rdb, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			ReturnError(c, global.ErrRefreshToken, nil)
+			return
+		}
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	// 用户被禁用后不能再刷新, 同时吊销用户的所有令牌族
+	auth, err := model.GetUserAuthInfoById(db, record.UserId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ReturnError(c, global.ErrRefreshToken, nil)
+			return
+		}
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	if auth.IsDisable {
+		if err := RevokeUserTokens(rdb, auth.ID); err != nil {
+			ReturnError(c, global.ErrRedisOp, err)
+			return
+		}
+		ReturnError(c, global.ErrUserDisabled, nil)
+		return
+	}
+
+	// 重新获取角色, 使角色的变化在刷新后生效
+	roleIds, err := model.GetRoleIdsByUserId(db, record.UserId)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	token, err := rotateTokens(db, rdb, record, hash, roleIds)
+	if err != nil {
+		if errors.Is(err, errRefreshReused) {
+			slog.Warn("refresh token 已经使用过或者令牌族已吊销", "user_id", record.UserId, "ip", c.ClientIP())
+			ReturnError(c, global.ErrRefreshToken, nil)
+			return
+		}
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	ReturnSuccess(c, token)
+}
+
 // Register 完成注册功能
-// 首先检查用户名是否存在，避免重复注册；其次吧用户输入的信息加密保存在 redis 中，等待验证
-// 在以下情况下会出错：1-用户邮箱已经注册过；2-用户邮箱无效等原因导致邮件发送失败
+// 首先检查用户名是否存在，避免重复注册；其次把邮箱和密码哈希保存在 redis 中，发送只包含随机 token 的验证邮件
+// 在以下情况下会出错：1-用户邮箱已经注册过；2-发送过于频繁；3-用户邮箱无效等原因导致邮件发送失败
 // @Summary 注册
 // @Description 注册
 // @Tags UserAuth
@@ -184,36 +304,72 @@ func (*UserAuth) Register(c *gin.Context) {
 	// 格式化用户名
 	req.Username = utils.Format(req.Username)
 
-	// 检查用户名是否存在，避免重复注册
-	auth, err := model.GetUserAuthInfoByName(GetDB(c), req.Username)
-	if err != nil {
-		var flag = false
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			flag = true
-		}
-		if !flag {
-			ReturnError(c, global.ErrDbOp, err)
-			return
-		}
+	// 用户名重复，不能正常进行注册
+	if exist, err := isUserExist(GetDB(c), req.Username); err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	} else if exist {
+		ReturnError(c, global.ErrUserExist, nil)
+		return
 	}
 
-	// 用户名重复，不能正常进行注册
-	if auth != nil {
-		ReturnError(c, global.ErrUserExist, err)
+	hash, err := utils.BcryptHash(req.Password)
+	if err != nil {
+		ReturnError(c, global.ErrRequest, err)
 		return
 	}
 
 	// 通过邮箱验证后才可以完成注册
-	info := utils.GenEmailVerificationInfo(req.Username, req.Password)
-	err = SetMailInfo(GetRDB(c), info, 15*time.Minute)
+	token, err := savePendingRegister(GetRDB(c), req.Username, hash)
 	if err != nil {
-		ReturnError(c, global.ErrRedisOp, err)
+		returnEmailTokenError(c, err)
 		return
 	}
 
-	EmailData := utils.GetEmailData(req.Username, info)
-	err = utils.SendEmail(req.Username, EmailData)
+	if err := utils.SendEmail(req.Username, utils.GetEmailData(req.Username, token)); err != nil {
+		ReturnError(c, global.ErrSendEmail, err)
+		return
+	}
+
+	ReturnSuccess(c, nil)
+}
+
+type ResendEmailReq struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResendEmail 重新发送注册验证邮件, 之前的验证链接失效
+// @Summary 重新发送验证邮件
+// @Description 注册后 24 小时内可以重新发送, 验证链接的有效期为 15 分钟
+// @Tags UserAuth
+// @Param form body ResendEmailReq true "邮箱"
+// @Accept json
+// @Produce json
+// @Success 0 {object} string
+// @Router /email/resend [post]
+func (*UserAuth) ResendEmail(c *gin.Context) {
+	var req ResendEmailReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+	email := utils.Format(req.Email)
+
+	if exist, err := isUserExist(GetDB(c), email); err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	} else if exist {
+		ReturnError(c, global.ErrUserExist, nil)
+		return
+	}
+
+	token, err := resendEmailToken(GetRDB(c), email)
 	if err != nil {
+		returnEmailTokenError(c, err)
+		return
+	}
+
+	if err := utils.SendEmail(email, utils.GetEmailData(email, token)); err != nil {
 		ReturnError(c, global.ErrSendEmail, err)
 		return
 	}
@@ -222,43 +378,71 @@ func (*UserAuth) Register(c *gin.Context) {
 }
 
 // VerifyCode 邮箱验证
-// 当用户点击邮箱中的链接时，会携带info（加密后的帐号密码）向这个接口发送请求。
-// Verify会检查info是否存在redis中，若存在则认证成功，完成注册
-// 会在以下方面出错： 1. 发送信息中没有info 2. info不存在redis中(已过期) 3. 创造新用户失败（数据库操作失败）
+// 当用户点击邮箱中的链接时，会携带 token 向这个接口发送请求。
+// Verify 会根据 token 找到待验证的账号，若存在且未过期则认证成功，完成注册
+// 默认返回 HTML 页面, 请求参数 format=json 或者请求头 Accept 为 application/json 时返回 JSON
+// 会在以下方面出错： 1. 发送信息中没有 token 2. token 无效或已过期 3. 创造新用户失败（数据库操作失败）
+// @Summary 邮箱验证
+// @Description 邮箱验证
+// @Tags UserAuth
+// @Param token query string true "验证链接中的 token"
+// @Param format query string false "json 表示返回 JSON"
+// @Produce html,json
+// @Success 0 {object} string
+// @Router /email/verify [get]
 func (*UserAuth) VerifyCode(c *gin.Context) {
-	var code string
-	if code = c.Query("info"); code == "" {
-		returnErrorPage(c)
+	token := c.Query("token")
+	if token == "" {
+		returnVerifyError(c, global.ErrEmailToken, nil)
 		return
 	}
-	// 验证是否在 redis 数据库中
-	ifExist, err := GetMailInfo(GetRDB(c), code)
-	if err != nil {
-		returnErrorPage(c)
+
+	rdb := GetRDB(c)
+	record, err := consumeEmailToken(rdb, token)
+	switch {
+	case errors.Is(err, errEmailToken):
+		returnVerifyError(c, global.ErrEmailToken, nil)
 		return
-	}
-	if !ifExist {
-		returnErrorPage(c)
+	case errors.Is(err, errEmailExpired):
+		returnVerifyError(c, global.ErrEmailExpired, nil)
+		return
+	case err != nil:
+		returnVerifyError(c, global.ErrRedisOp, err)
 		return
 	}
 
-	err = DeleteMailInfo(GetRDB(c), code)
-	if err != nil {
-		returnErrorPage(c)
+	// 验证期间邮箱可能已经被注册
+	db := GetDB(c)
+	if exist, err := isUserExist(db, record.Email); err != nil {
+		if err := restoreEmailToken(rdb, record); err != nil {
+			slog.Error("恢复邮箱验证 token 失败", "email", record.Email, "err", err)
+		}
+		returnVerifyError(c, global.ErrDbOp, err)
+		return
+	} else if exist {
+		if err := deletePendingRegister(rdb, record); err != nil {
+			slog.Error("删除待验证账号失败", "email", record.Email, "err", err)
+		}
+		returnVerifyError(c, global.ErrUserExist, nil)
 		return
 	}
 
-	// 从 code 中解析出来 用户名 和 密码
-	username, password, err := utils.ParseEmailVerificationInfo(code)
+	// 注册用户, 成功后再删除待验证的账号
+	_, _, _, err = model.CreateNewUser(db, record.Email, record.Password, global.GetConfig().RegisterRole())
 	if err != nil {
-		returnErrorPage(c)
+		slog.Error("注册用户失败", "username", record.Email, "err", err)
+		if err := restoreEmailToken(rdb, record); err != nil {
+			slog.Error("恢复邮箱验证 token 失败", "email", record.Email, "err", err)
+		}
+		returnVerifyError(c, global.ErrDbOp, err)
 		return
 	}
+	if err := deletePendingRegister(rdb, record); err != nil {
+		slog.Error("删除待验证账号失败", "email", record.Email, "err", err)
+	}
 
-	// 注册用户
-	_, _, _, err = model.CreateNewUser(GetDB(c), username, password)
-	if err != nil {
-		returnErrorPage(c)
+	if wantJSON(c) {
+		ReturnSuccess(c, nil)
 		return
 	}
 
@@ -305,15 +489,173 @@ func (*UserAuth) VerifyCode(c *gin.Context) {
     `))
 }
 
+type ForgotPasswordReq struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=4,max=20"`
+}
+
+// ForgotPassword 申请重置密码, 向邮箱发送重置密码链接
+// 邮箱未注册或者用户已禁用时同样返回成功, 避免通过该接口判断邮箱是否注册
+// @Summary 申请重置密码
+// @Description 同一邮箱每小时最多 3 次, 同一 IP 每小时最多 10 次
+// @Tags UserAuth
+// @Param form body ForgotPasswordReq true "邮箱"
+// @Accept json
+// @Produce json
+// @Success 0 {object} string
+// @Router /password/forgot [post]
+func (*UserAuth) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+	email := utils.Format(req.Email)
+
+	// 重置密码链接只使用配置文件中的网站地址, 不能使用可以被修改的 website_url 或者请求中的 Host
+	site := global.GetConfig().SiteURL()
+	if site == "" {
+		ReturnError(c, global.ErrSendEmail, "未配置网站地址 Server.SiteURL")
+		return
+	}
+
+	db, rdb := GetDB(c), GetRDB(c)
+	if !checkRateLimit(c, rdb, resetIPLimit, c.ClientIP()) || !checkRateLimit(c, rdb, resetEmailLimit, email) {
+		return
+	}
+
+	auth, err := model.GetUserAuthInfoByName(db, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && auth.IsDisable) {
+		slog.Info("申请重置密码的用户不存在或已禁用", "email", email, "ip", c.ClientIP())
+		ReturnSuccess(c, nil)
+		return
+	}
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	token, err := savePasswordResetToken(rdb, auth.ID)
+	if err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	link := site + "/reset-password?token=" + url.QueryEscape(token)
+	if err := utils.SendEmail(email, utils.GetPasswordResetEmailData(email, link)); err != nil {
+		ReturnError(c, global.ErrSendEmail, err)
+		return
+	}
+
+	ReturnSuccess(c, nil)
+}
+
+// ResetPassword 使用邮件中的链接重置密码, 重置后吊销用户的所有 token 并强制下线
+// @Summary 重置密码
+// @Description 重置密码的链接只能使用一次
+// @Tags UserAuth
+// @Param form body ResetPasswordReq true "token 和新密码"
+// @Accept json
+// @Produce json
+// @Success 0 {object} string
+// @Router /password/reset [post]
+func (*UserAuth) ResetPassword(c *gin.Context) {
+	var req ResetPasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	rdb := GetRDB(c)
+	if !checkRateLimit(c, rdb, resetConfirmLimit, c.ClientIP()) {
+		return
+	}
+
+	userId, err := consumePasswordResetToken(rdb, req.Token)
+	if errors.Is(err, errResetToken) {
+		ReturnError(c, global.ErrResetToken, nil)
+		return
+	}
+	if err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	hash, err := utils.BcryptHash(req.Password)
+	if err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+	if err := model.UpdateUserPassword(GetDB(c), userId, hash); err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	// 之前登录的会话全部失效, 需要使用新密码重新登录
+	if err := forceOffline(rdb, userId, "重置密码"); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	slog.Info("用户通过邮件重置密码", "user_id", userId, "ip", c.ClientIP())
+	ReturnSuccess(c, nil)
+}
+
+// isUserExist 用户名是否已经注册
+func isUserExist(db *gorm.DB, username string) (bool, error) {
+	_, err := model.GetUserAuthInfoByName(db, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// returnEmailTokenError 保存或者重新发送验证邮件 token 失败
+func returnEmailTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, redis.Nil):
+		ReturnError(c, global.ErrCodeNoexit, nil)
+	case errors.Is(err, errEmailFrequent):
+		ReturnError(c, global.ErrEmailFrequent, nil)
+	default:
+		ReturnError(c, global.ErrRedisOp, err)
+	}
+}
+
+// wantJSON 邮箱验证是否返回 JSON, 默认返回 HTML 页面
+func wantJSON(c *gin.Context) bool {
+	return c.Query("format") == "json" || c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+}
+
+// returnVerifyError 邮箱验证失败, 根据请求返回 JSON 或者错误页面
+func returnVerifyError(c *gin.Context, r global.Result, err error) {
+	if wantJSON(c) {
+		ReturnError(c, r, err)
+		return
+	}
+	if err != nil {
+		slog.Error("邮箱验证失败", "err", err)
+	}
+	status := http.StatusBadRequest
+	if r == global.ErrDbOp || r == global.ErrRedisOp {
+		status = http.StatusInternalServerError
+	}
+	returnErrorPage(c, status, "注册失败", r.Msg())
+}
+
 // c.Data 可以用来直接返回原始字节数据，而不是使用 Gin 中的 c.JSON、c.String 等方法。它特别适合于返回 非结构化数据，例如 HTML 页面、文本或文件。
-func returnErrorPage(c *gin.Context) {
-	c.Data(http.StatusInternalServerError, "text/html; charset=utf-8", []byte(`
+func returnErrorPage(c *gin.Context, status int, title, msg string) {
+	c.Data(status, "text/html; charset=utf-8", []byte(`
         <!DOCTYPE html>
         <html lang="zh-CN">
         <head>
             <meta charset="UTF-8">
             <meta name="viewport" content="width=device-width, initial-scale=1.0">
-            <title>注册失败</title>
+            <title>`+html.EscapeString(title)+`</title>
             <style>
                 body {
                     font-family: Arial, sans-serif;
@@ -341,16 +683,15 @@ func returnErrorPage(c *gin.Context) {
         </head>
         <body>
             <div class="container">
-                <h1>注册失败</h1>
-                <p>请重试。</p>
+                <h1>`+html.EscapeString(title)+`</h1>
+                <p>`+html.EscapeString(msg)+`</p>
             </div>
         </body>
         </html>
     `))
 }
 
-// Logout 退出登录
-// TODO：退出登录之后，应该将 jwt Token 失效就可以，session 的操作有些多余
+// Logout 退出登录, 同时吊销当前 token 所属的令牌族
 // @Summary 退出登录
 // @Description 退出登录
 // @Tags UserAuth
@@ -362,6 +703,15 @@ func (*UserAuth) Logout(c *gin.Context) {
 	// 防止其他请求设置干扰
 	c.Set(global.CTX_USER_AUTH, nil)
 
+	// 吊销请求中携带的 token, 已经过期或者不正确的 token 不需要处理
+	rdb := GetRDB(c)
+	if claims, err := ParseToken(GetDB(c), bearerToken(c)); err == nil {
+		if err := revokeToken(rdb, claims); err != nil {
+			ReturnError(c, global.ErrRedisOp, err)
+			return
+		}
+	}
+
 	// 已经退出登录
 	auth, _ := CurrentUserAuth(c)
 	if auth == nil {
@@ -374,7 +724,6 @@ func (*UserAuth) Logout(c *gin.Context) {
 	session.Save()
 
 	// 删除 Redis 中的在线状态
-	rdb := GetRDB(c)
 	onlineKey := global.ONLINE_USER + strconv.Itoa(auth.ID)
 	rdb.Del(rctx, onlineKey)
 	ReturnSuccess(c, nil)
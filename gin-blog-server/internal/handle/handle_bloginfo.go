@@ -116,6 +116,7 @@ func (*BlogInfo) GetConfigMap(c *gin.Context) {
 // @Produce json
 // @Param data body map[string]string true "更新配置信息"
 // @Success 0 {object} Response[any]
+// @Security ApiKeyAuth
 // @Router /config [patch]
 func (*BlogInfo) UpdateConfig(c *gin.Context) {
 	var m map[string]string
@@ -134,6 +135,11 @@ func (*BlogInfo) UpdateConfig(c *gin.Context) {
 		ReturnError(c, global.ErrRedisOp, err)
 		return
 	}
+	// 订阅源等缓存中包含网站名称等配置
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
 
 	ReturnSuccess(c, nil)
 }
@@ -12,6 +12,7 @@ type Category struct{}
 type AddOrEditCategoryReq struct {
 	ID   int    `json:"id"`
 	Name string `json:"name" binding:"required"`
+	Slug string `json:"slug"` // 为空时根据名称生成
 }
 
 // GetList 获取分类列表
@@ -64,12 +65,18 @@ func (*Category) SaveOrUpdate(c *gin.Context) {
 		return
 	}
 
-	category, err := model.SaveOrUpdateCategory(GetDB(c), req.ID, req.Name)
+	category, err := model.SaveOrUpdateCategory(GetDB(c), req.ID, req.Name, req.Slug)
 	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
 
+	// 订阅源, sitemap 中包含分类
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
 	ReturnSuccess(c, category)
 }
 
@@ -109,6 +116,10 @@ func (*Category) Delete(c *gin.Context) {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
 	ReturnSuccess(c, rows)
 }
 
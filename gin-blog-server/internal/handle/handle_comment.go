@@ -42,7 +42,12 @@ func (*Comment) GetList(c *gin.Context) {
 		return
 	}
 
-	list, total, err := model.GetCommentList(GetDB(c), query.Page, query.Size, query.Type, query.IsReview, query.Nickname)
+	scope, ok := dataScope(c, model.DATA_COMMENT)
+	if !ok {
+		return
+	}
+
+	list, total, err := model.GetCommentList(GetDB(c), scope, query.Page, query.Size, query.Type, query.IsReview, query.Nickname)
 	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
@@ -73,13 +78,18 @@ func (*Comment) Delete(c *gin.Context) {
 		return
 	}
 
-	result := GetDB(c).Model(&model.Comment{}).Delete("id in ?", ids)
-	if result.Error != nil {
-		ReturnError(c, global.ErrDbOp, result.Error)
+	scope, ok := dataScope(c, model.DATA_COMMENT)
+	if !ok {
 		return
 	}
 
-	ReturnSuccess(c, result.RowsAffected)
+	rows, err := model.DeleteComments(GetDB(c), scope, ids)
+	if err != nil {
+		returnDataError(c, err)
+		return
+	}
+
+	ReturnSuccess(c, rows)
 }
 
 // UpdateReview 修改评论审核（批量）
@@ -99,12 +109,16 @@ func (*Comment) UpdateReview(c *gin.Context) {
 		return
 	}
 
-	maps := map[string]any{"is_review": req.IsReview}
-	result := GetDB(c).Model(model.Comment{}).Where("id in ?", req.Ids).Updates(maps)
-	if result.Error != nil {
-		ReturnError(c, global.ErrDbOp, result.Error)
+	scope, ok := dataScope(c, model.DATA_COMMENT)
+	if !ok {
+		return
+	}
+
+	rows, err := model.UpdateCommentsReview(GetDB(c), scope, req.Ids, req.IsReview)
+	if err != nil {
+		returnDataError(c, err)
 		return
 	}
 
-	ReturnSuccess(c, result.RowsAffected)
+	ReturnSuccess(c, rows)
 }
@@ -0,0 +1,221 @@
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils"
+	"gin-blog-server/internal/utils/feed"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// feedSize 订阅源中的文章数量
+const feedSize = 20
+
+// feedFormats 订阅源格式: 路由的最后一段 => Content-Type, 生成函数
+var feedFormats = map[string]struct {
+	contentType string
+	marshal     func(*feed.Feed) ([]byte, error)
+}{
+	"feed.xml":  {feed.RSSContentType, (*feed.Feed).RSS},
+	"atom.xml":  {feed.AtomContentType, (*feed.Feed).Atom},
+	"feed.json": {feed.JSONContentType, (*feed.Feed).JSON},
+}
+
+// GetFeed 订阅源: feed.xml (RSS 2.0), atom.xml (Atom), feed.json (JSON Feed)
+// 支持全站, 分类 (/category/:category/feed.xml), 标签 (/tag/:tag/feed.xml) 三种订阅源, 分类和标签可以使用 id 或 slug
+// 生成结果缓存在 Redis 中, 并支持 ETag/Last-Modified 条件请求
+func (*Front) GetFeed(c *gin.Context) {
+	db := GetDB(c)
+	rdb := GetRDB(c)
+
+	name := path.Base(c.FullPath())
+	format, ok := feedFormats[name]
+	if !ok {
+		ReturnError(c, global.ErrRequest, "不支持的订阅源格式: "+name)
+		return
+	}
+
+	var categoryId, tagId int
+	if key := c.Param("category"); key != "" {
+		if categoryId, ok = resolveSlug(c, db, model.SLUG_CATEGORY, key, global.ErrCateNotExist); !ok {
+			return
+		}
+	}
+	if key := c.Param("tag"); key != "" {
+		if tagId, ok = resolveSlug(c, db, model.SLUG_TAG, key, global.ErrTagNotExist); !ok {
+			return
+		}
+	}
+
+	key := fmt.Sprintf("%s%s:%d:%d", global.ARTICLE_FEED, name, categoryId, tagId)
+	doc, err := getDocumentCache(rdb, key)
+	if err != nil {
+		f, err := buildFeed(c, categoryId, tagId)
+		if errors.Is(err, errSiteURL) {
+			ReturnError(c, global.ErrSiteURL, err)
+			return
+		}
+		if err != nil {
+			ReturnError(c, global.ErrDbOp, err)
+			return
+		}
+		body, err := format.marshal(f)
+		if err != nil {
+			ReturnError(c, global.FailResult, err)
+			return
+		}
+
+		doc = newCachedDocument(body, f.Updated)
+		if err := addDocumentCache(rdb, key, doc); err != nil {
+			slog.Warn("写入订阅源缓存失败", "key", key, "err", err)
+		}
+	}
+
+	serveDocument(c, doc, format.contentType)
+}
+
+// buildFeed 根据博客配置和最新的文章生成订阅源, 私密文章只输出摘要
+func buildFeed(c *gin.Context, categoryId, tagId int) (*feed.Feed, error) {
+	db := GetDB(c)
+	rdb := GetRDB(c)
+
+	config, err := getConfigMap(db, rdb)
+	if err != nil {
+		return nil, err
+	}
+	site := siteURL(config) // 前台通过同域名下的 /api 代理访问后台接口
+	if site == "" {
+		return nil, errSiteURL
+	}
+
+	f := &feed.Feed{
+		Title:       config[global.CONFIG_WEBSITE_NAME],
+		Link:        site,
+		FeedLink:    site + c.Request.URL.Path,
+		Description: config[global.CONFIG_WEBSITE_INTRO],
+		Author:      config[global.CONFIG_WEBSITE_AUTHOR],
+		Language:    "zh-CN",
+	}
+
+	switch {
+	case categoryId != 0:
+		category, err := model.GetCategoryById(db, categoryId)
+		if err != nil {
+			return nil, err
+		}
+		f.Title += " - " + category.Name
+		f.Link = site + "/categories/" + slugKey(category.ID, category.Slug)
+	case tagId != 0:
+		tag, err := model.GetTagById(db, tagId)
+		if err != nil {
+			return nil, err
+		}
+		f.Title += " - " + tag.Name
+		f.Link = site + "/tags/" + slugKey(tag.ID, tag.Slug)
+	}
+
+	list, _, err := model.GetBlogArticleList(db, 1, feedSize, categoryId, tagId)
+	if err != nil {
+		return nil, err
+	}
+	// 列表中置顶文章在前, 订阅源按发布时间排序
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+
+	for _, article := range list {
+		item := feed.Item{
+			Title:     article.Title,
+			Link:      site + "/article/" + slugKey(article.ID, article.Slug),
+			Summary:   article.Desc,
+			Image:     article.Img,
+			Published: article.CreatedAt,
+			Updated:   article.UpdatedAt,
+		}
+		if article.Category != nil {
+			item.Categories = append(item.Categories, article.Category.Name)
+		}
+		for _, tag := range article.Tags {
+			item.Categories = append(item.Categories, tag.Name)
+		}
+		if article.Status != model.STATUS_SECRET {
+			rendered, err := renderArticle(rdb, &article)
+			if err != nil {
+				return nil, err
+			}
+			item.Content = rendered.HTML
+		}
+		if article.UpdatedAt.After(f.Updated) {
+			f.Updated = article.UpdatedAt
+		}
+		f.Items = append(f.Items, item)
+	}
+	if f.Updated.IsZero() {
+		f.Updated = time.Now()
+	}
+	return f, nil
+}
+
+// errSiteURL 博客配置 website_url 和配置文件 Server.SiteURL 都没有配置
+var errSiteURL = errors.New("未配置网站地址 website_url 或 Server.SiteURL")
+
+// siteURL 前台网站地址, 优先使用博客配置中的 website_url, 未配置时使用配置文件中的 Server.SiteURL, 都未配置时为空
+// 只用于订阅源, sitemap 等公开内容中的链接, 邮件和跳转等安全相关的地址只使用 Server.SiteURL
+// 生成的内容会被缓存并返回给所有请求, 不能使用请求中的 Host
+func siteURL(config map[string]string) string {
+	if url := config[global.CONFIG_WEBSITE_URL]; url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return global.GetConfig().SiteURL()
+}
+
+// slugKey 文章, 分类, 标签链接中使用的标识, 优先使用 slug
+func slugKey(id int, slug string) string {
+	if slug != "" {
+		return slug
+	}
+	return strconv.Itoa(id)
+}
+
+// cachedDocument 缓存的 订阅源/sitemap 等文档, 以及用于条件请求的 ETag 和最后修改时间
+type cachedDocument struct {
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+func newCachedDocument(body []byte, lastModified time.Time) *cachedDocument {
+	return &cachedDocument{
+		Body:         body,
+		ETag:         `"` + utils.MD5(string(body)) + `"`,
+		LastModified: lastModified.UTC().Truncate(time.Second), // HTTP 时间精确到秒
+	}
+}
+
+// serveDocument 返回文档, 请求头中的 If-None-Match 或 If-Modified-Since 匹配时返回 304
+func serveDocument(c *gin.Context, doc *cachedDocument, contentType string) {
+	c.Header("ETag", doc.ETag)
+	c.Header("Last-Modified", doc.LastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "public, max-age=0, must-revalidate")
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if match == "*" || strings.Contains(match, doc.ETag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !doc.LastModified.After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, doc.Body)
+}
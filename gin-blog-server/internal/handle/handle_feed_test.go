@@ -0,0 +1,20 @@
+package handle
+
+import (
+	"gin-blog-server/internal/global"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSiteURL(t *testing.T) {
+	global.Conf = &global.Config{}
+
+	// 都未配置时为空, 不使用请求中的 Host
+	assert.Equal(t, "", siteURL(map[string]string{}))
+
+	global.Conf.Server.SiteURL = "https://blog.example.com/"
+	assert.Equal(t, "https://blog.example.com", siteURL(map[string]string{}))
+
+	config := map[string]string{global.CONFIG_WEBSITE_URL: "https://www.example.com/"}
+	assert.Equal(t, "https://www.example.com", siteURL(config))
+}
@@ -1,10 +1,14 @@
 package handle
 
 import (
+	"errors"
 	"gin-blog-server/internal/global"
 	"gin-blog-server/internal/model"
 	"gin-blog-server/internal/utils"
+	"gin-blog-server/internal/utils/jwt"
+	"gin-blog-server/internal/utils/search"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"html/template"
 	"strconv"
 	"strings"
@@ -15,18 +19,48 @@ type Front struct{}
 
 type FArticleQuery struct {
 	PageQuery
-	CategoryId int `form:"category_id"`
-	TagId      int `form:"tag_id"`
+	CategoryId   int    `form:"category_id"`
+	TagId        int    `form:"tag_id"`
+	CategorySlug string `form:"category_slug"` // 优先于 category_id
+	TagSlug      string `form:"tag_slug"`      // 优先于 tag_id
 }
 
+// FArticleVO 前台文章列表项, 私密文章不返回正文
+type FArticleVO struct {
+	model.Article
+	IsLocked bool `json:"is_locked"` // 私密文章, 需要密码访问
+}
+
+// FUnlockArticleReq 使用密码解锁私密文章
+type FUnlockArticleReq struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// FArticleTokenVO 私密文章的访问凭证, 访问文章详情时通过 X-Article-Token 请求头传递
+type FArticleTokenVO struct {
+	Token    string    `json:"token"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+// 私密文章访问凭证的有效期
+const articleTokenExpire = 2 * time.Hour
+
+// 私密文章密码尝试次数限制, 防止暴力破解: 每个 IP, 以及每篇文章 (防止使用多个 IP)
+var (
+	unlockIPLimit      = rateLimit{Scene: "unlock_ip", Limit: 20, Window: 10 * time.Minute}
+	unlockArticleLimit = rateLimit{Scene: "unlock_article", Limit: 100, Window: 10 * time.Minute}
+)
+
 type ArchiveVO struct {
 	ID        int       `json:"id"`
+	Slug      string    `json:"slug"`
 	Title     string    `json:"title"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 type ArticleSearchVO struct {
 	ID      int    `json:"id"`
+	Slug    string `json:"slug"`
 	Title   string `json:"title"`
 	Content string `json:"content"`
 }
@@ -97,6 +131,9 @@ func (*Front) GetArticleList(c *gin.Context) {
 		ReturnError(c, global.ErrRequest, err)
 		return
 	}
+	if !resolveArticleQuery(c, &query) {
+		return
+	}
 
 	list, _, err := model.GetBlogArticleList(GetDB(c), query.Page, query.Size, query.CategoryId, query.TagId)
 	if err != nil {
@@ -104,20 +141,30 @@ func (*Front) GetArticleList(c *gin.Context) {
 		return
 	}
 
-	ReturnSuccess(c, list)
+	data := make([]FArticleVO, 0, len(list))
+	for _, article := range list {
+		vo := FArticleVO{Article: article}
+		if article.Status == model.STATUS_SECRET {
+			vo.Content = ""
+			vo.IsLocked = true
+		}
+		data = append(data, vo)
+	}
+
+	ReturnSuccess(c, data)
 }
 
-// GetArticleInfo 根据 [文章id] 获取 [文章详情]
+// GetArticleInfo 根据 [文章id 或 slug] 获取 [文章详情]
+// 使用旧的 slug 访问时, 返回数据中的 slug 为当前的 slug, 前台可以据此更新地址
 func (*Front) GetArticleInfo(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		ReturnError(c, global.ErrRequest, err)
-		return
-	}
-
 	db := GetDB(c)
 	rdb := GetRDB(c)
 
+	id, ok := resolveSlug(c, db, model.SLUG_ARTICLE, c.Param("id"), global.ErrArticleNotExist)
+	if !ok {
+		return
+	}
+
 	// 文章详情
 	val, err := model.GetBlogArticle(db, id)
 	if err != nil {
@@ -127,6 +174,22 @@ func (*Front) GetArticleInfo(c *gin.Context) {
 
 	article := model.BlogArticleVO{Article: *val}
 
+	// 私密文章需要有效的访问凭证才返回正文
+	if val.Status == model.STATUS_SECRET && !hasArticleAccess(c, id) {
+		article.Content = ""
+		article.IsLocked = true
+	} else {
+		rendered, err := renderArticle(rdb, val)
+		if err != nil {
+			ReturnError(c, global.ErrArticleRender, err)
+			return
+		}
+		article.HTML = rendered.HTML
+		article.TOC = rendered.TOC
+		article.WordCount = rendered.WordCount
+		article.ReadingMinutes = rendered.ReadingMinutes
+	}
+
 	// 推荐文章 - 6篇
 	article.RecommendArticles, err = model.GetRecommendList(db, id, 6)
 	if err != nil {
@@ -177,6 +240,72 @@ func (*Front) GetArticleInfo(c *gin.Context) {
 	ReturnSuccess(c, article)
 }
 
+// UnlockArticle 使用密码解锁私密文章, 返回只能访问该文章的短期凭证
+func (*Front) UnlockArticle(c *gin.Context) {
+	var req FUnlockArticleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	db := GetDB(c)
+
+	id, ok := resolveSlug(c, db, model.SLUG_ARTICLE, c.Param("id"), global.ErrArticleNotExist)
+	if !ok {
+		return
+	}
+
+	article, err := model.GetBlogArticle(db, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ReturnError(c, global.ErrArticleNotExist, nil)
+			return
+		}
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	if article.Status != model.STATUS_SECRET {
+		ReturnError(c, global.ErrRequest, "该文章不需要密码访问")
+		return
+	}
+
+	rdb := GetRDB(c)
+	if !checkRateLimit(c, rdb, unlockIPLimit, c.ClientIP()) ||
+		!checkRateLimit(c, rdb, unlockArticleLimit, strconv.Itoa(id)) {
+		return
+	}
+	if article.Password == "" || !utils.BcryptCheck(req.Password, article.Password) {
+		ReturnError(c, global.ErrArticlePassword, nil)
+		return
+	}
+
+	conf := global.GetConfig().JWT
+	token, err := jwt.GenArticleToken(conf.Secret, conf.Issuer, id, articleTokenExpire)
+	if err != nil {
+		ReturnError(c, global.ErrTokenCreate, err)
+		return
+	}
+
+	ReturnSuccess(c, FArticleTokenVO{
+		Token:    token,
+		ExpireAt: time.Now().Add(articleTokenExpire),
+	})
+}
+
+// hasArticleAccess 判断请求是否携带了该私密文章的有效访问凭证
+// 凭证通过 X-Article-Token 请求头或 article_token 查询参数传递
+func hasArticleAccess(c *gin.Context, articleId int) bool {
+	token := c.GetHeader("X-Article-Token")
+	if token == "" {
+		token = c.Query("article_token")
+	}
+	if token == "" {
+		return false
+	}
+	return jwt.ParseArticleToken(global.GetConfig().JWT.Secret, token, articleId) == nil
+}
+
 // GetArchiveList 获取文章归档
 func (*Front) GetArchiveList(c *gin.Context) {
 	var query FArticleQuery
@@ -184,6 +313,9 @@ func (*Front) GetArchiveList(c *gin.Context) {
 		ReturnError(c, global.ErrRequest, err)
 		return
 	}
+	if !resolveArticleQuery(c, &query) {
+		return
+	}
 
 	list, total, err := model.GetBlogArticleList(GetDB(c), query.Page, query.Size, query.CategoryId, query.TagId)
 	if err != nil {
@@ -195,6 +327,7 @@ func (*Front) GetArchiveList(c *gin.Context) {
 	for _, article := range list {
 		archives = append(archives, ArchiveVO{
 			ID:        article.ID,
+			Slug:      article.Slug,
 			Title:     article.Title,
 			CreatedAt: article.CreatedAt,
 		})
@@ -208,115 +341,87 @@ func (*Front) GetArchiveList(c *gin.Context) {
 	})
 }
 
-// SearchArticle 文章搜索
-// TODO：搜索是否可以使用 ES
+// SearchArticle 文章搜索, 使用进程内的全文索引, 按相关度排序并分页
 func (*Front) SearchArticle(c *gin.Context) {
-	result := make([]ArticleSearchVO, 0)
+	var query PageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+	page, size := normalizePage(query.Page, query.Size)
 
-	keyword := c.Query("keyword")
-	if keyword == "" {
-		ReturnSuccess(c, result)
+	data := PageResult[ArticleSearchVO]{Page: page, Size: size, List: make([]ArticleSearchVO, 0)}
+	if strings.TrimSpace(query.Keyword) == "" {
+		ReturnSuccess(c, data)
 		return
 	}
 
 	db := GetDB(c)
 
-	// 筛选出符合条件 article
-	articleList, err := model.List(db, []model.Article{}, "*", "",
-		"is_delete = 0 AND status = 1 AND (title LIKE ? OR content LIKE ?)",
-		"%"+keyword+"%", "%"+keyword+"%")
+	// 索引中包含所有未删除的文章, 需要筛选出前台可见的文章
+	results := getArticleIndex().Search(query.Keyword)
+	ids := make([]int, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	visibleIds, err := model.GetVisibleArticleIds(db, ids)
 	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
+	visible := make(map[int]bool, len(visibleIds))
+	for _, id := range visibleIds {
+		visible[id] = true
+	}
 
-	for _, article := range articleList {
-		// 高亮标题中的关键字
-		title := strings.ReplaceAll(article.Title, keyword,
-			"<span style='color:#f47466'>"+keyword+"</span>")
-
-		content := article.Content
-
-		// 关键字在内容中的起始位置
-		keywordStartIndex := unicodeIndex(content, keyword)
-		if keywordStartIndex != -1 { // 关键字在内容中
-			preIndex, afterIndex := 0, 0
-			// 不要截取太长的字符串
-			if keywordStartIndex > 25 {
-				preIndex = keywordStartIndex - 25
-			}
-
-			// 防止中文截取出乱码 (中文在 golang 是 3 个字符, 使用 rune 中文占一个数组下标)
-			preText := substring(content, preIndex, keywordStartIndex)
-
-			// 关键字在内容中的结束位置
-			keywordEndIndex := keywordStartIndex + unicodeLen(keyword)
-			afterLength := unicodeLen(content) - keywordEndIndex
-			if afterLength > 100 {
-				afterIndex = keywordEndIndex + 100
-			} else {
-				afterIndex = keywordEndIndex + afterLength
-			}
-
-			// 截取后续的文章内容
-			afterText := substring(content, keywordStartIndex, afterIndex)
-			// 高亮内容中的关键字
-			content = strings.ReplaceAll(preText+afterText, keyword,
-				"<span style='color:#f47466'>"+keyword+"</span>")
+	hits := make([]int, 0, len(visibleIds))
+	for _, id := range ids {
+		if visible[id] {
+			hits = append(hits, id)
 		}
+	}
+	data.Total = int64(len(hits))
+
+	start := min((page-1)*size, len(hits))
+	end := min(start+size, len(hits))
+	articles, err := model.GetArticleListByIds(db, model.ScopeAll, hits[start:end])
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	articleMap := make(map[int]model.Article, len(articles))
+	for _, article := range articles {
+		articleMap[article.ID] = article
+	}
 
-		result = append(result, ArticleSearchVO{
+	for _, id := range hits[start:end] {
+		article, ok := articleMap[id]
+		if !ok {
+			continue
+		}
+		data.List = append(data.List, ArticleSearchVO{
 			ID:      article.ID,
-			Title:   title,
-			Content: content,
+			Slug:    article.Slug,
+			Title:   search.Highlight(article.Title, query.Keyword, highlightPre, highlightPost),
+			Content: search.Snippet(article.Content, query.Keyword, snippetLength, highlightPre, highlightPost),
 		})
 	}
 
-	ReturnSuccess(c, result)
-}
-
-// 获取带中文的字符串中 子字符串 的实际位置，非字节位置
-func unicodeIndex(str, substr string) int {
-	// 子串在字符串中的字节位置
-	result := strings.Index(str, substr)
-	if result > 0 {
-		// 前缀字节数目
-		prefix := []byte(str)[0:result]
-		// 字节转换成字符
-		rs := []rune(string(prefix))
-		// 统计字符数目
-		result = len(rs)
-	}
-	return result
-}
-
-// 获取带中文的字符串实际长度，非字节长度
-func unicodeLen(str string) int {
-	var r = []rune(str)
-	return len(r)
+	ReturnSuccess(c, data)
 }
 
-// 解决中文获取位置不正确问题
-func substring(source string, start int, end int) string {
-	var unicodeStr = []rune(source)
-	length := len(unicodeStr)
-	if start >= end {
-		return ""
+// normalizePage 与 model.Paginate 保持一致的分页参数
+func normalizePage(page, size int) (int, int) {
+	if page <= 0 {
+		page = 1
 	}
-	if start < 0 {
-		start = 0
+	switch {
+	case size > 100:
+		size = 100
+	case size <= 10:
+		size = 10
 	}
-	if end > length {
-		end = length
-	}
-	if start <= 0 && end >= length {
-		return source
-	}
-	var substring = ""
-	for i := start; i < end; i++ {
-		substring += string(unicodeStr[i])
-	}
-	return substring
+	return page, size
 }
 
 // SaveComment 保存评论（只能新增，不能编辑）
@@ -452,7 +557,7 @@ func (*Front) LikeComment(c *gin.Context) {
 // GetMessageList 查询消息列表
 func (*Front) GetMessageList(c *gin.Context) {
 	isReview := true
-	list, _, err := model.GetMessageList(GetDB(c), 1, 1000, "", &isReview)
+	list, _, err := model.GetMessageList(GetDB(c), model.ScopeAll, 1, 1000, "", &isReview)
 	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
@@ -478,7 +583,7 @@ func (*Front) SaveMessage(c *gin.Context) {
 	isReview := model.GetConfigBool(db, global.CONFIG_IS_COMMENT_REVIEW)
 
 	info := auth.UserInfo
-	message, err := model.SaveMessage(db, info.Nickname, info.Nickname, req.Content, ipAddress, ipSource, req.Speed, isReview)
+	message, err := model.SaveMessage(db, auth.ID, info.Nickname, info.Nickname, req.Content, ipAddress, ipSource, req.Speed, isReview)
 	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
@@ -503,9 +608,8 @@ func (*Front) GetLinkList(c *gin.Context) {
 func (*Front) LikeArticle(c *gin.Context) {
 	auth, _ := CurrentUserAuth(c)
 
-	articleId, err := strconv.Atoi(c.Param("article_id"))
-	if err != nil {
-		ReturnError(c, global.ErrRequest, err)
+	articleId, ok := resolveSlug(c, GetDB(c), model.SLUG_ARTICLE, c.Param("article_id"), global.ErrArticleNotExist)
+	if !ok {
 		return
 	}
 
@@ -524,3 +628,35 @@ func (*Front) LikeArticle(c *gin.Context) {
 
 	ReturnSuccess(c, nil)
 }
+
+// resolveSlug 根据 id 或 slug (包括旧的 slug) 获取数据 id, 出错时直接返回错误响应
+func resolveSlug(c *gin.Context, db *gorm.DB, typ, key string, notExist global.Result) (int, bool) {
+	id, err := model.ResolveSlug(db, typ, key)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ReturnError(c, notExist, nil)
+			return 0, false
+		}
+		ReturnError(c, global.ErrDbOp, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// resolveArticleQuery 将文章查询中的 分类/标签 slug 转换为 id
+func resolveArticleQuery(c *gin.Context, query *FArticleQuery) bool {
+	db := GetDB(c)
+
+	var ok bool
+	if query.CategorySlug != "" {
+		if query.CategoryId, ok = resolveSlug(c, db, model.SLUG_CATEGORY, query.CategorySlug, global.ErrCateNotExist); !ok {
+			return false
+		}
+	}
+	if query.TagSlug != "" {
+		if query.TagId, ok = resolveSlug(c, db, model.SLUG_TAG, query.TagSlug, global.ErrTagNotExist); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -20,18 +20,9 @@ type MenuTreeVO struct {
 
 // GetUserMenu 获取当前用户菜单: 生成后台管理界面的菜单
 func (*Menu) GetUserMenu(c *gin.Context) {
-	db := GetDB(c)
 	auth, _ := CurrentUserAuth(c)
 
-	var menus []model.Menu
-	var err error
-
-	if auth.IsSuper { // 如果当前用户是超级管理员
-		menus, err = model.GetAllMenuList(db)
-	} else {
-		menus, err = model.GetMenuListByUserId(GetDB(c), auth.ID)
-	}
-
+	menus, err := userMenus(GetDB(c), auth)
 	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
@@ -40,6 +31,14 @@ func (*Menu) GetUserMenu(c *gin.Context) {
 	ReturnSuccess(c, menus2MenuVos(menus))
 }
 
+// userMenus 用户可以看到的菜单: 超级管理员为所有菜单, 其他用户为所有角色及其上级角色的菜单
+func userMenus(db *gorm.DB, auth *model.UserAuth) ([]model.Menu, error) {
+	if auth.IsSuper {
+		return model.GetAllMenuList(db)
+	}
+	return model.GetMenuListByUserId(db, auth.ID)
+}
+
 // GetTreeList 根据请求url中携带的 keyword 条件来获取对应的菜单列表
 func (*Menu) GetTreeList(c *gin.Context) {
 	keyword := c.Query("keyword")
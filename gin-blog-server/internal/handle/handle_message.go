@@ -35,7 +35,12 @@ func (*Message) GetList(c *gin.Context) {
 		return
 	}
 
-	data, total, err := model.GetMessageList(GetDB(c), query.Page, query.Size, query.Nickname, query.IsReview)
+	scope, ok := dataScope(c, model.DATA_MESSAGE)
+	if !ok {
+		return
+	}
+
+	data, total, err := model.GetMessageList(GetDB(c), scope, query.Page, query.Size, query.Nickname, query.IsReview)
 	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
@@ -66,9 +71,14 @@ func (*Message) Delete(c *gin.Context) {
 		return
 	}
 
-	rows, err := model.DeleteMessages(GetDB(c), ids)
+	scope, ok := dataScope(c, model.DATA_MESSAGE)
+	if !ok {
+		return
+	}
+
+	rows, err := model.DeleteMessages(GetDB(c), scope, ids)
 	if err != nil {
-		ReturnError(c, global.ErrDbOp, err)
+		returnDataError(c, err)
 		return
 	}
 
@@ -92,9 +102,14 @@ func (*Message) UpdateReview(c *gin.Context) {
 		return
 	}
 
-	rows, err := model.UpdateMessagesReview(GetDB(c), req.Ids, req.IsReview)
+	scope, ok := dataScope(c, model.DATA_MESSAGE)
+	if !ok {
+		return
+	}
+
+	rows, err := model.UpdateMessagesReview(GetDB(c), scope, req.Ids, req.IsReview)
 	if err != nil {
-		ReturnError(c, global.ErrDbOp, err)
+		returnDataError(c, err)
 		return
 	}
 
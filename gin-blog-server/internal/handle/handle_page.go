@@ -75,9 +75,14 @@ func (*Page) SaveOrUpdate(c *gin.Context) {
 	db := GetDB(c)
 	rdb := GetRDB(c)
 
-	page, err := model.SaveOrUpdatePage(db, req.ID, req.Name, req.Label, req.Cover)
+	scope, ok := dataScope(c, model.DATA_PAGE)
+	if !ok {
+		return
+	}
+
+	page, err := model.SaveOrUpdatePage(db, scope, req.ID, req.Name, req.Label, req.Cover)
 	if err != nil {
-		ReturnError(c, global.ErrDbOp, err)
+		returnDataError(c, err)
 		return
 	}
 
@@ -86,6 +91,11 @@ func (*Page) SaveOrUpdate(c *gin.Context) {
 		ReturnError(c, global.ErrRedisOp, err)
 		return
 	}
+	// sitemap 中包含页面
+	if err := RemoveArticleCache(rdb); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
 
 	ReturnSuccess(c, page)
 }
@@ -107,9 +117,14 @@ func (*Page) Delete(c *gin.Context) {
 		return
 	}
 
-	result := GetDB(c).Delete(model.Page{}, "id in ?", ids)
-	if result.Error != nil {
-		ReturnError(c, global.ErrDbOp, result.Error)
+	scope, ok := dataScope(c, model.DATA_PAGE)
+	if !ok {
+		return
+	}
+
+	rows, err := model.DeletePages(GetDB(c), scope, ids)
+	if err != nil {
+		returnDataError(c, err)
 		return
 	}
 
@@ -118,6 +133,10 @@ func (*Page) Delete(c *gin.Context) {
 		ReturnError(c, global.ErrRedisOp, err)
 		return
 	}
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
 
-	ReturnSuccess(c, result.RowsAffected)
+	ReturnSuccess(c, rows)
 }
@@ -12,6 +12,19 @@ import (
 
 type Resource struct{}
 
+// authRoutes 需要登录的接口路由 (后台管理系统, 博客前台需要登录的操作), 注册路由时设置, 用于同步资源表
+var authRoutes []model.Route
+
+// SetAuthRoutes 设置需要登录的接口路由
+func SetAuthRoutes(routes []model.Route) {
+	authRoutes = routes
+}
+
+// SyncResources 将需要登录的接口路由同步到资源表, 路由没有对应的资源时按照未注册资源的访问策略处理
+func SyncResources(db *gorm.DB) (*model.ResourceSyncResult, error) {
+	return model.SyncResources(db, authRoutes)
+}
+
 type TreeOptionVO struct {
 	ID       int            `json:"key"`
 	Label    string         `json:"label"`
@@ -25,6 +38,7 @@ type ResourceTreeVO struct {
 	Url       string           `json:"url"`
 	Method    string           `json:"request_method"`
 	Anonymous bool             `json:"is_anonymous"`
+	Stale     bool             `json:"is_stale"`
 	Children  []ResourceTreeVO `json:"children"`
 }
 
@@ -81,6 +95,7 @@ func resource2ResourceVo(r model.Resource) ResourceTreeVO {
 		Url:       r.Url,
 		Method:    r.Method,
 		Anonymous: r.Anonymous,
+		Stale:     r.Stale,
 		CreatedAt: r.CreatedAt,
 	}
 }
@@ -127,6 +142,7 @@ func (*Resource) SaveOrUpdate(c *gin.Context) {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
+	InvalidatePolicy(GetRDB(c))
 	ReturnSuccess(c, nil)
 }
 
@@ -173,6 +189,7 @@ func (*Resource) Delete(c *gin.Context) {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
+	InvalidatePolicy(GetRDB(c))
 
 	ReturnSuccess(c, rows)
 }
@@ -190,10 +207,22 @@ func (*Resource) UpdateAnonymous(c *gin.Context) {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
+	InvalidatePolicy(GetRDB(c))
 
 	ReturnSuccess(c, nil)
 }
 
+// Sync 同步接口路由到资源表, 返回新增和失效的资源
+func (*Resource) Sync(c *gin.Context) {
+	result, err := SyncResources(GetDB(c))
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	InvalidatePolicy(GetRDB(c))
+	ReturnSuccess(c, result)
+}
+
 // GetOption 获取数据选项(树形)
 func (*Resource) GetOption(c *gin.Context) {
 	result := make([]TreeOptionVO, 0)
@@ -2,27 +2,33 @@ package handle
 
 import (
 	"errors"
+	"fmt"
 	"gin-blog-server/internal/global"
 	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils/policy"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"slices"
 )
 
 type Role struct{}
 
-// AddOrEditRoleReq 新增/编辑 角色, 关联维护 role_resource, role_menu
+// AddOrEditRoleReq 新增/编辑 角色, 关联维护 role_resource, role_menu, role_scope
 type AddOrEditRoleReq struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name" binding:"required"`
-	Label       string `json:"label" binding:"required"`
-	IsDisable   bool   `json:"is_disable"`
-	ResourceIds []int  `json:"resource_ids"` // 资源 id 列表
-	MenuIds     []int  `json:"menu_ids"`     // 菜单 id 列表
+	ID          int               `json:"id"`
+	Name        string            `json:"name" binding:"required"`
+	Label       string            `json:"label" binding:"required"`
+	IsDisable   bool              `json:"is_disable"`
+	Require2FA  bool              `json:"require_2fa"`  // 拥有该角色的用户必须开启两步验证
+	ParentId    int               `json:"parent_id"`    // 上级角色 id, 继承上级角色的资源和菜单
+	ResourceIds []int             `json:"resource_ids"` // 资源 id 列表
+	MenuIds     []int             `json:"menu_ids"`     // 菜单 id 列表
+	Scopes      map[string]string `json:"scopes"`       // 数据权限: 数据类型 => all | own | none
 }
 
-// GetTreeList 获取角色列表
+// GetTreeList 获取角色树, 按照继承关系组装, 分页以顶层角色为单位
 // @Summary 获取角色列表
-// @Description 获取角色列表
+// @Description 获取角色树, 下级角色在 children 中
 // @Tags role
 // @Produce json
 // @Param keyword query string false "关键字"
@@ -38,28 +44,73 @@ func (*Role) GetTreeList(c *gin.Context) {
 	}
 
 	db := GetDB(c)
-	result := make([]model.RoleVO, 0)
 
-	list, total, err := model.GetRoleList(db, query.Page, query.Size, query.Keyword)
+	list, err := model.GetRoleList(db, query.Keyword)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
 
-	for _, role := range list {
-		role.ResourceIds, _ = model.GetResourceIdsByRoleId(db, role.ID)
-		role.MenuIds, _ = model.GetMenuIdsByRoleId(db, role.ID)
-		result = append(result, role)
+	for i := range list {
+		list[i].ResourceIds, _ = model.GetResourceIdsByRoleId(db, list[i].ID)
+		list[i].MenuIds, _ = model.GetMenuIdsByRoleId(db, list[i].ID)
+		list[i].Scopes, _ = model.GetScopesByRoleId(db, list[i].ID)
 	}
 
+	tree := roleTree(list)
+	total := len(tree)
+	start, end := pageRange(query.Page, query.Size, total)
+
 	ReturnSuccess(c, PageResult[model.RoleVO]{
 		Size:  query.Size,
 		Page:  query.Page,
-		Total: total,
-		List:  result,
+		Total: int64(total),
+		List:  tree[start:end],
 	})
 }
 
+// roleTree 按照继承关系组装角色树, 上级角色不在列表中 (例如被关键字过滤) 的角色作为顶层角色
+func roleTree(list []model.RoleVO) []model.RoleVO {
+	exist := make(map[int]bool, len(list))
+	for _, role := range list {
+		exist[role.ID] = true
+	}
+
+	var build func(pid int, visited map[int]bool) []model.RoleVO
+	build = func(pid int, visited map[int]bool) []model.RoleVO {
+		children := make([]model.RoleVO, 0)
+		for _, role := range list {
+			if role.ParentId != pid || visited[role.ID] {
+				continue
+			}
+			visited[role.ID] = true
+			role.Children = build(role.ID, visited)
+			children = append(children, role)
+		}
+		return children
+	}
+
+	visited := make(map[int]bool, len(list))
+	tree := make([]model.RoleVO, 0)
+	for _, role := range list {
+		if role.ParentId != 0 && exist[role.ParentId] {
+			continue
+		}
+		visited[role.ID] = true
+		role.Children = build(role.ID, visited)
+		tree = append(tree, role)
+	}
+	return tree
+}
+
+// pageRange 内存分页: 第 page 页在长度为 total 的列表中的范围, 分页参数与 model.Paginate 保持一致
+func pageRange(page, size, total int) (start, end int) {
+	page, size = normalizePage(page, size)
+	start = min((page-1)*size, total)
+	end = min(start+size, total)
+	return start, end
+}
+
 // SaveOrUpdate 删除角色
 func (*Role) SaveOrUpdate(c *gin.Context) {
 	var req AddOrEditRoleReq
@@ -68,25 +119,45 @@ func (*Role) SaveOrUpdate(c *gin.Context) {
 		return
 	}
 
+	for data, scope := range req.Scopes {
+		if !slices.Contains(model.DataTypes, data) || !policy.ValidScope(scope) {
+			ReturnError(c, global.ErrRequest, fmt.Sprintf("数据权限错误: %s => %s", data, scope))
+			return
+		}
+	}
+
 	db := GetDB(c)
 
 	if req.ID == 0 {
-		err := model.SaveRole(db, req.Name, req.Label)
+		err := model.SaveRole(db, req.Name, req.Label, req.ParentId)
 		if err != nil {
-			ReturnError(c, global.ErrDbOp, err)
+			returnRoleError(c, err)
 			return
 		}
 	} else {
-		err := model.UpdateRole(db, req.ID, req.Name, req.Label, req.IsDisable, req.ResourceIds, req.MenuIds)
+		err := model.UpdateRole(db, req.ID, req.Name, req.Label, req.IsDisable, req.Require2FA, req.ParentId, req.ResourceIds, req.MenuIds, req.Scopes)
 		if err != nil {
-			ReturnError(c, global.ErrDbOp, err)
+			returnRoleError(c, err)
 			return
 		}
+		InvalidatePolicy(GetRDB(c))
 	}
 
 	ReturnSuccess(c, nil)
 }
 
+// returnRoleError 返回保存角色的错误, 区分上级角色错误和数据库错误
+func returnRoleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrRoleCycle):
+		ReturnError(c, global.ErrRoleCycle, err)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ReturnError(c, global.ErrRoleNotExist, err)
+	default:
+		ReturnError(c, global.ErrDbOp, err)
+	}
+}
+
 // Delete 删除角色
 func (*Role) Delete(c *gin.Context) {
 	var ids []int
@@ -100,6 +171,7 @@ func (*Role) Delete(c *gin.Context) {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
+	InvalidatePolicy(GetRDB(c))
 
 	ReturnSuccess(c, nil)
 }
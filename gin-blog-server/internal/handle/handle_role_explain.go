@@ -0,0 +1,235 @@
+package handle
+
+import (
+	"errors"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils/policy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// ExplainQuery 权限诊断的参数, 不传 user_id 时诊断当前用户
+type ExplainQuery struct {
+	UserId int    `form:"user_id"`                   // 用户 id (user_auth_id)
+	Method string `form:"method" binding:"required"` // 请求方法
+	Path   string `form:"path" binding:"required"`   // 请求路径, 例如 /api/article/12 或者 /article/:id
+}
+
+// RoleDecisionVO 单个角色对接口的授权结果
+type RoleDecisionVO struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Label     string `json:"label"`
+	IsDisable bool   `json:"is_disable"`
+	Allow     bool   `json:"allow"`
+	GrantedBy int    `json:"granted_by"` // 授予权限的角色: 自己或者上级角色, 0 表示没有权限
+	Reason    string `json:"reason"`
+}
+
+// PermissionExplainVO 权限诊断结果: 与鉴权中间件的判断过程一致
+type PermissionExplainVO struct {
+	UserId       int              `json:"user_id"`
+	Username     string           `json:"username"`
+	IsSuper      bool             `json:"is_super"`
+	Method       string           `json:"method"`
+	Path         string           `json:"path"`
+	Route        string           `json:"route"`                         // 匹配到的路由, 没有匹配时为去掉 /api 前缀的路径
+	Public       bool             `json:"is_public"`                     // 是否是不需要登录的基础接口
+	Resource     *model.Resource  `json:"resource"`                      // 路由对应的资源, 为空表示没有注册资源
+	Anonymous    bool             `json:"is_anonymous"`                  // 是否是匿名资源
+	Unregistered string           `json:"unregistered_policy,omitempty"` // 没有注册资源时使用的访问策略
+	Roles        []RoleDecisionVO `json:"roles"`
+	Allow        bool             `json:"allow"` // 最终结果
+	Reason       string           `json:"reason"`
+}
+
+// Explain 权限诊断: 解释用户访问接口时被允许或拒绝的原因
+// @Summary 权限诊断
+// @Description 返回匹配的资源, 是否匿名, 每个角色的授权结果以及最终结果
+// @Tags role
+// @Produce json
+// @Param user_id query int false "用户 id, 不传时为当前用户"
+// @Param method query string true "请求方法"
+// @Param path query string true "请求路径"
+// @Success 0 {object} Response[PermissionExplainVO]
+// @Router /role/explain [get]
+func (*Role) Explain(c *gin.Context) {
+	var query ExplainQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	auth, ok := explainUser(c, query.UserId)
+	if !ok {
+		return
+	}
+
+	db := GetDB(c)
+	method := strings.ToUpper(query.Method)
+	route := routeOf(method, query.Path)
+	vo := PermissionExplainVO{
+		UserId:   auth.ID,
+		Username: auth.Username,
+		IsSuper:  auth.IsSuper,
+		Method:   method,
+		Path:     query.Path,
+		Route:    route,
+		Roles:    make([]RoleDecisionVO, 0, len(auth.Roles)),
+	}
+
+	if IsPublicRoute(method, route) {
+		vo.Public, vo.Allow, vo.Reason = true, true, "不需要登录的基础接口"
+		ReturnSuccess(c, vo)
+		return
+	}
+
+	resource, err := model.GetResource(db, route, method)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		vo.Unregistered = global.GetConfig().UnregisteredPolicy()
+		switch vo.Unregistered {
+		case global.POLICY_ALLOW:
+			vo.Allow, vo.Reason = true, "接口没有注册资源, 按照 allow 策略允许访问"
+		case global.POLICY_AUTHENTICATED:
+			vo.Allow, vo.Reason = true, "接口没有注册资源, 按照 authenticated-only 策略登录即可访问"
+		default:
+			vo.Reason = "接口没有注册资源, 按照 deny 策略拒绝访问"
+		}
+		ReturnSuccess(c, vo)
+		return
+	case err != nil:
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	vo.Resource, vo.Anonymous = &resource, resource.Anonymous
+	if resource.Anonymous {
+		vo.Allow, vo.Reason = true, "匿名资源, 不需要登录"
+		ReturnSuccess(c, vo)
+		return
+	}
+
+	p, err := loadPolicy(db)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	names, err := roleNames(db)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	var granted []string
+	for _, role := range auth.Roles {
+		decision := RoleDecisionVO{ID: role.ID, Name: role.Name, Label: role.Label, IsDisable: role.IsDisable}
+		switch by, ok := p.GrantedBy(role.ID, method, route); {
+		case role.IsDisable:
+			decision.Reason = "角色已禁用"
+		case !ok:
+			decision.Reason = "角色及其上级角色都没有该资源"
+		case by == role.ID:
+			decision.Allow, decision.GrantedBy, decision.Reason = true, by, "角色拥有该资源"
+		default:
+			decision.Allow, decision.GrantedBy, decision.Reason = true, by, "继承自上级角色: "+names[by]
+		}
+		if decision.Allow {
+			granted = append(granted, role.Name)
+		}
+		vo.Roles = append(vo.Roles, decision)
+	}
+
+	switch {
+	case auth.IsSuper:
+		vo.Allow, vo.Reason = true, "超级管理员不需要验证权限"
+	case len(granted) > 0:
+		vo.Allow, vo.Reason = true, "角色拥有该资源: "+strings.Join(granted, ", ")
+	case len(auth.Roles) == 0:
+		vo.Reason = "用户没有任何角色"
+	default:
+		vo.Reason = "用户的所有角色都没有该资源"
+	}
+	ReturnSuccess(c, vo)
+}
+
+// SimulateMenu 模拟用户查看后台菜单, 不传 user_id 时为当前用户
+// @Summary 模拟用户菜单
+// @Description 以指定用户的身份获取后台菜单树
+// @Tags role
+// @Produce json
+// @Param user_id query int false "用户 id, 不传时为当前用户"
+// @Success 0 {object} Response[[]MenuTreeVO]
+// @Router /role/menu [get]
+func (*Role) SimulateMenu(c *gin.Context) {
+	var query ExplainQuery
+	_ = c.ShouldBindQuery(&query) // 只需要 user_id
+
+	auth, ok := explainUser(c, query.UserId)
+	if !ok {
+		return
+	}
+
+	menus, err := userMenus(GetDB(c), auth)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	ReturnSuccess(c, menus2MenuVos(menus))
+}
+
+// explainUser 获取需要诊断的用户, id 为 0 时为当前用户, 出错时直接返回错误响应
+func explainUser(c *gin.Context, id int) (*model.UserAuth, bool) {
+	var auth *model.UserAuth
+	var err error
+	if id == 0 {
+		auth, err = CurrentUserAuth(c)
+	} else {
+		auth, err = model.GetUserAuthInfoById(GetDB(c), id)
+	}
+	if err != nil {
+		ReturnError(c, global.ErrUserNotExist, err)
+		return nil, false
+	}
+	return auth, true
+}
+
+// routeOf 请求路径对应的路由: 去掉 /api 前缀和查询参数后匹配已注册的路由, 没有匹配时返回路径本身
+func routeOf(method, path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	if path == "/api" || strings.HasPrefix(path, "/api/") {
+		path = path[4:]
+	}
+
+	routes := make([]string, 0, len(authRoutes)+len(publicRoutes))
+	for _, r := range authRoutes {
+		if r.Method == method {
+			routes = append(routes, r.Url)
+		}
+	}
+	for key := range publicRoutes {
+		if m, url, _ := strings.Cut(key, " "); m == method {
+			routes = append(routes, url)
+		}
+	}
+
+	if route, ok := policy.MatchRoute(routes, path); ok {
+		return route
+	}
+	return path
+}
+
+// roleNames 所有角色的 id => 名称, 用于显示继承自哪个上级角色
+func roleNames(db *gorm.DB) (map[int]string, error) {
+	list, err := model.GetRoleOption(db)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(list))
+	for _, item := range list {
+		names[item.ID] = item.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,84 @@
+package handle
+
+import (
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// ImportRBACQuery 导入权限配置的参数
+type ImportRBACQuery struct {
+	DryRun bool `form:"dry_run"` // 只返回变化, 不修改数据库
+	Prune  bool `form:"prune"`   // 删除配置中没有的菜单, 资源, 角色
+}
+
+// Export 导出权限配置: 菜单, 资源, 角色及其关联, 使用自然键代替 id
+// @Summary 导出权限配置
+// @Description 导出 YAML 格式的权限配置, 可以导入到其他环境
+// @Tags role
+// @Produce application/x-yaml
+// @Router /role/export [get]
+func (*Role) Export(c *gin.Context) {
+	doc, err := model.ExportRBAC(GetDB(c))
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	data, err := model.MarshalRBAC(doc)
+	if err != nil {
+		ReturnError(c, global.FailResult, err)
+		return
+	}
+
+	fileName := "rbac-" + time.Now().Format("20060102150405") + ".yml"
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Data(http.StatusOK, "application/x-yaml; charset=utf-8", data)
+}
+
+// Import 导入权限配置, 按照自然键新增或更新, 重复导入结果相同
+// @Summary 导入权限配置
+// @Description 上传 YAML 格式的权限配置, dry_run 为 true 时只返回变化
+// @Tags role
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "权限配置文件"
+// @Param dry_run query bool false "只返回变化, 不修改数据库"
+// @Param prune query bool false "删除配置中没有的菜单, 资源, 角色"
+// @Success 0 {object} Response[model.RBACImportResult]
+// @Router /role/import [post]
+func (*Role) Import(c *gin.Context) {
+	var query ImportRBACQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		ReturnError(c, global.ErrFileReceive, err)
+		return
+	}
+	data, err := readFromFileHeader(fileHeader)
+	if err != nil {
+		ReturnError(c, global.ErrFileReceive, err)
+		return
+	}
+
+	doc, err := model.ParseRBAC(data)
+	if err != nil {
+		ReturnError(c, global.ErrRBACImport, err)
+		return
+	}
+	result, err := model.ImportRBAC(GetDB(c), doc, query.DryRun, query.Prune)
+	if err != nil {
+		ReturnError(c, global.ErrRBACImport, err)
+		return
+	}
+	if !query.DryRun && len(result.Changes) > 0 {
+		InvalidatePolicy(GetRDB(c))
+	}
+
+	ReturnSuccess(c, result)
+}
@@ -0,0 +1,174 @@
+package handle
+
+import (
+	"fmt"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils/sitemap"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pageRoutes 页面 label => 前台路由, 个人中心, 404 等不需要收录的页面不在其中
+var pageRoutes = map[string]string{
+	"archive":  "/archives",
+	"category": "/categories",
+	"tag":      "/tags",
+	"album":    "/albums",
+	"link":     "/links",
+	"about":    "/about",
+	"message":  "/message",
+}
+
+// defaultRobots 未配置 robots_txt 时使用的 robots.txt
+const defaultRobots = "User-agent: *\nDisallow: /user\n"
+
+// GetSitemap sitemap.xml, URL 数量超过 sitemap.MaxURLs 时返回 sitemap 索引, 各部分通过 /sitemap/:page 访问
+func (*Front) GetSitemap(c *gin.Context) {
+	serveSitemap(c, 0)
+}
+
+// GetSitemapPage sitemap 索引中的第 n 部分: /sitemap/1.xml
+func (*Front) GetSitemapPage(c *gin.Context) {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || page <= 0 {
+		ReturnError(c, global.ErrRequest, "sitemap 页码错误: "+c.Param("page"))
+		return
+	}
+	serveSitemap(c, page)
+}
+
+// serveSitemap 返回 sitemap, page 为 0 时返回完整的 sitemap 或者 sitemap 索引
+// 生成结果缓存在 Redis 中, 文章, 分类, 标签, 页面变化时删除
+func serveSitemap(c *gin.Context, page int) {
+	rdb := GetRDB(c)
+
+	key := global.ARTICLE_SITEMAP + strconv.Itoa(page)
+	doc, err := getDocumentCache(rdb, key)
+	if err != nil {
+		site, err := sitemapSite(c)
+		if err != nil {
+			ReturnError(c, global.ErrSiteURL, err)
+			return
+		}
+		urls, err := sitemapURLs(c, site)
+		if err != nil {
+			ReturnError(c, global.ErrDbOp, err)
+			return
+		}
+
+		parts := sitemap.Split(urls, sitemap.MaxURLs)
+		var body []byte
+		var lastMod time.Time
+		switch {
+		case page == 0 && len(parts) <= 1:
+			body, err = sitemap.URLSet(urls)
+			lastMod = sitemap.LastMod(urls)
+		case page == 0:
+			index := make([]sitemap.URL, 0, len(parts))
+			for i, part := range parts {
+				index = append(index, sitemap.URL{
+					Loc:     fmt.Sprintf("%s/sitemap/%d.xml", site, i+1),
+					LastMod: sitemap.LastMod(part),
+				})
+			}
+			body, err = sitemap.Index(index)
+			lastMod = sitemap.LastMod(index)
+		case page <= len(parts):
+			body, err = sitemap.URLSet(parts[page-1])
+			lastMod = sitemap.LastMod(parts[page-1])
+		default:
+			ReturnError(c, global.ErrRequest, "sitemap 页码错误: "+strconv.Itoa(page))
+			return
+		}
+		if err != nil {
+			ReturnError(c, global.FailResult, err)
+			return
+		}
+
+		doc = newCachedDocument(body, lastMod)
+		if err := addDocumentCache(rdb, key, doc); err != nil {
+			slog.Warn("写入 sitemap 缓存失败", "key", key, "err", err)
+		}
+	}
+
+	serveDocument(c, doc, sitemap.ContentType)
+}
+
+// sitemapURLs sitemap 中的所有地址: 首页, 页面, 文章, 分类, 标签
+func sitemapURLs(c *gin.Context, site string) ([]sitemap.URL, error) {
+	data, err := model.GetSitemapData(GetDB(c))
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]sitemap.URL, 0, len(data.Articles)+len(data.Categories)+len(data.Tags)+len(data.Pages)+1)
+	home := sitemap.URL{Loc: site + "/"}
+	if len(data.Articles) > 0 {
+		home.LastMod = data.Articles[0].UpdatedAt
+	}
+	urls = append(urls, home)
+
+	for _, page := range data.Pages {
+		if route, ok := pageRoutes[page.Label]; ok {
+			urls = append(urls, sitemap.URL{Loc: site + route, LastMod: page.UpdatedAt})
+		}
+	}
+	for _, article := range data.Articles {
+		urls = append(urls, sitemap.URL{Loc: site + "/article/" + slugKey(article.ID, article.Slug), LastMod: article.UpdatedAt})
+	}
+	for _, category := range data.Categories {
+		urls = append(urls, sitemap.URL{Loc: site + "/categories/" + slugKey(category.ID, category.Slug), LastMod: category.UpdatedAt})
+	}
+	for _, tag := range data.Tags {
+		urls = append(urls, sitemap.URL{Loc: site + "/tags/" + slugKey(tag.ID, tag.Slug), LastMod: tag.UpdatedAt})
+	}
+	return urls, nil
+}
+
+// sitemapSite 前台网站地址, 获取配置失败时使用 Server.SiteURL, 都未配置时返回 errSiteURL, 不生成也不缓存 sitemap
+func sitemapSite(c *gin.Context) (string, error) {
+	config, err := getConfigMap(GetDB(c), GetRDB(c))
+	if err != nil {
+		slog.Warn("获取博客配置失败", "err", err)
+	}
+	if site := siteURL(config); site != "" {
+		return site, nil
+	}
+	return "", errSiteURL
+}
+
+// GetRobots robots.txt, 内容来自博客配置 robots_txt, 并自动添加 sitemap 地址
+func (*Front) GetRobots(c *gin.Context) {
+	rdb := GetRDB(c)
+
+	key := global.ARTICLE_ROBOTS
+	doc, err := getDocumentCache(rdb, key)
+	if err != nil {
+		config, err := getConfigMap(GetDB(c), rdb)
+		if err != nil {
+			ReturnError(c, global.ErrDbOp, err)
+			return
+		}
+
+		robots := config[global.CONFIG_ROBOTS_TXT]
+		if strings.TrimSpace(robots) == "" {
+			robots = defaultRobots
+		}
+		robots = strings.TrimRight(strings.ReplaceAll(robots, "\r\n", "\n"), "\n") + "\n"
+		// 没有配置网站地址时不添加 sitemap 地址
+		if site := siteURL(config); site != "" && !strings.Contains(strings.ToLower(robots), "sitemap:") {
+			robots += "\nSitemap: " + site + "/sitemap.xml\n"
+		}
+
+		doc = newCachedDocument([]byte(robots), time.Now())
+		if err := addDocumentCache(rdb, key, doc); err != nil {
+			slog.Warn("写入 robots.txt 缓存失败", "key", key, "err", err)
+		}
+	}
+
+	serveDocument(c, doc, "text/plain; charset=utf-8")
+}
@@ -11,6 +11,7 @@ type Tag struct{}
 type AddOrEditTagReq struct {
 	ID   int    `json:"id"`
 	Name string `json:"name" binding:"required"`
+	Slug string `json:"slug"` // 为空时根据名称生成
 }
 
 // GetList 获取标签列表
@@ -63,12 +64,18 @@ func (*Tag) SaveOrUpdate(c *gin.Context) {
 		return
 	}
 
-	tag, err := model.SaveOrUpdateTag(GetDB(c), form.ID, form.Name)
+	tag, err := model.SaveOrUpdateTag(GetDB(c), form.ID, form.Name, form.Slug)
 	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
 
+	// 订阅源, sitemap 中包含标签
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
 	ReturnSuccess(c, tag)
 }
 
@@ -103,12 +110,16 @@ func (*Tag) Delete(c *gin.Context) {
 		return
 	}
 
-	result := db.Delete(model.Tag{}, "id in ?", ids)
-	if result.Error != nil {
+	rows, err := model.DeleteTag(db, ids)
+	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
-	ReturnSuccess(c, result.RowsAffected)
+	if err := RemoveArticleCache(GetRDB(c)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+	ReturnSuccess(c, rows)
 }
 
 // GetOption 获取标签选项列表
@@ -2,10 +2,14 @@ package handle
 
 import (
 	"encoding/json"
+	"errors"
 	"gin-blog-server/internal/global"
 	"gin-blog-server/internal/model"
 	"gin-blog-server/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
@@ -30,9 +34,17 @@ type UserQuery struct {
 }
 
 type UpdateUserReq struct {
-	UserAuthId int    `json:"id"`
-	Nickname   string `json:"nickname" binding:"required"`
-	RoleIds    []int  `json:"role_ids"`
+	UserAuthId int            `json:"id"`
+	Nickname   string         `json:"nickname" binding:"required"`
+	RoleIds    []int          `json:"role_ids"`
+	Grants     []RoleGrantReq `json:"grants"` // 带有效期的角色授权, 不为空时忽略 role_ids
+}
+
+// RoleGrantReq 用户的角色授权, 有效期的起止时间都可以为空
+type RoleGrantReq struct {
+	RoleId     int        `json:"role_id" binding:"required"`
+	ValidFrom  *time.Time `json:"valid_from"`  // 生效时间, 为空表示立即生效
+	ValidUntil *time.Time `json:"valid_until"` // 失效时间, 为空表示永久有效
 }
 
 type UpdateUserDisableReq struct {
@@ -102,6 +114,11 @@ func (*User) GetList(c *gin.Context) {
 		return
 	}
 
+	if err := setLockedUntil(GetRDB(c), list); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
 	ReturnSuccess(c, PageResult[model.UserAuth]{
 		Size:  query.Size,
 		Page:  query.Page,
@@ -118,7 +135,21 @@ func (*User) Update(c *gin.Context) {
 		return
 	}
 
-	if err := model.UpdateUserNicknameAndRole(GetDB(c), req.UserAuthId, req.Nickname, req.RoleIds); err != nil {
+	grants := make([]model.UserAuthRole, 0, len(req.RoleIds))
+	if len(req.Grants) == 0 {
+		for _, id := range req.RoleIds {
+			grants = append(grants, model.UserAuthRole{RoleId: id})
+		}
+	}
+	for _, grant := range req.Grants {
+		if grant.ValidFrom != nil && grant.ValidUntil != nil && !grant.ValidUntil.After(*grant.ValidFrom) {
+			ReturnError(c, global.ErrRequest, "角色的失效时间必须晚于生效时间")
+			return
+		}
+		grants = append(grants, model.UserAuthRole{RoleId: grant.RoleId, ValidFrom: grant.ValidFrom, ValidUntil: grant.ValidUntil})
+	}
+
+	if err := model.UpdateUserNicknameAndRole(GetDB(c), req.UserAuthId, req.Nickname, grants); err != nil {
 		ReturnError(c, global.ErrDbOp, err)
 		return
 	}
@@ -138,6 +169,15 @@ func (*User) UpdateDisable(c *gin.Context) {
 	err := model.UpdateUserDisable(GetDB(c), req.UserAuthId, req.IsDisable)
 	if err != nil {
 		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	// 禁用后立即下线, 并吊销所有 token, 不能再通过 refresh token 续期
+	if req.IsDisable {
+		if err := forceOffline(GetRDB(c), req.UserAuthId, "禁用用户"); err != nil {
+			ReturnError(c, global.ErrRedisOp, err)
+			return
+		}
 	}
 
 	ReturnSuccess(c, nil)
@@ -168,7 +208,11 @@ func (*User) UpdateCurrentPassword(c *gin.Context) {
 		return
 	}
 
-	// TODO: 修改完密码后，强制当前用户下线
+	// 修改完密码后，吊销该用户的所有 token, 需要重新登录
+	if err := RevokeUserTokens(GetRDB(c), auth.ID); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
 
 	ReturnSuccess(c, nil)
 }
@@ -227,12 +271,82 @@ func (*User) ForceOffline(c *gin.Context) {
 		return
 	}
 
-	rdb := GetRDB(c)
+	if err := forceOffline(GetRDB(c), uid, auth); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	ReturnSuccess(c, "强制离线成功")
+}
+
+// Unlock 解除用户因登录失败次数过多导致的临时锁定, 同时清除失败次数
+func (*User) Unlock(c *gin.Context) {
+	uid, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	auth, err := model.GetUserAuthInfoById(GetDB(c), uid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ReturnError(c, global.ErrUserNotExist, nil)
+			return
+		}
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	if err := clearLoginFailure(GetRDB(c), auth.Username); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	slog.Info("解除用户登录锁定", "user_id", uid, "username", auth.Username)
+	ReturnSuccess(c, "解除锁定成功")
+}
+
+// setLockedUntil 查询用户列表中每个用户的登录锁定状态
+func setLockedUntil(rdb *redis.Client, list []model.UserAuth) error {
+	cmds := make([]*redis.DurationCmd, len(list))
+	if _, err := rdb.Pipelined(rctx, func(pipe redis.Pipeliner) error {
+		for i := range list {
+			cmds[i] = pipe.PTTL(rctx, global.LOGIN_LOCK+loginName(list[i].Username))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i, cmd := range cmds {
+		if ttl := cmd.Val(); ttl > 0 {
+			until := now.Add(ttl)
+			list[i].LockedUntil = &until
+		}
+	}
+	return nil
+}
+
+// ForceOfflineUsers 强制多个用户离线, 用于角色授权到期等后台任务, reason 记录在离线标识中
+func ForceOfflineUsers(rdb *redis.Client, ids []int, reason string) error {
+	for _, id := range ids {
+		if err := forceOffline(rdb, id, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forceOffline 删除用户的在线状态并设置离线标识, 同时吊销用户的所有 token
+// 用户下次请求时被要求重新登录, 重新登录时删除离线标识
+func forceOffline(rdb *redis.Client, uid int, value any) error {
 	onlineKey := global.ONLINE_USER + strconv.Itoa(uid)
 	offlineKey := global.OFFLINE_USER + strconv.Itoa(uid)
 
 	rdb.Del(rctx, onlineKey)
-	rdb.Set(rctx, offlineKey, auth, time.Hour)
-
-	ReturnSuccess(c, "强制离线成功")
+	if err := rdb.Set(rctx, offlineKey, value, time.Hour).Err(); err != nil {
+		return err
+	}
+	return RevokeUserTokens(rdb, uid)
 }
@@ -0,0 +1,227 @@
+package handle
+
+import (
+	"errors"
+	"fmt"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils"
+	"gin-blog-server/internal/utils/jwt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 内存中的 JWT 签名密钥, 第一次使用时从数据库加载
+// 其他实例轮换密钥后, 遇到未知 kid 的 token 时重新加载, 签名使用的密钥最多 keyTTL 后更新
+var (
+	keyMu       sync.Mutex
+	keyCache    *jwt.KeySet
+	keyLoadedAt time.Time
+)
+
+const (
+	keyTTL            = 5 * time.Minute
+	keyReloadInterval = 10 * time.Second // 遇到未知 kid 时重新加载的最小间隔, 防止伪造的 kid 导致频繁查询数据库
+)
+
+// ErrSigningKey 加载或者生成签名密钥失败
+var ErrSigningKey = errors.New("加载签名密钥失败")
+
+// ParseToken 校验 token, 签名密钥未知时重新加载密钥后重试
+// 加载密钥失败时返回的错误包含 ErrSigningKey
+func ParseToken(db *gorm.DB, token string) (*jwt.MyClaims, error) {
+	keys, err := loadKeys(db, false)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := jwt.ParseToken(keys, token)
+	if errors.Is(err, jwt.ErrKeyNotFound) {
+		if keys, err = loadKeys(db, true); err != nil {
+			return nil, err
+		}
+		claims, err = jwt.ParseToken(keys, token)
+	}
+	return claims, err
+}
+
+// GetJWKS 签名密钥的公钥 (JSON Web Key Set), 其他服务使用公钥验证 token
+// 使用 HS256 共享密钥时返回空的密钥列表
+// @Summary JWKS
+// @Description 当前和轮换前仍然有效的签名密钥的公钥, 根据 token 头部的 kid 选择
+// @Tags UserAuth
+// @Produce json
+// @Success 200 {object} jwt.JWKS
+// @Router /.well-known/jwks.json [get]
+func (*UserAuth) GetJWKS(c *gin.Context) {
+	keys, err := loadKeys(GetDB(c), true)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keys.JWKS())
+}
+
+// RotateSigningKey 生成新的签名密钥替换当前密钥, 返回新密钥的 kid
+// interval 大于 0 时只替换使用时间超过 interval 的密钥, 没有替换时返回空字符串
+// 旧密钥保留到用它签名的 access token 全部过期
+func RotateSigningKey(db *gorm.DB, interval time.Duration) (string, error) {
+	alg := global.GetConfig().SigningAlg()
+	if alg == jwt.AlgHS256 {
+		return "", fmt.Errorf("%s 使用配置中的共享密钥, 不需要轮换", alg)
+	}
+
+	current, err := model.GetActiveJWTKey(db)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		current = &model.JWTKey{}
+	case err != nil:
+		return "", err
+	case interval > 0 && time.Since(current.CreatedAt) < interval && current.Alg == alg:
+		return "", nil
+	}
+
+	kid, err := rotateKey(db, alg, current.Kid)
+	if err != nil || kid == "" {
+		return kid, err
+	}
+	keyMu.Lock()
+	keyCache = nil
+	keyMu.Unlock()
+	return kid, nil
+}
+
+// rotateKey 生成 alg 签名密钥替换 current, current 已经被其他实例替换时返回空字符串
+func rotateKey(db *gorm.DB, alg, current string) (string, error) {
+	key, err := jwt.GenerateKey(alg)
+	if err != nil {
+		return "", err
+	}
+	pem, err := key.MarshalPrivate()
+	if err != nil {
+		return "", err
+	}
+	secret, err := global.GetConfig().KeySecret()
+	if err != nil {
+		return "", err
+	}
+	private, err := utils.Encrypt(secret, pem)
+	if err != nil {
+		return "", err
+	}
+
+	// 签名使用的密钥最多 keyTTL 后更新, 旧密钥签名的 token 最晚在 keyTTL + access token 有效期后过期
+	now := time.Now()
+	expiresAt := now.Add(keyTTL + global.GetConfig().AccessExpire())
+	rotated, err := model.RotateJWTKey(db, &model.JWTKey{Kid: key.Kid, Alg: alg, PrivateKey: private}, current, now, expiresAt)
+	if err != nil || !rotated {
+		return "", err
+	}
+	slog.Info("JWT 签名密钥已轮换", "kid", key.Kid, "alg", alg, "previous", current)
+	return key.Kid, nil
+}
+
+// loadKeys 获取签名密钥, HS256 直接使用配置中的共享密钥
+// force 为 true 时, 距离上次加载超过 keyReloadInterval 就重新加载
+func loadKeys(db *gorm.DB, force bool) (*jwt.KeySet, error) {
+	conf := global.GetConfig()
+	alg := conf.SigningAlg()
+	if alg == jwt.AlgHS256 {
+		return jwt.HMACKeySet(conf.JWT.Secret), nil
+	}
+
+	// 签名私钥只能使用安全的密钥加密保存, 不能生成或者加载
+	if _, err := conf.KeySecret(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSigningKey, err)
+	}
+
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	age := time.Since(keyLoadedAt)
+	if keyCache != nil && age < keyTTL && (!force || age < keyReloadInterval) {
+		return keyCache, nil
+	}
+
+	keys, err := readKeys(db)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSigningKey, err)
+	}
+	// 还没有密钥, 或者修改了签名算法时生成新的密钥
+	if active := keys.Active(); active == nil || active.Alg != alg {
+		var current string
+		if active != nil {
+			current = active.Kid
+		}
+		if _, err := rotateKey(db, alg, current); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSigningKey, err)
+		}
+		if keys, err = readKeys(db); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSigningKey, err)
+		}
+	}
+
+	keyCache, keyLoadedAt = keys, time.Now()
+	return keys, nil
+}
+
+// readKeys 从数据库读取未过期的签名密钥, 无法解密或解析的密钥跳过
+func readKeys(db *gorm.DB) (*jwt.KeySet, error) {
+	list, err := model.GetJWTKeys(db, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var active *jwt.Key
+	keys := make([]*jwt.Key, 0, len(list))
+	for _, item := range list {
+		pem, err := decryptPrivateKey(db, &item)
+		if err != nil {
+			slog.Warn("JWT 签名密钥解密失败", "kid", item.Kid, "err", err)
+			continue
+		}
+		key, err := jwt.ParseKey(item.Kid, item.Alg, pem)
+		if err != nil {
+			slog.Warn("JWT 签名密钥解析失败", "kid", item.Kid, "err", err)
+			continue
+		}
+		if item.ExpiresAt != nil {
+			key.NotAfter = *item.ExpiresAt
+		}
+		// 多个实例同时生成密钥时可能有多个未替换的密钥, 使用最新的密钥签名
+		if item.RetiredAt == nil {
+			if active != nil {
+				keys = append(keys, active)
+			}
+			active = key
+		} else {
+			keys = append(keys, key)
+		}
+	}
+	return jwt.NewKeySet(active, keys...), nil
+}
+
+// decryptPrivateKey 解密签名密钥保存的私钥
+// 加密之前以明文保存的私钥, 加密后写回数据库
+func decryptPrivateKey(db *gorm.DB, item *model.JWTKey) (string, error) {
+	secret, err := global.GetConfig().KeySecret()
+	if err != nil {
+		return "", err
+	}
+	if utils.IsEncrypted(item.PrivateKey) {
+		return utils.Decrypt(secret, item.PrivateKey)
+	}
+
+	private, err := utils.Encrypt(secret, item.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	if err := model.UpdateJWTKeyPrivate(db, item.ID, private); err != nil {
+		return "", err
+	}
+	slog.Info("JWT 签名私钥已加密保存", "kid", item.Kid)
+	return item.PrivateKey, nil
+}
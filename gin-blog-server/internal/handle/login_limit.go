@@ -0,0 +1,208 @@
+package handle
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/utils"
+	"gin-blog-server/internal/utils/captcha"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 登录防暴力破解: 按账号和 IP 统计 Login.FailWindow 内的登录失败次数
+// 账号或 IP 失败次数达到 Login.CaptchaAfter 后需要图形验证码
+// 账号失败次数达到 Login.LockAfter 后临时锁定, 锁定期间再次失败时重新锁定, 每次锁定时长翻倍
+
+// lockLevelExpire 锁定次数的保留时间, 超过这个时间没有被再次锁定时从 Login.LockTime 重新开始
+const lockLevelExpire = 24 * time.Hour
+
+// captchaLimit 获取图形验证码的限流规则
+var captchaLimit = rateLimit{Scene: "captcha", Limit: 30, Window: time.Minute}
+
+type CaptchaVO struct {
+	CaptchaId string `json:"captcha_id"`
+	Image     string `json:"image"`      // data URL 格式的 PNG 图片
+	ExpiresIn int64  `json:"expires_in"` // 有效期 (秒)
+}
+
+// GetCaptcha 获取图形验证码, 登录失败次数过多时登录需要携带
+// @Summary 获取图形验证码
+// @Description 每个验证码只能使用一次, 无论是否正确
+// @Tags UserAuth
+// @Produce json
+// @Success 0 {object} Response[CaptchaVO]
+// @Router /captcha [get]
+func (*UserAuth) GetCaptcha(c *gin.Context) {
+	rdb := GetRDB(c)
+	if !checkRateLimit(c, rdb, captchaLimit, c.ClientIP()) {
+		return
+	}
+
+	code, img, err := captcha.New(captcha.Length)
+	if err != nil {
+		ReturnError(c, global.FailResult, err)
+		return
+	}
+
+	id, _ := utils.GenToken()
+	expire := global.GetConfig().CaptchaExpire()
+	if err := rdb.Set(rctx, global.CAPTCHA+id, code, expire).Err(); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	ReturnSuccess(c, CaptchaVO{
+		CaptchaId: id,
+		Image:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(img),
+		ExpiresIn: int64(expire.Seconds()),
+	})
+}
+
+// checkLoginAttempt 登录前检查账号是否被锁定, 是否需要图形验证码, 不能继续登录时返回错误响应
+func checkLoginAttempt(c *gin.Context, rdb *redis.Client, username, captchaId, answer string) bool {
+	username = loginName(username)
+	ttl, err := loginLockTTL(rdb, username)
+	if err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return false
+	}
+	if ttl > 0 {
+		returnLocked(c, ttl)
+		return false
+	}
+
+	need, err := needCaptcha(rdb, username, c.ClientIP())
+	if err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return false
+	}
+	if !need {
+		return true
+	}
+	if captchaId == "" {
+		ReturnError(c, global.ErrCaptchaRequired, nil)
+		return false
+	}
+	ok, err := useCaptcha(rdb, captchaId, answer)
+	if err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return false
+	}
+	if !ok {
+		ReturnError(c, global.ErrCaptcha, nil)
+		return false
+	}
+	return true
+}
+
+// loginFailed 记录一次登录失败并返回错误响应, 达到次数限制时返回账号已锁定
+func loginFailed(c *gin.Context, rdb *redis.Client, username string, r global.Result) {
+	lock, err := recordLoginFailure(rdb, loginName(username), c.ClientIP())
+	if err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+	if lock > 0 {
+		returnLocked(c, lock)
+		return
+	}
+	ReturnError(c, r, nil)
+}
+
+func returnLocked(c *gin.Context, ttl time.Duration) {
+	minutes := int(math.Ceil(ttl.Minutes()))
+	ReturnError(c, global.ErrUserLocked, fmt.Sprintf("登录失败次数过多，账号已临时锁定，请 %d 分钟后再试", minutes))
+}
+
+// loginName 统计失败次数使用的用户名, 忽略大小写和首尾空白
+func loginName(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func loginFailKey(kind, id string) string {
+	return global.LOGIN_FAIL + kind + ":" + id
+}
+
+// loginLockTTL 返回账号剩余的锁定时间, 没有锁定时返回 0
+func loginLockTTL(rdb *redis.Client, username string) (time.Duration, error) {
+	ttl, err := rdb.PTTL(rctx, global.LOGIN_LOCK+username).Result()
+	if err != nil {
+		return 0, err
+	}
+	return max(ttl, 0), nil
+}
+
+// needCaptcha 账号或者 IP 的登录失败次数达到限制时需要图形验证码
+func needCaptcha(rdb *redis.Client, username, ip string) (bool, error) {
+	vals, err := rdb.MGet(rctx, loginFailKey("user", username), loginFailKey("ip", ip)).Result()
+	if err != nil {
+		return false, err
+	}
+	limit := global.GetConfig().CaptchaAfter()
+	for _, v := range vals {
+		if s, ok := v.(string); ok {
+			if n, _ := strconv.ParseInt(s, 10, 64); n >= limit {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// useCaptcha 校验图形验证码, 每个验证码只能使用一次
+func useCaptcha(rdb *redis.Client, id, answer string) (bool, error) {
+	code, err := rdb.GetDel(rctx, global.CAPTCHA+id).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return captcha.Verify(code, answer), nil
+}
+
+// recordLoginFailure 记录一次登录失败, 账号的失败次数达到限制时锁定账号, 返回锁定时长
+func recordLoginFailure(rdb *redis.Client, username, ip string) (time.Duration, error) {
+	conf := global.GetConfig()
+	window := conf.FailWindow().Milliseconds()
+	if err := limitScript.Run(rctx, rdb, []string{loginFailKey("ip", ip)}, window).Err(); err != nil {
+		return 0, err
+	}
+	n, err := limitScript.Run(rctx, rdb, []string{loginFailKey("user", username)}, window).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if n < conf.LockAfter() {
+		return 0, nil
+	}
+
+	levelKey := global.LOGIN_LOCK_LEVEL + username
+	var level *redis.IntCmd
+	if _, err := rdb.TxPipelined(rctx, func(pipe redis.Pipeliner) error {
+		level = pipe.Incr(rctx, levelKey)
+		pipe.Expire(rctx, levelKey, lockLevelExpire)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	lock := conf.LockDuration(level.Val())
+	if err := rdb.Set(rctx, global.LOGIN_LOCK+username, level.Val(), lock).Err(); err != nil {
+		return 0, err
+	}
+	slog.Warn("登录失败次数过多, 临时锁定账号", "username", username, "ip", ip, "failures", n, "lock", lock)
+	return lock, nil
+}
+
+// clearLoginFailure 登录成功或者管理员解锁时清除账号的失败次数和锁定状态
+func clearLoginFailure(rdb *redis.Client, username string) error {
+	username = loginName(username)
+	return rdb.Del(rctx, loginFailKey("user", username), global.LOGIN_LOCK+username, global.LOGIN_LOCK_LEVEL+username).Err()
+}
@@ -0,0 +1,568 @@
+package handle
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils"
+	"gin-blog-server/internal/utils/oauth"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 第三方登录 (OAuth 2.0 / OIDC):
+// 1. 前端获取授权地址并跳转, state, PKCE verifier, nonce 和完成后跳转的前端地址保存在 Redis 中
+//    同时在 Cookie 中保存 state 的摘要, 把 state 绑定到发起授权的浏览器
+// 2. 第三方平台回调 /oauth/:provider/callback, 校验 state 和 Cookie 后获取第三方用户信息, 登录或者绑定账号
+// 3. 回调跳转回前端, 登录时携带一次性登录码 oauth_code, 前端通过 /oauth/login 换取 token
+//    token 不出现在跳转地址中, 避免通过浏览器历史记录, Referer 泄露
+
+const (
+	oauthStateExpire = 10 * time.Minute // 从发起授权到回调的最长时间
+	oauthCodeExpire  = time.Minute      // 一次性登录码的有效期
+	oauthStateCookie = "oauth_state"    // 保存 state 摘要的 Cookie
+)
+
+// oauthLimit 发起第三方登录的限流规则
+var oauthLimit = rateLimit{Scene: "oauth", Limit: 30, Window: time.Minute}
+
+var errOAuthProvider = errors.New("第三方登录方式不存在")
+
+// oauthState 保存在 Redis 中的授权状态, 回调时取出并删除, 每个 state 只能使用一次
+type oauthState struct {
+	Provider string            `json:"provider"`
+	Request  oauth.AuthRequest `json:"request"`
+	Redirect string            `json:"redirect"` // 完成后跳转的前端地址
+	UserId   int               `json:"user_id"`  // 绑定第三方账号的用户, 登录时为 0
+}
+
+type OAuthProviderVO struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+type OAuthURLVO struct {
+	URL string `json:"url"` // 第三方平台的授权页面, 前端直接跳转
+}
+
+type UserOAuthVO struct {
+	OAuthProviderVO
+	Linked   bool       `json:"linked"`
+	Nickname string     `json:"nickname"` // 第三方平台中的昵称
+	Avatar   string     `json:"avatar"`
+	Email    string     `json:"email"`
+	LinkedAt *time.Time `json:"linked_at"`
+}
+
+type OAuthAuthorizeQuery struct {
+	Redirect string `form:"redirect"` // 完成后跳转的前端地址, 可以是网站的相对路径, 或者网站地址, OAuth.RedirectOrigins 下的地址
+}
+
+type OAuthLoginReq struct {
+	Code string `json:"code" binding:"required"` // 回调跳转时携带的 oauth_code
+}
+
+// GetOAuthProviders 获取可用的第三方登录方式
+// @Summary 第三方登录方式
+// @Description 配置文件 OAuth.Providers 中的第三方登录方式
+// @Tags UserAuth
+// @Produce json
+// @Success 0 {object} Response[[]OAuthProviderVO]
+// @Router /oauth/providers [get]
+func (*UserAuth) GetOAuthProviders(c *gin.Context) {
+	ReturnSuccess(c, oauthProviderList())
+}
+
+// OAuthAuthorize 发起第三方登录, 返回第三方平台的授权地址
+// @Summary 发起第三方登录
+// @Description 前端跳转到返回的地址, 授权完成后回调跳转到 redirect, 成功时携带 oauth_code, 失败时携带 oauth_error
+// @Tags UserAuth
+// @Param provider path string true "第三方登录方式"
+// @Param redirect query string false "完成后跳转的前端地址"
+// @Produce json
+// @Success 0 {object} Response[OAuthURLVO]
+// @Router /oauth/{provider}/authorize [get]
+func (*UserAuth) OAuthAuthorize(c *gin.Context) {
+	startOAuth(c, 0)
+}
+
+// OAuthCallback 第三方平台的回调, 登录或绑定完成后跳转回前端
+// @Summary 第三方登录回调
+// @Description 在第三方平台中填写的回调地址, 不由前端调用
+// @Tags UserAuth
+// @Param provider path string true "第三方登录方式"
+// @Param code query string false "授权码"
+// @Param state query string true "state"
+// @Success 302
+// @Router /oauth/{provider}/callback [get]
+func (*UserAuth) OAuthCallback(c *gin.Context) {
+	db := GetDB(c)
+	rdb := GetRDB(c)
+
+	// 没有有效的 state 时不知道应该跳转到哪里, 返回错误页面
+	// state 必须由当前浏览器发起, 防止把其他人的授权结果登录或绑定到当前浏览器
+	if !checkOAuthStateCookie(c, c.Query("state")) {
+		returnErrorPage(c, http.StatusBadRequest, "第三方登录失败", global.ErrOAuthState.Msg())
+		return
+	}
+	clearOAuthStateCookie(c)
+	state, err := takeOAuthState(rdb, c.Query("state"))
+	if err != nil || state.Provider != c.Param("provider") {
+		if err != nil && !errors.Is(err, redis.Nil) {
+			slog.Error("获取第三方登录状态失败", "err", err)
+		}
+		returnErrorPage(c, http.StatusBadRequest, "第三方登录失败", global.ErrOAuthState.Msg())
+		return
+	}
+
+	// 用户拒绝授权等情况, 第三方平台回调时携带 error (RFC 6749 4.1.2.1)
+	if e := c.Query("error"); e != "" {
+		slog.Info("第三方登录授权失败", "provider", state.Provider, "error", e, "description", c.Query("error_description"))
+		redirectOAuth(c, state.Redirect, "oauth_error", global.ErrOAuth.Msg())
+		return
+	}
+
+	provider, conf, err := getOAuthProvider(state.Provider)
+	if err != nil {
+		redirectOAuth(c, state.Redirect, "oauth_error", global.ErrOAuthProvider.Msg())
+		return
+	}
+	profile, err := provider.Profile(c.Request.Context(), c.Query("code"), state.Request)
+	if err != nil {
+		slog.Warn("获取第三方用户信息失败", "provider", state.Provider, "err", err)
+		redirectOAuth(c, state.Redirect, "oauth_error", global.ErrOAuth.Msg())
+		return
+	}
+	binding := model.NewUserOauth(conf.Name, profile.Subject, profile.Nickname, profile.Avatar, profile.Email)
+
+	var r global.Result
+	if state.UserId != 0 {
+		r, err = linkOAuth(db, state.UserId, binding)
+		if err == nil && r == global.OkResult {
+			redirectOAuth(c, state.Redirect, "oauth_linked", conf.Name)
+			return
+		}
+	} else {
+		var code string
+		code, r, err = loginOAuth(db, rdb, conf, binding)
+		if err == nil && r == global.OkResult {
+			redirectOAuth(c, state.Redirect, "oauth_code", code)
+			return
+		}
+	}
+	if err != nil {
+		slog.Error("第三方登录失败", "provider", conf.Name, "err", err)
+	}
+	redirectOAuth(c, state.Redirect, "oauth_error", r.Msg())
+}
+
+// OAuthLogin 使用第三方登录回调中的一次性登录码完成登录
+// @Summary 第三方登录
+// @Description 登录码只能使用一次; 开启了两步验证时返回 challenge, 通过 /login/2fa 完成登录
+// @Tags UserAuth
+// @Param form body OAuthLoginReq true "登录码"
+// @Accept json
+// @Produce json
+// @Success 0 {object} Response[LoginVO]
+// @Router /oauth/login [post]
+func (*UserAuth) OAuthLogin(c *gin.Context) {
+	var req OAuthLoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	userId, err := GetRDB(c).GetDel(rctx, global.OAUTH_LOGIN+utils.HashToken(req.Code)).Int()
+	if errors.Is(err, redis.Nil) {
+		ReturnError(c, global.ErrOAuthState, nil)
+		return
+	}
+	if err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	userAuth, err := model.GetUserAuthInfoById(GetDB(c), userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ReturnError(c, global.ErrUserNotExist, nil)
+			return
+		}
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	loginVerified(c, userAuth)
+}
+
+// GetOAuthList 获取当前用户的第三方账号绑定情况
+// @Summary 第三方账号绑定情况
+// @Description 返回所有可用的第三方登录方式, 以及当前用户是否已绑定
+// @Tags User
+// @Produce json
+// @Success 0 {object} Response[[]UserOAuthVO]
+// @Security ApiKeyAuth
+// @Router /user/oauth [get]
+func (*User) GetOAuthList(c *gin.Context) {
+	auth, err := CurrentUserAuth(c)
+	if err != nil {
+		ReturnError(c, global.ErrUserAuth, err)
+		return
+	}
+
+	bindings, err := model.GetUserOauthList(GetDB(c), auth.ID)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	list := make([]UserOAuthVO, 0)
+	for _, p := range oauthProviderList() {
+		vo := UserOAuthVO{OAuthProviderVO: p}
+		for _, b := range bindings {
+			if b.Provider == p.Name {
+				vo.Linked, vo.Nickname, vo.Avatar, vo.Email = true, b.Nickname, b.Avatar, b.Email
+				vo.LinkedAt = &b.CreatedAt
+			}
+		}
+		list = append(list, vo)
+	}
+	ReturnSuccess(c, list)
+}
+
+// LinkOAuth 绑定第三方账号, 返回第三方平台的授权地址
+// @Summary 绑定第三方账号
+// @Description 前端跳转到返回的地址, 授权完成后回调跳转到 redirect, 成功时携带 oauth_linked, 失败时携带 oauth_error
+// @Tags User
+// @Param provider path string true "第三方登录方式"
+// @Param redirect query string false "完成后跳转的前端地址"
+// @Produce json
+// @Success 0 {object} Response[OAuthURLVO]
+// @Security ApiKeyAuth
+// @Router /user/oauth/{provider} [post]
+func (*User) LinkOAuth(c *gin.Context) {
+	auth, err := CurrentUserAuth(c)
+	if err != nil {
+		ReturnError(c, global.ErrUserAuth, err)
+		return
+	}
+	startOAuth(c, auth.ID)
+}
+
+// UnlinkOAuth 解绑第三方账号
+// @Summary 解绑第三方账号
+// @Description 没有设置密码的用户不能解绑唯一的第三方账号
+// @Tags User
+// @Param provider path string true "第三方登录方式"
+// @Produce json
+// @Success 0 {object} Response[any]
+// @Security ApiKeyAuth
+// @Router /user/oauth/{provider} [delete]
+func (*User) UnlinkOAuth(c *gin.Context) {
+	auth, err := CurrentUserAuth(c)
+	if err != nil {
+		ReturnError(c, global.ErrUserAuth, err)
+		return
+	}
+
+	db := GetDB(c)
+	provider := c.Param("provider")
+	if auth.Password == "" {
+		bindings, err := model.GetUserOauthList(db, auth.ID)
+		if err != nil {
+			ReturnError(c, global.ErrDbOp, err)
+			return
+		}
+		others := slices.ContainsFunc(bindings, func(b model.UserOauth) bool { return b.Provider != provider })
+		if !others {
+			ReturnError(c, global.ErrOAuthUnlink, nil)
+			return
+		}
+	}
+
+	if _, err := model.UnlinkOauth(db, auth.ID, provider); err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	slog.Info("解绑第三方账号", "user_id", auth.ID, "provider", provider)
+	ReturnSuccess(c, nil)
+}
+
+// startOAuth 发起授权: 保存授权状态并返回授权地址, userId 不为 0 时为绑定账号
+func startOAuth(c *gin.Context, userId int) {
+	var query OAuthAuthorizeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	rdb := GetRDB(c)
+	if !checkRateLimit(c, rdb, oauthLimit, c.ClientIP()) {
+		return
+	}
+
+	name := c.Param("provider")
+	provider, _, err := getOAuthProvider(name)
+	if err != nil {
+		ReturnError(c, global.ErrOAuthProvider, err)
+		return
+	}
+
+	redirect, ok := oauthRedirect(query.Redirect)
+	if !ok {
+		ReturnError(c, global.ErrOAuthRedirect, query.Redirect)
+		return
+	}
+
+	req := oauth.NewAuthRequest(oauthCallbackURL(name))
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), req)
+	if err != nil {
+		ReturnError(c, global.ErrOAuth, err)
+		return
+	}
+
+	data, err := json.Marshal(oauthState{Provider: name, Request: req, Redirect: redirect, UserId: userId})
+	if err != nil {
+		ReturnError(c, global.FailResult, err)
+		return
+	}
+	if err := rdb.Set(rctx, global.OAUTH_STATE+req.State, data, oauthStateExpire).Err(); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+	setOAuthStateCookie(c, req.State)
+	ReturnSuccess(c, OAuthURLVO{URL: authURL})
+}
+
+// loginOAuth 第三方登录: 已绑定时登录绑定的用户, 否则创建新用户, 返回一次性登录码
+// 第三方邮箱已经注册时不自动绑定, 需要用户使用原账号登录后绑定, 避免通过第三方平台冒用邮箱
+func loginOAuth(db *gorm.DB, rdb *redis.Client, conf global.OAuthProvider, binding *model.UserOauth) (string, global.Result, error) {
+	var userId int
+	existing, err := model.GetUserOauth(db, binding.Provider, binding.Subject)
+	switch {
+	case err == nil:
+		userId = existing.UserAuthId
+		binding.ID = existing.ID
+		if err := model.UpdateOauthProfile(db, binding); err != nil {
+			return "", global.ErrDbOp, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if binding.Email != "" {
+			exist, err := isUserExist(db, binding.Email)
+			if err != nil {
+				return "", global.ErrDbOp, err
+			}
+			if exist {
+				return "", global.ErrOAuthEmailExist, nil
+			}
+		}
+		userAuth, err := model.CreateOauthUser(db, binding, oauthLoginType(conf.Type), global.GetConfig().RegisterRole())
+		if err != nil {
+			return "", global.ErrDbOp, err
+		}
+		userId = userAuth.ID
+		slog.Info("第三方登录创建用户", "provider", conf.Name, "user_id", userId, "username", userAuth.Username)
+	default:
+		return "", global.ErrDbOp, err
+	}
+
+	code, hash := utils.GenToken()
+	if err := rdb.Set(rctx, global.OAUTH_LOGIN+hash, userId, oauthCodeExpire).Err(); err != nil {
+		return "", global.ErrRedisOp, err
+	}
+	return code, global.OkResult, nil
+}
+
+// linkOAuth 为用户绑定第三方账号, 已经绑定到当前用户时更新第三方资料
+func linkOAuth(db *gorm.DB, userId int, binding *model.UserOauth) (global.Result, error) {
+	existing, err := model.GetUserOauth(db, binding.Provider, binding.Subject)
+	switch {
+	case err == nil && existing.UserAuthId != userId:
+		return global.ErrOAuthLinked, nil
+	case err == nil:
+		binding.ID = existing.ID
+		if err := model.UpdateOauthProfile(db, binding); err != nil {
+			return global.ErrDbOp, err
+		}
+		return global.OkResult, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return global.ErrDbOp, err
+	}
+
+	bindings, err := model.GetUserOauthList(db, userId)
+	if err != nil {
+		return global.ErrDbOp, err
+	}
+	if slices.ContainsFunc(bindings, func(b model.UserOauth) bool { return b.Provider == binding.Provider }) {
+		return global.ErrOAuthBound, nil
+	}
+
+	binding.UserAuthId = userId
+	if err := model.LinkOauth(db, binding); err != nil {
+		return global.ErrDbOp, err
+	}
+	slog.Info("绑定第三方账号", "user_id", userId, "provider", binding.Provider)
+	return global.OkResult, nil
+}
+
+// takeOAuthState 取出并删除授权状态
+func takeOAuthState(rdb *redis.Client, state string) (*oauthState, error) {
+	if state == "" {
+		return nil, redis.Nil
+	}
+	data, err := rdb.GetDel(rctx, global.OAUTH_STATE+state).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var s oauthState
+	return &s, json.Unmarshal(data, &s)
+}
+
+// setOAuthStateCookie 在 Cookie 中保存 state 的摘要
+// 回调是第三方平台发起的顶级跳转, SameSite=Lax 的 Cookie 会被携带
+func setOAuthStateCookie(c *gin.Context, state string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, utils.HashToken(state), int(oauthStateExpire.Seconds()), "/", "", oauthCookieSecure(), true)
+}
+
+// checkOAuthStateCookie 校验 state 是否由当前浏览器发起
+func checkOAuthStateCookie(c *gin.Context, state string) bool {
+	hash, err := c.Cookie(oauthStateCookie)
+	return err == nil && state != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(utils.HashToken(state))) == 1
+}
+
+// clearOAuthStateCookie 回调后删除 Cookie
+func clearOAuthStateCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, "", -1, "/", "", oauthCookieSecure(), true)
+}
+
+// oauthCookieSecure 网站使用 https 时 Cookie 只通过 https 发送
+func oauthCookieSecure() bool {
+	return strings.HasPrefix(global.GetConfig().SiteURL(), "https://")
+}
+
+// redirectOAuth 跳转回前端, 在跳转地址中添加结果参数
+func redirectOAuth(c *gin.Context, redirect, key, value string) {
+	u, err := url.Parse(redirect)
+	if err != nil {
+		returnErrorPage(c, http.StatusBadRequest, "第三方登录失败", global.ErrOAuthRedirect.Msg())
+		return
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	c.Redirect(http.StatusFound, u.String())
+}
+
+// oauthRedirect 校验完成后跳转的前端地址, 防止开放重定向
+// 允许网站的相对路径, 网站地址 (Server.SiteURL) 和 OAuth.RedirectOrigins 下的绝对地址, 为空时跳转到网站首页
+// 只使用配置文件中的地址, 不使用可以被修改的 website_url 或者请求中的 Host
+func oauthRedirect(redirect string) (string, bool) {
+	site := global.GetConfig().SiteURL()
+	if site == "" && (redirect == "" || strings.HasPrefix(redirect, "/")) {
+		return "", false
+	}
+	if redirect == "" {
+		return site + "/", true
+	}
+	// "//host" 和 "/\host" 会被浏览器当作其他网站的地址
+	if strings.HasPrefix(redirect, "/") && !strings.HasPrefix(redirect, "//") && !strings.HasPrefix(redirect, "/\\") {
+		return site + redirect, true
+	}
+
+	u, err := url.Parse(redirect)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return "", false
+	}
+	origin := u.Scheme + "://" + u.Host
+	if s, err := url.Parse(site); err == nil && site != "" && origin == s.Scheme+"://"+s.Host {
+		return redirect, true
+	}
+	for _, allowed := range global.GetConfig().OAuth.RedirectOrigins {
+		if origin == strings.TrimSuffix(allowed, "/") {
+			return redirect, true
+		}
+	}
+	return "", false
+}
+
+// oauthCallbackURL 第三方平台的回调地址, 需要与在第三方平台中填写的一致, 未配置时为 <Server.SiteURL>/api/oauth/<Name>/callback
+func oauthCallbackURL(name string) string {
+	conf := global.GetConfig()
+	base := conf.OAuth.CallbackURL
+	if base == "" {
+		base = conf.SiteURL() + "/api"
+	}
+	return strings.TrimSuffix(base, "/") + "/oauth/" + url.PathEscape(name) + "/callback"
+}
+
+// oauthLoginType 第三方登录创建的用户的登录类型
+func oauthLoginType(typ string) int {
+	if typ == "github" {
+		return model.LOGIN_GITHUB
+	}
+	return model.LOGIN_OIDC
+}
+
+func oauthProviderList() []OAuthProviderVO {
+	list := make([]OAuthProviderVO, 0)
+	for _, p := range global.GetConfig().OAuth.Providers {
+		title := p.Title
+		if title == "" {
+			title = p.Name
+		}
+		list = append(list, OAuthProviderVO{Name: p.Name, Title: title, Type: p.Type})
+	}
+	return list
+}
+
+// 根据配置创建的第三方登录, 第一次使用时创建并缓存, OIDC 会缓存发现文档和公钥
+var (
+	oauthMu        sync.Mutex
+	oauthProviders = map[string]oauth.Provider{}
+)
+
+// getOAuthProvider 返回配置中名称对应的第三方登录
+func getOAuthProvider(name string) (oauth.Provider, global.OAuthProvider, error) {
+	conf, ok := global.GetConfig().OAuthProvider(name)
+	if !ok {
+		return nil, conf, errOAuthProvider
+	}
+	if len(name) > 30 { // UserOauth.Provider 的长度限制
+		return nil, conf, fmt.Errorf("第三方登录方式的名称 %s 超过 30 个字符", name)
+	}
+
+	oauthMu.Lock()
+	defer oauthMu.Unlock()
+	if p, ok := oauthProviders[name]; ok {
+		return p, conf, nil
+	}
+	p, err := oauth.New(oauth.Config{
+		Type:         conf.Type,
+		ClientId:     conf.ClientId,
+		ClientSecret: conf.ClientSecret,
+		Issuer:       conf.Issuer,
+		AuthURL:      conf.AuthURL,
+		TokenURL:     conf.TokenURL,
+		UserInfoURL:  conf.UserInfoURL,
+		Scopes:       conf.Scopes,
+	})
+	if err != nil {
+		return nil, conf, err
+	}
+	oauthProviders[name] = p
+	return p, conf, nil
+}
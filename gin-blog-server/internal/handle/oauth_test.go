@@ -0,0 +1,81 @@
+package handle
+
+import (
+	"gin-blog-server/internal/global"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuthRedirect(t *testing.T) {
+	global.Conf = &global.Config{}
+	global.Conf.Server.SiteURL = "https://blog.example.com/"
+	global.Conf.OAuth.RedirectOrigins = []string{"http://localhost:3333"}
+
+	allowed := map[string]string{
+		"":                                 "https://blog.example.com/",
+		"/user":                            "https://blog.example.com/user",
+		"https://blog.example.com/admin":   "https://blog.example.com/admin",
+		"http://localhost:3333/#/user?a=b": "http://localhost:3333/#/user?a=b",
+	}
+	for redirect, want := range allowed {
+		got, ok := oauthRedirect(redirect)
+		assert.True(t, ok, redirect)
+		assert.Equal(t, want, got)
+	}
+
+	for _, redirect := range []string{
+		"//evil.com",
+		"/\\evil.com",
+		"https://evil.com/user",
+		"http://blog.example.com/user",
+		"https://user@blog.example.com/",
+		"javascript:alert(1)",
+	} {
+		_, ok := oauthRedirect(redirect)
+		assert.False(t, ok, redirect)
+	}
+
+	// 没有配置网站地址时只允许 OAuth.RedirectOrigins
+	global.Conf.Server.SiteURL = ""
+	for _, redirect := range []string{"", "/user", "https://blog.example.com/user"} {
+		_, ok := oauthRedirect(redirect)
+		assert.False(t, ok, redirect)
+	}
+	_, ok := oauthRedirect("http://localhost:3333/")
+	assert.True(t, ok)
+}
+
+func TestOAuthStateCookie(t *testing.T) {
+	global.Conf = &global.Config{}
+	global.Conf.Server.SiteURL = "https://blog.example.com"
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/oauth/github/authorize", nil)
+	setOAuthStateCookie(c, "state")
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	assert.NotContains(t, cookie.Value, "state")
+
+	callback := func(state string, cookies ...*http.Cookie) bool {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/oauth/github/callback?state="+state, nil)
+		for _, cookie := range cookies {
+			c.Request.AddCookie(cookie)
+		}
+		return checkOAuthStateCookie(c, state)
+	}
+	assert.True(t, callback("state", cookie))
+	assert.False(t, callback("other", cookie))
+	assert.False(t, callback("state"))
+	assert.False(t, callback("", &http.Cookie{Name: oauthStateCookie, Value: ""}))
+}
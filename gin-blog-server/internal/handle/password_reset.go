@@ -0,0 +1,67 @@
+package handle
+
+import (
+	"errors"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/utils"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// 忘记密码时通过邮件发送重置密码链接, 链接中的 token 只能使用一次, passwordResetExpire 后过期
+// 服务端只保存 token 的摘要, 每个用户只有最后申请的 token 可用
+
+const passwordResetExpire = 30 * time.Minute
+
+// 重置密码的限流规则, 同时按照邮箱和 IP 限制, 防止被用来向任意邮箱发送邮件
+var (
+	resetEmailLimit   = rateLimit{Scene: "reset_email", Limit: 3, Window: time.Hour}
+	resetIPLimit      = rateLimit{Scene: "reset_ip", Limit: 10, Window: time.Hour}
+	resetConfirmLimit = rateLimit{Scene: "reset_confirm", Limit: 20, Window: time.Hour}
+)
+
+// errResetToken 重置密码的 token 不存在, 已过期或者已使用
+var errResetToken = errors.New("重置密码链接无效或已过期")
+
+// savePasswordResetToken 为用户生成重置密码的 token, 之前申请的 token 失效
+func savePasswordResetToken(rdb *redis.Client, userId int) (string, error) {
+	userKey := global.USER_PASSWORD_RESET + strconv.Itoa(userId)
+	oldHash, err := rdb.Get(rctx, userKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	token, hash := utils.GenToken()
+	pipe := rdb.TxPipeline()
+	if oldHash != "" {
+		pipe.Del(rctx, global.PASSWORD_RESET+oldHash)
+	}
+	pipe.Set(rctx, global.PASSWORD_RESET+hash, userId, passwordResetExpire)
+	pipe.Set(rctx, userKey, hash, passwordResetExpire)
+	_, err = pipe.Exec(rctx)
+	return token, err
+}
+
+// consumePasswordResetToken 使用重置密码的 token, 返回用户 id
+// token 不存在, 已过期或者已经使用时返回 errResetToken
+func consumePasswordResetToken(rdb *redis.Client, token string) (int, error) {
+	key := global.PASSWORD_RESET + utils.HashToken(token)
+	userId, err := rdb.Get(rctx, key).Int()
+	if errors.Is(err, redis.Nil) {
+		return 0, errResetToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// 同时使用时只有删除成功的请求可以继续
+	n, err := rdb.Del(rctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, errResetToken
+	}
+	return userId, rdb.Del(rctx, global.USER_PASSWORD_RESET+strconv.Itoa(userId)).Err()
+}
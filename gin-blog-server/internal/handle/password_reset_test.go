@@ -0,0 +1,91 @@
+package handle
+
+import (
+	"gin-blog-server/internal/global"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPasswordResetToken(t *testing.T) {
+	rdb := newTestRedis(t)
+	userId := int(time.Now().UnixNano() % 1e9)
+	t.Cleanup(func() { rdb.Del(rctx, global.USER_PASSWORD_RESET+strconv.Itoa(userId)) })
+
+	token, err := savePasswordResetToken(rdb, userId)
+	assert.Nil(t, err)
+
+	id, err := consumePasswordResetToken(rdb, token)
+	assert.Nil(t, err)
+	assert.Equal(t, userId, id)
+
+	// 只能使用一次
+	_, err = consumePasswordResetToken(rdb, token)
+	assert.ErrorIs(t, err, errResetToken)
+
+	_, err = consumePasswordResetToken(rdb, "not exist")
+	assert.ErrorIs(t, err, errResetToken)
+}
+
+func TestPasswordResetTokenReplace(t *testing.T) {
+	rdb := newTestRedis(t)
+	userId := int(time.Now().UnixNano()%1e9) + 1
+
+	// 重新申请后之前的 token 失效
+	old, err := savePasswordResetToken(rdb, userId)
+	assert.Nil(t, err)
+	token, err := savePasswordResetToken(rdb, userId)
+	assert.Nil(t, err)
+	assert.NotEqual(t, old, token)
+
+	_, err = consumePasswordResetToken(rdb, old)
+	assert.ErrorIs(t, err, errResetToken)
+
+	id, err := consumePasswordResetToken(rdb, token)
+	assert.Nil(t, err)
+	assert.Equal(t, userId, id)
+}
+
+func TestRateLimit(t *testing.T) {
+	rdb := newTestRedis(t)
+	l := rateLimit{Scene: "test", Limit: 3, Window: time.Minute}
+	id := strconv.FormatInt(time.Now().UnixNano(), 10)
+	t.Cleanup(func() { rdb.Del(rctx, global.RATE_LIMIT+"test:"+id, global.RATE_LIMIT+"test:other"+id) })
+
+	for i := 0; i < 3; i++ {
+		ok, err := l.allow(rdb, id)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+	ok, err := l.allow(rdb, id)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// 不同 id 分别计数
+	ok, err = l.allow(rdb, "other"+id)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// 第一次计数时设置过期时间
+	ttl, err := rdb.PTTL(rctx, global.RATE_LIMIT+"test:"+id).Result()
+	assert.Nil(t, err)
+	assert.True(t, ttl > 0 && ttl <= time.Minute)
+}
+
+func TestRateLimitWindow(t *testing.T) {
+	rdb := newTestRedis(t)
+	l := rateLimit{Scene: "test_window", Limit: 1, Window: 100 * time.Millisecond}
+	id := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	ok, _ := l.allow(rdb, id)
+	assert.True(t, ok)
+	ok, _ = l.allow(rdb, id)
+	assert.False(t, ok)
+
+	// 窗口过期后重新计数
+	time.Sleep(150 * time.Millisecond)
+	ok, err := l.allow(rdb, id)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,180 @@
+package handle
+
+import (
+	"context"
+	"errors"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils/policy"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// policyTTL 内存中权限表的有效期, 订阅断开期间错过的通知最多延迟这么久生效
+const policyTTL = 5 * time.Minute
+
+// publicRoutes 不需要登录的基础接口, 不受未注册资源访问策略的影响
+// 基础接口新增路由时需要同时加到这里, 否则会按照 Auth.Unregistered 策略处理
+var publicRoutes = map[string]bool{
+	"POST /login":                    true, // 登录
+	"POST /login/2fa":                true, // 两步验证登录
+	"POST /login/2fa/setup":          true, // 登录时设置两步验证
+	"GET /captcha":                   true, // 获取图形验证码
+	"GET /oauth/providers":           true, // 第三方登录方式
+	"GET /oauth/:provider/authorize": true, // 发起第三方登录
+	"GET /oauth/:provider/callback":  true, // 第三方登录回调
+	"POST /oauth/login":              true, // 第三方登录
+	"POST /refresh":                  true, // 刷新 token
+	"POST /register":                 true, // 注册
+	"GET /email/verify":              true, // 邮箱验证
+	"POST /email/resend":             true, // 重新发送验证邮件
+	"POST /password/forgot":          true, // 申请重置密码
+	"POST /password/reset":           true, // 重置密码
+	"GET /logout":                    true, // 退出登录
+	"POST /report":                   true, // 上报信息
+	"GET /config":                    true, // 获取配置
+}
+
+// IsPublicRoute 是否是不需要登录的基础接口
+func IsPublicRoute(method, url string) bool {
+	return publicRoutes[method+" "+url]
+}
+
+// 内存中的 角色 => 接口权限, 数据权限表, 第一次使用时从数据库加载, 权限变化时清空
+var (
+	policyMu       sync.RWMutex
+	policyCache    *policy.Policy
+	policyLoadedAt time.Time
+)
+
+// CheckPermission 判断角色是否可以访问接口, 取所有角色及其上级角色权限的并集, 禁用的角色没有权限
+func CheckPermission(db *gorm.DB, roleIds []int, method, url string) (bool, error) {
+	p, err := loadPolicy(db)
+	if err != nil {
+		return false, err
+	}
+	return p.Allow(roleIds, method, url), nil
+}
+
+func loadPolicy(db *gorm.DB) (*policy.Policy, error) {
+	policyMu.RLock()
+	p, loadedAt := policyCache, policyLoadedAt
+	policyMu.RUnlock()
+	if p != nil && time.Since(loadedAt) < policyTTL {
+		return p, nil
+	}
+
+	// 加载期间持有写锁, 保证加载过程中的权限变化不会被覆盖
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	if policyCache != nil && time.Since(policyLoadedAt) < policyTTL {
+		return policyCache, nil
+	}
+
+	roleIds, err := model.GetEnabledRoleIds(db)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := model.GetRoleGrants(db)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := model.GetRoleScopes(db)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := model.GetRoleParents(db)
+	if err != nil {
+		return nil, err
+	}
+
+	p = policy.New()
+	for _, id := range roleIds {
+		p.AddRole(id)
+	}
+	for _, id := range roleIds {
+		if pid, ok := parents[id]; ok { // 禁用的角色不继承上级角色的权限
+			p.SetParent(id, pid)
+		}
+	}
+	for _, g := range grants {
+		p.Grant(g.RoleId, g.Method, g.Url)
+	}
+	for _, s := range scopes {
+		p.SetScope(s.RoleId, s.Data, s.Scope)
+	}
+	policyCache, policyLoadedAt = p, time.Now()
+	slog.Debug("权限表加载成功", "roles", len(roleIds), "grants", len(grants), "scopes", len(scopes))
+	return p, nil
+}
+
+// dataScope 当前用户对某类数据的权限范围, 超级管理员拥有所有数据的权限, 多个角色取最大的范围
+// 出错时直接返回错误响应
+func dataScope(c *gin.Context, data string) (model.DataScope, bool) {
+	auth, err := CurrentUserAuth(c)
+	if err != nil {
+		ReturnError(c, global.ErrUserNotExist, err)
+		return model.DataScope{}, false
+	}
+	if auth.IsSuper {
+		return model.DataScope{Scope: policy.ScopeAll, UserId: auth.ID}, true
+	}
+
+	p, err := loadPolicy(GetDB(c))
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return model.DataScope{}, false
+	}
+	roleIds := make([]int, 0, len(auth.Roles))
+	for _, role := range auth.Roles {
+		roleIds = append(roleIds, role.ID)
+	}
+	return model.DataScope{Scope: p.Scope(roleIds, data), UserId: auth.ID}, true
+}
+
+// returnDataError 返回数据操作的错误, 区分数据权限错误和数据库错误
+func returnDataError(c *gin.Context, err error) {
+	if errors.Is(err, model.ErrOutOfScope) {
+		ReturnError(c, global.ErrDataScope, err)
+		return
+	}
+	ReturnError(c, global.ErrDbOp, err)
+}
+
+// clearPolicy 清空内存中的权限表, 下次使用时重新加载
+func clearPolicy() {
+	policyMu.Lock()
+	policyCache = nil
+	policyMu.Unlock()
+}
+
+// InvalidatePolicy 角色或资源变化后调用: 清空本实例的权限表, 并通过 Redis 通知其他实例
+func InvalidatePolicy(rdb *redis.Client) {
+	clearPolicy()
+	if err := rdb.Publish(rctx, global.POLICY_CHANNEL, time.Now().UnixMilli()).Err(); err != nil {
+		slog.Warn("发布权限变化通知失败", "err", err)
+	}
+}
+
+// WatchPolicy 订阅权限变化通知, 收到通知时清空权限表, ctx 结束时停止
+func WatchPolicy(ctx context.Context, rdb *redis.Client) {
+	sub := rdb.Subscribe(ctx, global.POLICY_CHANNEL)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			clearPolicy()
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package handle
+
+import (
+	"gin-blog-server/internal/global"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"time"
+)
+
+// limitScript 计数加一, 第一次计数时设置过期时间, 返回当前计数
+var limitScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// rateLimit 限流规则: Window 时间内最多 Limit 次
+type rateLimit struct {
+	Scene  string // 场景, 作为 key 的一部分
+	Limit  int64
+	Window time.Duration
+}
+
+// allow 固定窗口限流, 记录一次 id 的请求, 超过次数限制时返回 false
+func (l rateLimit) allow(rdb *redis.Client, id string) (bool, error) {
+	key := global.RATE_LIMIT + l.Scene + ":" + id
+	n, err := limitScript.Run(rctx, rdb, []string{key}, l.Window.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n <= l.Limit, nil
+}
+
+// checkRateLimit 记录一次 id 的请求, 超过次数限制或者出错时返回错误响应, 返回是否继续处理请求
+func checkRateLimit(c *gin.Context, rdb *redis.Client, l rateLimit, id string) bool {
+	ok, err := l.allow(rdb, id)
+	if err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return false
+	}
+	if !ok {
+		slog.Warn("请求过于频繁", "scene", l.Scene, "id", id)
+		ReturnError(c, global.ErrTooMany, nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/utils"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// 注册时待验证的账号 (邮箱, 密码哈希) 保存在 Redis 中, 验证邮件中的链接只携带随机生成的 token
+// 验证链接 emailTokenExpire 后过期, 待验证的账号保留 pendingRegisterExpire, 期间可以重新发送验证邮件
+// 重新注册或者重新发送后, 之前的验证链接失效
+
+const (
+	emailTokenExpire      = 15 * time.Minute
+	pendingRegisterExpire = 24 * time.Hour
+	emailResendInterval   = time.Minute // 同一个邮箱发送验证邮件的最小间隔
+)
+
+var (
+	errEmailToken    = errors.New("验证链接无效或已使用")
+	errEmailExpired  = errors.New("验证链接已过期")
+	errEmailFrequent = errors.New("发送验证邮件过于频繁")
+)
+
+// pendingRegister 待验证的注册账号
+type pendingRegister struct {
+	Email     string    `json:"email"`
+	Password  string    `json:"password"`   // bcrypt 哈希
+	TokenHash string    `json:"token_hash"` // 当前验证链接中 token 的摘要
+	ExpiresAt time.Time `json:"expires_at"` // 当前验证链接的过期时间
+	SentAt    time.Time `json:"sent_at"`    // 最后一次发送验证邮件的时间
+}
+
+// savePendingRegister 保存待验证的账号, 返回验证链接中的 token
+// 邮箱已经有待验证的账号时覆盖, 距离上次发送不足 emailResendInterval 时返回 errEmailFrequent
+func savePendingRegister(rdb *redis.Client, email, passwordHash string) (string, error) {
+	record, err := getPendingRegister(rdb, email)
+	switch {
+	case errors.Is(err, redis.Nil):
+		record = &pendingRegister{Email: email}
+	case err != nil:
+		return "", err
+	}
+	record.Password = passwordHash
+	return renewEmailToken(rdb, record)
+}
+
+// resendEmailToken 为待验证的账号重新生成 token, 不存在待验证的账号时返回 redis.Nil
+func resendEmailToken(rdb *redis.Client, email string) (string, error) {
+	record, err := getPendingRegister(rdb, email)
+	if err != nil {
+		return "", err
+	}
+	return renewEmailToken(rdb, record)
+}
+
+// renewEmailToken 生成新的 token 替换之前的 token, 待验证的账号重新保留 pendingRegisterExpire
+func renewEmailToken(rdb *redis.Client, record *pendingRegister) (string, error) {
+	now := time.Now()
+	if now.Sub(record.SentAt) < emailResendInterval {
+		return "", errEmailFrequent
+	}
+
+	oldHash := record.TokenHash
+	token, hash := utils.GenToken()
+	record.TokenHash, record.ExpiresAt, record.SentAt = hash, now.Add(emailTokenExpire), now
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	// token 保留到待验证的账号过期, 用于区分过期和无效的验证链接
+	pipe := rdb.TxPipeline()
+	if oldHash != "" {
+		pipe.Del(rctx, global.EMAIL_TOKEN+oldHash)
+	}
+	pipe.Set(rctx, global.EMAIL_TOKEN+hash, record.Email, pendingRegisterExpire)
+	pipe.Set(rctx, global.PENDING_REGISTER+record.Email, data, pendingRegisterExpire)
+	_, err = pipe.Exec(rctx)
+	return token, err
+}
+
+// consumeEmailToken 使用 token 取出待验证的账号, 每个 token 只能使用一次
+// token 不存在或者已经被替换时返回 errEmailToken, 已过期时返回 errEmailExpired
+// 待验证的账号在用户创建成功后通过 deletePendingRegister 删除, 创建失败时通过 restoreEmailToken 恢复 token
+func consumeEmailToken(rdb *redis.Client, token string) (*pendingRegister, error) {
+	hash := utils.HashToken(token)
+	email, err := rdb.Get(rctx, global.EMAIL_TOKEN+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, errEmailToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := getPendingRegister(rdb, email)
+	if errors.Is(err, redis.Nil) || (err == nil && record.TokenHash != hash) {
+		return nil, errEmailToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, errEmailExpired
+	}
+
+	// 同时验证时只有删除成功的请求可以继续
+	n, err := rdb.Del(rctx, global.EMAIL_TOKEN+hash).Result()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errEmailToken
+	}
+	return record, nil
+}
+
+// deletePendingRegister 删除已经完成注册的待验证账号
+func deletePendingRegister(rdb *redis.Client, record *pendingRegister) error {
+	return rdb.Del(rctx, global.PENDING_REGISTER+record.Email).Err()
+}
+
+// restoreEmailToken 创建用户失败时恢复已经使用的 token, 可以再次通过验证链接注册
+func restoreEmailToken(rdb *redis.Client, record *pendingRegister) error {
+	ttl := time.Until(record.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return rdb.Set(rctx, global.EMAIL_TOKEN+record.TokenHash, record.Email, ttl).Err()
+}
+
+// getPendingRegister 获取邮箱待验证的账号, 不存在时返回 redis.Nil
+func getPendingRegister(rdb *redis.Client, email string) (*pendingRegister, error) {
+	data, err := rdb.Get(rctx, global.PENDING_REGISTER+email).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var record pendingRegister
+	return &record, json.Unmarshal(data, &record)
+}
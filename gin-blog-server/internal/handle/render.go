@@ -0,0 +1,44 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils/markdown"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// renderCacheExpire 文章渲染结果的缓存时间, 文章变化时通过 RemoveArticleCache 提前删除
+const renderCacheExpire = 7 * 24 * time.Hour
+
+// renderArticle 获取文章正文渲染后的 HTML, 目录, 字数和阅读时长, 优先使用 Redis 中的缓存
+// 缓存 key 包含文章更新时间, 即使缓存没有及时删除也不会返回旧内容
+// 缓存读写失败不影响渲染, 只记录日志
+func renderArticle(rdb *redis.Client, article *model.Article) (*markdown.Rendered, error) {
+	key := global.ARTICLE_RENDER + strconv.Itoa(article.ID) + ":" + strconv.FormatInt(article.UpdatedAt.UnixMilli(), 10)
+
+	data, err := rdb.Get(rctx, key).Bytes()
+	if err == nil {
+		var rendered markdown.Rendered
+		if err := json.Unmarshal(data, &rendered); err == nil {
+			return &rendered, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		slog.Warn("读取文章渲染缓存失败", "id", article.ID, "err", err)
+	}
+
+	rendered, err := markdown.Render(article.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ = json.Marshal(rendered)
+	if err := rdb.Set(rctx, key, data, renderCacheExpire).Err(); err != nil {
+		slog.Warn("写入文章渲染缓存失败", "id", article.ID, "err", err)
+	}
+	return rendered, nil
+}
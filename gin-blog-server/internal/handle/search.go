@@ -0,0 +1,84 @@
+package handle
+
+import (
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils/search"
+	"gorm.io/gorm"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// 搜索结果中关键字的高亮样式, 以及摘要长度
+const (
+	highlightPre  = "<span style='color:#f47466'>"
+	highlightPost = "</span>"
+	snippetLength = 120
+)
+
+// articleIndex 文章全文索引 (进程内), 启动时构建, 文章 新增/编辑/删除 时增量更新
+var articleIndex atomic.Pointer[search.Index]
+
+// articleIndexMu 串行化索引的重建和增量更新
+// 否则重建期间的增量更新会写入旧的索引, 而新索引基于更早的数据构建, 这些更新会丢失
+var articleIndexMu sync.Mutex
+
+// getArticleIndex 获取文章全文索引, 未构建时返回空索引
+func getArticleIndex() *search.Index {
+	if idx := articleIndex.Load(); idx != nil {
+		return idx
+	}
+	return search.NewIndex()
+}
+
+// BuildArticleIndex 从数据库重新构建文章全文索引, 返回索引的文章数量
+// 构建完成后再替换旧的索引, 构建期间不影响搜索, 但增量更新需要等待构建完成
+func BuildArticleIndex(db *gorm.DB) (int, error) {
+	articleIndexMu.Lock()
+	defer articleIndexMu.Unlock()
+
+	list, err := model.GetArticleSearchDocs(db, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	idx := search.NewIndex()
+	for _, article := range list {
+		idx.Add(article.ID, article.Title, article.Content)
+	}
+	articleIndex.Store(idx)
+	return idx.Len(), nil
+}
+
+// refreshArticleIndex 文章变化后更新全文索引, 在回收站中或已删除的文章从索引中移除
+// 索引更新失败不影响文章操作本身, 只记录日志, 可以通过重建索引修复
+func refreshArticleIndex(db *gorm.DB, ids ...int) {
+	articleIndexMu.Lock()
+	defer articleIndexMu.Unlock()
+
+	idx := articleIndex.Load()
+	if idx == nil || len(ids) == 0 {
+		return
+	}
+
+	list, err := model.GetArticleSearchDocs(db, ids)
+	if err != nil {
+		slog.Error("更新文章全文索引失败", "ids", ids, "err", err)
+		return
+	}
+
+	found := make(map[int]bool, len(list))
+	for _, article := range list {
+		found[article.ID] = true
+		if article.IsDelete {
+			idx.Remove(article.ID)
+		} else {
+			idx.Add(article.ID, article.Title, article.Content)
+		}
+	}
+	for _, id := range ids {
+		if !found[id] {
+			idx.Remove(id)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/utils/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 登录后签发短期的 access token (JWT) 和长期的 refresh token
+// 同一次登录以及之后刷新得到的 token 属于同一个令牌族, 每次刷新都会更换 refresh token,
+// 已经使用过的 refresh token 再次被使用时说明 refresh token 被盗用, 吊销整个令牌族
+
+// errRefreshReused refresh token 已经使用过, 或者所属的令牌族已经吊销
+var errRefreshReused = errors.New("refresh token 已经使用过或者令牌族已吊销")
+
+// rotateScript 令牌族当前的 refresh token 为 ARGV[1] 时替换为 ARGV[2], 保证同一个 refresh token 只能刷新一次
+var rotateScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
+// TokenVO 登录, 刷新时返回的 token
+type TokenVO struct {
+	Token        string `json:"token"`         // access token
+	RefreshToken string `json:"refresh_token"` // refresh token
+	ExpiresIn    int64  `json:"expires_in"`    // access token 的有效期 (秒)
+}
+
+// refreshRecord Redis 中保存的 refresh token 信息
+type refreshRecord struct {
+	UserId int    `json:"user_id"`
+	Family string `json:"family"`
+}
+
+// issueTokens 登录时签发 token, 创建新的令牌族
+func issueTokens(db *gorm.DB, rdb *redis.Client, userId int, roleIds []int) (TokenVO, error) {
+	family := jwt.NewTokenId()
+	vo, hash, err := newTokens(db, rdb, userId, roleIds, family)
+	if err != nil {
+		return vo, err
+	}
+
+	expire := global.GetConfig().RefreshExpire()
+	userKey := global.USER_TOKEN_FAMILY + strconv.Itoa(userId)
+	pipe := rdb.TxPipeline()
+	pipe.Set(rctx, global.TOKEN_FAMILY+family, hash, expire)
+	pipe.SAdd(rctx, userKey, family)
+	pipe.Expire(rctx, userKey, expire)
+	_, err = pipe.Exec(rctx)
+	return vo, err
+}
+
+// getRefreshToken 获取 refresh token 的信息和摘要, 不存在或者已过期时返回 redis.Nil
+func getRefreshToken(rdb *redis.Client, refreshToken string) (record refreshRecord, hash string, err error) {
+	hash = jwt.HashToken(refreshToken)
+	data, err := rdb.Get(rctx, global.REFRESH_TOKEN+hash).Bytes()
+	if err != nil {
+		return record, hash, err
+	}
+	err = json.Unmarshal(data, &record)
+	return record, hash, err
+}
+
+// rotateTokens 刷新 token: 签发新的 token 并替换令牌族当前的 refresh token
+// 令牌族已吊销或者 refresh token 已经使用过时, 吊销令牌族并返回 errRefreshReused
+func rotateTokens(db *gorm.DB, rdb *redis.Client, record refreshRecord, oldHash string, roleIds []int) (TokenVO, error) {
+	vo, hash, err := newTokens(db, rdb, record.UserId, roleIds, record.Family)
+	if err != nil {
+		return vo, err
+	}
+
+	expire := global.GetConfig().RefreshExpire()
+	ok, err := rotateScript.Run(rctx, rdb, []string{global.TOKEN_FAMILY + record.Family}, oldHash, hash, expire.Milliseconds()).Bool()
+	if err != nil {
+		return TokenVO{}, err
+	}
+	if !ok {
+		if err := revokeFamily(rdb, record.UserId, record.Family); err != nil {
+			return TokenVO{}, err
+		}
+		return TokenVO{}, errRefreshReused
+	}
+	return vo, rdb.Expire(rctx, global.USER_TOKEN_FAMILY+strconv.Itoa(record.UserId), expire).Err()
+}
+
+// newTokens 签发令牌族中新的 access token 和 refresh token, 返回 refresh token 的摘要
+// 使用过的 refresh token 保留到过期, 用于发现重复使用
+func newTokens(db *gorm.DB, rdb *redis.Client, userId int, roleIds []int, family string) (TokenVO, string, error) {
+	conf := global.GetConfig()
+	keys, err := loadKeys(db, false)
+	if err != nil {
+		return TokenVO{}, "", err
+	}
+	token, _, err := jwt.GenToken(keys, conf.JWT.Issuer, conf.AccessExpire(), userId, roleIds, family)
+	if err != nil {
+		return TokenVO{}, "", err
+	}
+
+	refreshToken := jwt.GenRefreshToken()
+	hash := jwt.HashToken(refreshToken)
+	data, err := json.Marshal(refreshRecord{UserId: userId, Family: family})
+	if err != nil {
+		return TokenVO{}, "", err
+	}
+	if err := rdb.Set(rctx, global.REFRESH_TOKEN+hash, data, conf.RefreshExpire()).Err(); err != nil {
+		return TokenVO{}, "", err
+	}
+
+	return TokenVO{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(conf.AccessExpire().Seconds()),
+	}, hash, nil
+}
+
+// IsTokenRevoked access token 是否已经被吊销: token 本身或者所属的令牌族被吊销
+func IsTokenRevoked(rdb *redis.Client, claims *jwt.MyClaims) (bool, error) {
+	keys := []string{global.REVOKED_TOKEN + claims.ID}
+	if claims.Family != "" {
+		keys = append(keys, global.REVOKED_FAMILY+claims.Family)
+	}
+	n, err := rdb.Exists(rctx, keys...).Result()
+	return n > 0, err
+}
+
+// revokeToken 吊销 access token 及其令牌族, 用于退出登录
+func revokeToken(rdb *redis.Client, claims *jwt.MyClaims) error {
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl > 0 {
+		if err := rdb.Set(rctx, global.REVOKED_TOKEN+claims.ID, 1, ttl).Err(); err != nil {
+			return err
+		}
+	}
+	if claims.Family == "" {
+		return nil
+	}
+	return revokeFamily(rdb, claims.UserId, claims.Family)
+}
+
+// revokeFamily 吊销令牌族: 令牌族中未过期的 access token 不能再使用, refresh token 不能再刷新
+// 吊销记录保留到令牌族中最后签发的 access token 过期
+func revokeFamily(rdb *redis.Client, userId int, family string) error {
+	pipe := rdb.TxPipeline()
+	pipe.Set(rctx, global.REVOKED_FAMILY+family, 1, global.GetConfig().AccessExpire())
+	pipe.Del(rctx, global.TOKEN_FAMILY+family)
+	pipe.SRem(rctx, global.USER_TOKEN_FAMILY+strconv.Itoa(userId), family)
+	_, err := pipe.Exec(rctx)
+	return err
+}
+
+// RevokeUserTokens 吊销用户的所有令牌族, 用于修改密码, 强制下线
+func RevokeUserTokens(rdb *redis.Client, userId int) error {
+	families, err := rdb.SMembers(rctx, global.USER_TOKEN_FAMILY+strconv.Itoa(userId)).Result()
+	if err != nil {
+		return err
+	}
+	for _, family := range families {
+		if err := revokeFamily(rdb, userId, family); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bearerToken 请求头 Authorization 中的 token, 格式不正确时返回空字符串
+func bearerToken(c *gin.Context) string {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || scheme != "Bearer" {
+		return ""
+	}
+	return token
+}
@@ -0,0 +1,545 @@
+package handle
+
+import (
+	"encoding/json"
+	"errors"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils"
+	"gin-blog-server/internal/utils/totp"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// 两步验证 (TOTP): 开启后登录分为两步, 密码正确时返回短期有效的 challenge, 再使用 challenge 和验证码换取 token
+// 角色要求两步验证但用户还没有开启时, 使用 challenge 获取密钥, 完成设置的同时登录
+
+const (
+	challengeExpire      = 5 * time.Minute
+	challengeMaxAttempts = 5 // 每个 challenge 允许验证失败的次数, 超过后需要重新输入密码
+	totpSetupExpire      = 10 * time.Minute
+)
+
+var (
+	errChallenge     = errors.New("两步验证 challenge 不存在或已过期")
+	errTwoFactorCode = errors.New("两步验证码错误")
+)
+
+// usedStepScript 记录用户最后使用的验证码时间步, 时间步不大于上次使用的时间步时返回 0
+var usedStepScript = redis.NewScript(`
+local last = tonumber(redis.call("GET", KEYS[1]) or "0")
+if tonumber(ARGV[1]) <= last then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// loginChallenge Redis 中保存的两步验证登录状态
+type loginChallenge struct {
+	UserId   int    `json:"user_id"`
+	Enroll   bool   `json:"enroll"`           // 需要先设置两步验证
+	Secret   string `json:"secret,omitempty"` // 设置两步验证时生成的密钥
+	Attempts int    `json:"attempts"`         // 验证失败的次数
+}
+
+// TwoFactorVO 需要两步验证时, 登录接口返回的 challenge
+type TwoFactorVO struct {
+	Challenge string `json:"challenge"`
+	Enroll    bool   `json:"enroll"`     // 为 true 时需要先通过 /login/2fa/setup 获取密钥
+	ExpiresIn int64  `json:"expires_in"` // challenge 的有效期 (秒)
+}
+
+// TwoFactorSetupVO 设置两步验证时返回的密钥
+type TwoFactorSetupVO struct {
+	Secret string `json:"secret"` // base32 密钥, 用于手动输入
+	URI    string `json:"uri"`    // otpauth URI, 生成二维码供认证器应用扫描
+}
+
+// TwoFactorStatusVO 当前用户的两步验证状态
+type TwoFactorStatusVO struct {
+	Enabled       bool  `json:"enabled"`
+	Required      bool  `json:"required"`       // 角色要求开启两步验证
+	RecoveryCodes int64 `json:"recovery_codes"` // 未使用的恢复码数量
+}
+
+// RecoveryCodesVO 新生成的恢复码, 只在生成时返回一次
+type RecoveryCodesVO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type LoginTwoFactorReq struct {
+	Challenge    string `json:"challenge" binding:"required"`
+	Code         string `json:"code"`          // 认证器中的 6 位验证码
+	RecoveryCode string `json:"recovery_code"` // 无法使用认证器时使用恢复码, 设置两步验证时不能使用
+}
+
+type ChallengeReq struct {
+	Challenge string `json:"challenge" binding:"required"`
+}
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorReq struct {
+	Password     string `json:"password" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// LoginTwoFactorSetup 登录时设置两步验证: 角色要求两步验证但用户还没有开启时, 使用 challenge 获取密钥
+// 同一个 challenge 多次获取时返回相同的密钥
+// @Summary 登录时设置两步验证
+// @Description 登录接口返回的 two_factor.enroll 为 true 时调用, 使用认证器添加密钥后通过 /login/2fa 完成登录
+// @Tags UserAuth
+// @Param form body ChallengeReq true "challenge"
+// @Accept json
+// @Produce json
+// @Success 0 {object} Response[TwoFactorSetupVO]
+// @Router /login/2fa/setup [post]
+func (*UserAuth) LoginTwoFactorSetup(c *gin.Context) {
+	var req ChallengeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	db, rdb := GetDB(c), GetRDB(c)
+	ch, key, err := getLoginChallenge(rdb, req.Challenge)
+	if err != nil {
+		returnChallengeError(c, err)
+		return
+	}
+	if !ch.Enroll {
+		ReturnError(c, global.ErrTwoFactorEnabled, nil)
+		return
+	}
+
+	auth, err := model.GetUserAuthInfoById(db, ch.UserId)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	if ch.Secret == "" {
+		ch.Secret = totp.GenerateSecret()
+		if err := saveLoginChallenge(rdb, key, ch); err != nil {
+			ReturnError(c, global.ErrRedisOp, err)
+			return
+		}
+	}
+
+	ReturnSuccess(c, TwoFactorSetupVO{Secret: ch.Secret, URI: totp.URI(totpIssuer(db, rdb), auth.Username, ch.Secret)})
+}
+
+// LoginTwoFactor 登录的第二步: 使用 challenge 和验证码 (或者恢复码) 换取 token
+// 登录时设置两步验证的, 验证通过后开启两步验证, 同时返回恢复码
+// @Summary 两步验证登录
+// @Description 每个 challenge 最多验证失败 5 次, 之后需要重新输入密码
+// @Tags UserAuth
+// @Param form body LoginTwoFactorReq true "challenge 和验证码"
+// @Accept json
+// @Produce json
+// @Success 0 {object} Response[LoginVO]
+// @Router /login/2fa [post]
+func (*UserAuth) LoginTwoFactor(c *gin.Context) {
+	var req LoginTwoFactorReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	db, rdb := GetDB(c), GetRDB(c)
+	ch, key, err := getLoginChallenge(rdb, req.Challenge)
+	if err != nil {
+		returnChallengeError(c, err)
+		return
+	}
+
+	auth, err := model.GetUserAuthInfoById(db, ch.UserId)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	// 两步验证失败同样计入登录失败次数, 账号锁定后 challenge 不能继续使用
+	if ttl, err := loginLockTTL(rdb, loginName(auth.Username)); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	} else if ttl > 0 {
+		rdb.Del(rctx, key)
+		returnLocked(c, ttl)
+		return
+	}
+
+	// 设置两步验证时使用 challenge 中的密钥验证, 不能使用恢复码
+	switch {
+	case ch.Enroll && ch.Secret == "":
+		ReturnError(c, global.ErrTwoFactorState, nil)
+		return
+	case ch.Enroll:
+		err = checkTOTP(rdb, auth.ID, ch.Secret, req.Code)
+	case !auth.TotpEnabled:
+		// 密码验证之后关闭了两步验证
+		returnChallengeError(c, errChallenge)
+		return
+	default:
+		err = checkSecondFactor(db, rdb, auth, req.Code, req.RecoveryCode)
+	}
+	if errors.Is(err, errTwoFactorCode) {
+		slog.Warn("两步验证失败", "user_id", auth.ID, "ip", c.ClientIP())
+		if err := failLoginChallenge(rdb, key, ch); err != nil {
+			ReturnError(c, global.ErrRedisOp, err)
+			return
+		}
+		loginFailed(c, rdb, auth.Username, global.ErrTwoFactorCode)
+		return
+	}
+	if err != nil {
+		ReturnError(c, global.ErrUserAuth, err)
+		return
+	}
+
+	// 同时使用同一个 challenge 时只有删除成功的请求可以继续
+	if n, err := rdb.Del(rctx, key).Result(); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	} else if n == 0 {
+		returnChallengeError(c, errChallenge)
+		return
+	}
+
+	var recoveryCodes []string
+	if ch.Enroll {
+		codes, hashes := newRecoveryCodes()
+		if err := model.EnableTwoFactor(db, auth.ID, ch.Secret, hashes); err != nil {
+			ReturnError(c, global.ErrDbOp, err)
+			return
+		}
+		slog.Info("用户登录时开启两步验证", "user_id", auth.ID)
+		recoveryCodes = codes
+	}
+
+	completeLogin(c, auth, recoveryCodes)
+}
+
+// GetTwoFactor 获取当前用户的两步验证状态
+// @Summary 两步验证状态
+// @Tags User
+// @Produce json
+// @Success 0 {object} Response[TwoFactorStatusVO]
+// @Router /user/2fa [get]
+func (*User) GetTwoFactor(c *gin.Context) {
+	auth, err := CurrentUserAuth(c)
+	if err != nil {
+		ReturnError(c, global.ErrUserAuth, err)
+		return
+	}
+
+	db := GetDB(c)
+	required, err := model.IsTwoFactorRequired(db, auth.ID)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	count, err := model.CountRecoveryCodes(db, auth.ID)
+	if err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+
+	ReturnSuccess(c, TwoFactorStatusVO{Enabled: auth.TotpEnabled, Required: required, RecoveryCodes: count})
+}
+
+// SetupTwoFactor 生成新的 TOTP 密钥, 使用认证器添加后通过 EnableTwoFactor 确认开启
+// @Summary 设置两步验证
+// @Description 密钥 10 分钟内有效
+// @Tags User
+// @Produce json
+// @Success 0 {object} Response[TwoFactorSetupVO]
+// @Router /user/2fa/setup [post]
+func (*User) SetupTwoFactor(c *gin.Context) {
+	auth, err := CurrentUserAuth(c)
+	if err != nil {
+		ReturnError(c, global.ErrUserAuth, err)
+		return
+	}
+	if auth.TotpEnabled {
+		ReturnError(c, global.ErrTwoFactorEnabled, nil)
+		return
+	}
+
+	rdb := GetRDB(c)
+	secret := totp.GenerateSecret()
+	if err := rdb.Set(rctx, global.TOTP_SETUP+strconv.Itoa(auth.ID), secret, totpSetupExpire).Err(); err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	ReturnSuccess(c, TwoFactorSetupVO{Secret: secret, URI: totp.URI(totpIssuer(GetDB(c), rdb), auth.Username, secret)})
+}
+
+// EnableTwoFactor 输入认证器中的验证码, 确认开启两步验证, 返回恢复码
+// @Summary 开启两步验证
+// @Tags User
+// @Param form body TwoFactorCodeReq true "验证码"
+// @Accept json
+// @Produce json
+// @Success 0 {object} Response[RecoveryCodesVO]
+// @Router /user/2fa/enable [post]
+func (*User) EnableTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	auth, err := CurrentUserAuth(c)
+	if err != nil {
+		ReturnError(c, global.ErrUserAuth, err)
+		return
+	}
+	if auth.TotpEnabled {
+		ReturnError(c, global.ErrTwoFactorEnabled, nil)
+		return
+	}
+
+	rdb := GetRDB(c)
+	setupKey := global.TOTP_SETUP + strconv.Itoa(auth.ID)
+	secret, err := rdb.Get(rctx, setupKey).Result()
+	if errors.Is(err, redis.Nil) {
+		ReturnError(c, global.ErrTwoFactorState, nil)
+		return
+	}
+	if err != nil {
+		ReturnError(c, global.ErrRedisOp, err)
+		return
+	}
+
+	if !returnTwoFactorError(c, checkTOTP(rdb, auth.ID, secret, req.Code)) {
+		return
+	}
+
+	codes, hashes := newRecoveryCodes()
+	if err := model.EnableTwoFactor(GetDB(c), auth.ID, secret, hashes); err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	rdb.Del(rctx, setupKey)
+
+	ReturnSuccess(c, RecoveryCodesVO{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor 关闭两步验证, 需要输入密码和验证码 (或者恢复码), 角色要求两步验证时不能关闭
+// @Summary 关闭两步验证
+// @Tags User
+// @Param form body DisableTwoFactorReq true "密码和验证码"
+// @Accept json
+// @Produce json
+// @Success 0 {object} string
+// @Router /user/2fa/disable [post]
+func (*User) DisableTwoFactor(c *gin.Context) {
+	var req DisableTwoFactorReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	auth, err := CurrentUserAuth(c)
+	if err != nil {
+		ReturnError(c, global.ErrUserAuth, err)
+		return
+	}
+	if !auth.TotpEnabled {
+		ReturnError(c, global.ErrTwoFactorOff, nil)
+		return
+	}
+
+	db := GetDB(c)
+	if required, err := model.IsTwoFactorRequired(db, auth.ID); err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	} else if required {
+		ReturnError(c, global.ErrTwoFactorRequire, nil)
+		return
+	}
+
+	if !utils.BcryptCheck(req.Password, auth.Password) {
+		ReturnError(c, global.ErrPassword, nil)
+		return
+	}
+	if !returnTwoFactorError(c, checkSecondFactor(db, GetRDB(c), auth, req.Code, req.RecoveryCode)) {
+		return
+	}
+
+	if err := model.DisableTwoFactor(db, auth.ID); err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	slog.Info("用户关闭两步验证", "user_id", auth.ID)
+	ReturnSuccess(c, nil)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码, 之前的恢复码全部失效
+// @Summary 重新生成恢复码
+// @Tags User
+// @Param form body TwoFactorCodeReq true "验证码"
+// @Accept json
+// @Produce json
+// @Success 0 {object} Response[RecoveryCodesVO]
+// @Router /user/2fa/recovery-codes [post]
+func (*User) RegenerateRecoveryCodes(c *gin.Context) {
+	var req TwoFactorCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ReturnError(c, global.ErrRequest, err)
+		return
+	}
+
+	auth, err := CurrentUserAuth(c)
+	if err != nil {
+		ReturnError(c, global.ErrUserAuth, err)
+		return
+	}
+	if !auth.TotpEnabled {
+		ReturnError(c, global.ErrTwoFactorOff, nil)
+		return
+	}
+	if !returnTwoFactorError(c, checkTOTP(GetRDB(c), auth.ID, auth.TotpSecret, req.Code)) {
+		return
+	}
+
+	codes, hashes := newRecoveryCodes()
+	if err := model.ReplaceRecoveryCodes(GetDB(c), auth.ID, hashes); err != nil {
+		ReturnError(c, global.ErrDbOp, err)
+		return
+	}
+	ReturnSuccess(c, RecoveryCodesVO{RecoveryCodes: codes})
+}
+
+// newLoginChallenge 密码验证通过后创建两步验证登录的 challenge
+func newLoginChallenge(rdb *redis.Client, userId int, enroll bool) (*TwoFactorVO, error) {
+	token, hash := utils.GenToken()
+	data, err := json.Marshal(loginChallenge{UserId: userId, Enroll: enroll})
+	if err != nil {
+		return nil, err
+	}
+	if err := rdb.Set(rctx, global.LOGIN_CHALLENGE+hash, data, challengeExpire).Err(); err != nil {
+		return nil, err
+	}
+	return &TwoFactorVO{Challenge: token, Enroll: enroll, ExpiresIn: int64(challengeExpire.Seconds())}, nil
+}
+
+// getLoginChallenge 获取 challenge 的状态和 Redis key, 不存在或已过期时返回 errChallenge
+func getLoginChallenge(rdb *redis.Client, challenge string) (*loginChallenge, string, error) {
+	key := global.LOGIN_CHALLENGE + utils.HashToken(challenge)
+	data, err := rdb.Get(rctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, key, errChallenge
+	}
+	if err != nil {
+		return nil, key, err
+	}
+	var ch loginChallenge
+	return &ch, key, json.Unmarshal(data, &ch)
+}
+
+// saveLoginChallenge 更新 challenge 的状态, 不改变过期时间
+func saveLoginChallenge(rdb *redis.Client, key string, ch *loginChallenge) error {
+	data, err := json.Marshal(ch)
+	if err != nil {
+		return err
+	}
+	return rdb.SetArgs(rctx, key, data, redis.SetArgs{KeepTTL: true, Mode: "XX"}).Err()
+}
+
+// failLoginChallenge 记录一次验证失败, 失败次数达到上限时删除 challenge
+func failLoginChallenge(rdb *redis.Client, key string, ch *loginChallenge) error {
+	ch.Attempts++
+	if ch.Attempts >= challengeMaxAttempts {
+		return rdb.Del(rctx, key).Err()
+	}
+	err := saveLoginChallenge(rdb, key, ch)
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
+
+// checkSecondFactor 验证 TOTP 验证码, 或者使用一个恢复码
+func checkSecondFactor(db *gorm.DB, rdb *redis.Client, auth *model.UserAuth, code, recoveryCode string) error {
+	if recoveryCode == "" {
+		return checkTOTP(rdb, auth.ID, auth.TotpSecret, code)
+	}
+	ok, err := model.UseRecoveryCode(db, auth.ID, totp.HashRecoveryCode(recoveryCode))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errTwoFactorCode
+	}
+	slog.Info("用户使用恢复码完成两步验证", "user_id", auth.ID)
+	return nil
+}
+
+// checkTOTP 验证 TOTP 验证码, 每个验证码只能使用一次
+func checkTOTP(rdb *redis.Client, userId int, secret, code string) error {
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return errTwoFactorCode
+	}
+	// 验证码在前后各一个时间步内有效, 记录保留到验证码失效
+	ttl := time.Duration(totp.Period*(2*totp.Skew+1)) * time.Second
+	unused, err := usedStepScript.Run(rctx, rdb, []string{global.TOTP_USED + strconv.Itoa(userId)}, step, ttl.Milliseconds()).Bool()
+	if err != nil {
+		return err
+	}
+	if !unused {
+		return errTwoFactorCode
+	}
+	return nil
+}
+
+// newRecoveryCodes 生成恢复码, 返回恢复码和它们的摘要
+func newRecoveryCodes() (codes, hashes []string) {
+	codes = totp.GenerateRecoveryCodes(totp.RecoveryCodeCount)
+	hashes = make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = totp.HashRecoveryCode(code)
+	}
+	return codes, hashes
+}
+
+// totpIssuer 认证器中显示的服务名称, 使用博客配置中的网站名称
+func totpIssuer(db *gorm.DB, rdb *redis.Client) string {
+	if config, err := getConfigMap(db, rdb); err == nil && config[global.CONFIG_WEBSITE_NAME] != "" {
+		return config[global.CONFIG_WEBSITE_NAME]
+	}
+	return "gin-blog"
+}
+
+// returnChallengeError 返回 challenge 相关的错误
+func returnChallengeError(c *gin.Context, err error) {
+	if errors.Is(err, errChallenge) {
+		ReturnError(c, global.ErrTwoFactorExpired, nil)
+		return
+	}
+	ReturnError(c, global.ErrRedisOp, err)
+}
+
+// returnTwoFactorError 验证码错误或者出错时返回错误响应, 返回是否验证通过
+func returnTwoFactorError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errTwoFactorCode):
+		ReturnError(c, global.ErrTwoFactorCode, nil)
+	default:
+		ReturnError(c, global.ErrUserAuth, err)
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package handle
+
+import (
+	"github.com/redis/go-redis/v9"
+	"os"
+	"testing"
+)
+
+// newTestRedis 连接测试使用的 Redis (环境变量 TEST_REDIS_ADDR, 默认 localhost:6379), 无法连接时跳过测试
+func newTestRedis(t *testing.T) *redis.Client {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	if err := rdb.Ping(rctx).Err(); err != nil {
+		rdb.Close()
+		t.Skipf("无法连接 Redis %s: %v", addr, err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
@@ -3,6 +3,7 @@ package ginblog
 import (
 	"context"
 	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/handle"
 	"gin-blog-server/internal/model"
 	"github.com/glebarez/sqlite"
 	"github.com/redis/go-redis/v9"
@@ -138,6 +139,80 @@ func InitDatabase(conf *global.Config) *gorm.DB {
 	return db
 }
 
+// InitSearchIndex 构建文章全文索引, 失败时只打印日志, 可以在后台手动重建
+func InitSearchIndex(db *gorm.DB) {
+	count, err := handle.BuildArticleIndex(db)
+	if err != nil {
+		log.Println("文章全文索引构建失败: ", err)
+		return
+	}
+	log.Println("文章全文索引构建成功, 文章数量: ", count)
+}
+
+// SyncResources 将需要登录的接口路由同步到资源表, 需要在 RegisterHandlers 之后调用
+func SyncResources(db *gorm.DB) *model.ResourceSyncResult {
+	result, err := handle.SyncResources(db)
+	if err != nil {
+		log.Fatal("资源同步失败: ", err)
+	}
+	for _, r := range result.Added {
+		slog.Warn("新增资源, 请为角色分配权限", "method", r.Method, "url", r.Url, "name", r.Name)
+	}
+	for _, r := range result.Stale {
+		slog.Warn("资源已失效, 路由中不存在", "id", r.ID, "method", r.Method, "url", r.Url, "name", r.Name)
+	}
+	log.Printf("资源同步完成, 路由数量: %d, 新增: %d, 失效: %d\n", result.Routes, len(result.Added), len(result.Stale))
+	return result
+}
+
+// ExportRBAC 导出权限配置 (YAML) 到文件, path 为 "-" 时输出到标准输出
+func ExportRBAC(db *gorm.DB, path string) error {
+	doc, err := model.ExportRBAC(db)
+	if err != nil {
+		return err
+	}
+	data, err := model.MarshalRBAC(doc)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ImportRBAC 从文件导入权限配置 (YAML), 修改后通过 rdb 通知运行中的服务刷新权限表, dry run 时 rdb 可以为 nil
+func ImportRBAC(db *gorm.DB, rdb *redis.Client, path string, dryRun, prune bool) (*model.RBACImportResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := model.ParseRBAC(data)
+	if err != nil {
+		return nil, err
+	}
+	result, err := model.ImportRBAC(db, doc, dryRun, prune)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun && len(result.Changes) > 0 && rdb != nil {
+		handle.InvalidatePolicy(rdb)
+	}
+	return result, nil
+}
+
+// RotateJWTKey 立即轮换 JWT 签名密钥, 返回新密钥的 kid
+// 运行中的服务最多 5 分钟后使用新密钥签名, 旧密钥签名的 token 在过期之前仍然有效
+func RotateJWTKey(db *gorm.DB) (string, error) {
+	return handle.RotateSigningKey(db, 0)
+}
+
+// StartPolicyWatcher 在后台订阅权限变化通知, 多实例部署时各实例同步清空内存中的权限表
+func StartPolicyWatcher(ctx context.Context, rdb *redis.Client) {
+	go handle.WatchPolicy(ctx, rdb)
+}
+
 // InitRedis 初始化 Redis 客户端并测试连接
 func InitRedis(conf *global.Config) *redis.Client {
 	// 创建一个 Redis 客户端实例，配置连接参数
@@ -4,9 +4,11 @@ import (
 	"gin-blog-server/docs"
 	"gin-blog-server/internal/handle"
 	"gin-blog-server/internal/middleware"
+	"gin-blog-server/internal/model"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"strings"
 )
 
 var (
@@ -43,22 +45,58 @@ func RegisterHandlers(r *gin.Engine) {
 	// 调用 registerBaseHandler 注册其他基础的路由处理
 	// 例如，可能是一些常见的基础 API 路由
 	registerBaseHandler(r)
+	// 记录需要登录的接口路由, 用于同步资源表
+	registered := routeSet(r.Routes())
 	registerAdminHandler(r)
+	registerBlogAuthHandler(r)
+	handle.SetAuthRoutes(newRoutes(r.Routes(), registered))
 	registerBlogHandler(r)
 }
 
+func routeSet(routes gin.RoutesInfo) map[string]bool {
+	set := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		set[route.Method+" "+route.Path] = true
+	}
+	return set
+}
+
+// newRoutes 不在 registered 中的 /api 路由, 去掉 /api 前缀
+func newRoutes(routes gin.RoutesInfo, registered map[string]bool) []model.Route {
+	list := make([]model.Route, 0)
+	for _, route := range routes {
+		if registered[route.Method+" "+route.Path] || !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		list = append(list, model.Route{Method: route.Method, Url: strings.TrimPrefix(route.Path, "/api")})
+	}
+	return list
+}
+
 // 通用接口: 全部不需要 登录 + 鉴权
+// 新增接口时需要同时加到 middleware 的 publicRoutes 中, 否则按照未注册资源的访问策略处理
 func registerBaseHandler(r *gin.Engine) {
 	base := r.Group("/api")
+	base.Use(middleware.JWTAuth())
 
 	// TODO: 登录, 注册 记录日志
-	base.POST("/login", userAuthAPI.Login)            // 登录
-	base.POST("/register", userAuthAPI.Register)      // 注册
-	base.GET("/email/verify", userAuthAPI.VerifyCode) // 邮箱验证
-	base.GET("/logout", userAuthAPI.Logout)           // 退出登录
-	base.POST("/report", blogInfoAPI.Report)          // 上报信息
-	base.GET("/config", blogInfoAPI.GetConfigMap)     // 获取配置
-	base.PATCH("/config", blogInfoAPI.UpdateConfig)   // 更新配置
+	base.POST("/login", userAuthAPI.Login)                             // 登录
+	base.POST("/login/2fa", userAuthAPI.LoginTwoFactor)                // 两步验证登录
+	base.POST("/login/2fa/setup", userAuthAPI.LoginTwoFactorSetup)     // 登录时设置两步验证
+	base.GET("/captcha", userAuthAPI.GetCaptcha)                       // 获取图形验证码
+	base.GET("/oauth/providers", userAuthAPI.GetOAuthProviders)        // 第三方登录方式
+	base.GET("/oauth/:provider/authorize", userAuthAPI.OAuthAuthorize) // 发起第三方登录
+	base.GET("/oauth/:provider/callback", userAuthAPI.OAuthCallback)   // 第三方登录回调
+	base.POST("/oauth/login", userAuthAPI.OAuthLogin)                  // 第三方登录
+	base.POST("/refresh", userAuthAPI.RefreshToken)                    // 刷新 token
+	base.POST("/register", userAuthAPI.Register)                       // 注册
+	base.GET("/email/verify", userAuthAPI.VerifyCode)                  // 邮箱验证
+	base.POST("/email/resend", userAuthAPI.ResendEmail)                // 重新发送验证邮件
+	base.POST("/password/forgot", userAuthAPI.ForgotPassword)          // 申请重置密码
+	base.POST("/password/reset", userAuthAPI.ResetPassword)            // 重置密码
+	base.GET("/logout", userAuthAPI.Logout)                            // 退出登录
+	base.POST("/report", blogInfoAPI.Report)                           // 上报信息
+	base.GET("/config", blogInfoAPI.GetConfigMap)                      // 获取配置
 }
 
 // 后台管理系统的接口: 全部需要 登录 + 鉴权
@@ -71,8 +109,9 @@ func registerAdminHandler(r *gin.Engine) {
 	auth.Use(middleware.OperationLog())
 	auth.Use(middleware.ListenOnline())
 
-	auth.GET("/home", blogInfoAPI.GetHomeInfo) // 后台首页信息
-	auth.POST("/upload", uploadAPI.UploadFile) // 文件上传
+	auth.GET("/home", blogInfoAPI.GetHomeInfo)      // 后台首页信息
+	auth.POST("/upload", uploadAPI.UploadFile)      // 文件上传
+	auth.PATCH("/config", blogInfoAPI.UpdateConfig) // 更新配置
 
 	// 博客设置
 	setting := auth.Group("/setting")
@@ -84,14 +123,23 @@ func registerAdminHandler(r *gin.Engine) {
 	// 用户模块
 	user := auth.Group("/user")
 	{
-		user.GET("/info", userAPI.GetInfo)                           // 获取当前用户信息
-		user.GET("/current", userAPI.UpdateCurrent)                  // 修改当前用户信息
-		user.GET("/list", userAPI.GetList)                           // 用户列表
-		user.PUT("", userAPI.Update)                                 // 更新用户信息
-		user.PUT("/disable", userAPI.UpdateDisable)                  // 修改用户禁用状态
-		user.PUT("/current/password", userAPI.UpdateCurrentPassword) // 修改当前用户密码
-		user.GET("/online", userAPI.GetOnlineList)                   // 获取在线用户
-		user.POST("/offline/:id", userAPI.ForceOffline)              // 强制用户下线
+		user.GET("/info", userAPI.GetInfo)                                // 获取当前用户信息
+		user.GET("/current", userAPI.UpdateCurrent)                       // 修改当前用户信息
+		user.GET("/list", userAPI.GetList)                                // 用户列表
+		user.PUT("", userAPI.Update)                                      // 更新用户信息
+		user.PUT("/disable", userAPI.UpdateDisable)                       // 修改用户禁用状态
+		user.PUT("/current/password", userAPI.UpdateCurrentPassword)      // 修改当前用户密码
+		user.GET("/2fa", userAPI.GetTwoFactor)                            // 当前用户的两步验证状态
+		user.POST("/2fa/setup", userAPI.SetupTwoFactor)                   // 生成两步验证密钥
+		user.POST("/2fa/enable", userAPI.EnableTwoFactor)                 // 开启两步验证
+		user.POST("/2fa/disable", userAPI.DisableTwoFactor)               // 关闭两步验证
+		user.POST("/2fa/recovery-codes", userAPI.RegenerateRecoveryCodes) // 重新生成恢复码
+		user.GET("/online", userAPI.GetOnlineList)                        // 获取在线用户
+		user.POST("/offline/:id", userAPI.ForceOffline)                   // 强制用户下线
+		user.POST("/unlock/:id", userAPI.Unlock)                          // 解除登录锁定
+		user.GET("/oauth", userAPI.GetOAuthList)                          // 当前用户的第三方账号
+		user.POST("/oauth/:provider", userAPI.LinkOAuth)                  // 绑定第三方账号
+		user.DELETE("/oauth/:provider", userAPI.UnlinkOAuth)              // 解绑第三方账号
 	}
 
 	// 分类模块
@@ -133,6 +181,13 @@ func registerAdminHandler(r *gin.Engine) {
 		articles.DELETE("", articleAPI.Delete)                    // 物理删除文章(删除回收站)
 		articles.POST("/export", articleAPI.Export)               // 导出文章
 		articles.POST("/import", articleAPI.Import)               // 导入文章
+
+		articles.GET("/revision/list", articleAPI.GetRevisionList)         // 文章版本列表
+		articles.GET("/revision/diff", articleAPI.DiffRevision)            // 比较文章版本
+		articles.GET("/revision/:id", articleAPI.GetRevision)              // 文章版本详情
+		articles.POST("/revision/restore/:id", articleAPI.RestoreRevision) // 恢复文章版本
+
+		articles.POST("/search-index/rebuild", articleAPI.RebuildSearchIndex) // 重建文章全文索引
 	}
 
 	// 评论模块
@@ -167,15 +222,20 @@ func registerAdminHandler(r *gin.Engine) {
 		resource.DELETE("/:id", resourceAPI.Delete)             // 删除资源
 		resource.PUT("/anonymous", resourceAPI.UpdateAnonymous) // 修改资源匿名访问
 		resource.GET("/option", resourceAPI.GetOption)          // 资源选项列表(树形)
+		resource.POST("/sync", resourceAPI.Sync)                // 同步路由到资源表
 	}
 
 	// 角色模块
 	role := auth.Group("/role")
 	{
-		role.GET("/list", roleAPI.GetTreeList) // 角色列表(树形)
-		role.POST("", roleAPI.SaveOrUpdate)    // 新增/编辑菜单
-		role.DELETE("", roleAPI.Delete)        // 删除角色
-		role.GET("/option", roleAPI.GetOption) // 角色选项列表(树形)
+		role.GET("/list", roleAPI.GetTreeList)  // 角色列表(树形)
+		role.POST("", roleAPI.SaveOrUpdate)     // 新增/编辑菜单
+		role.DELETE("", roleAPI.Delete)         // 删除角色
+		role.GET("/option", roleAPI.GetOption)  // 角色选项列表(树形)
+		role.GET("/explain", roleAPI.Explain)   // 权限诊断
+		role.GET("/menu", roleAPI.SimulateMenu) // 模拟用户菜单
+		role.GET("/export", roleAPI.Export)     // 导出权限配置
+		role.POST("/import", roleAPI.Import)    // 导入权限配置
 	}
 
 	// 操作日志模块
@@ -202,22 +262,41 @@ func registerBlogHandler(r *gin.Engine) {
 	base.GET("/home", frontAPI.GetHomeInfo)  // 前台首页
 	base.GET("/page", pageAPI.GetList)       // 前台页面
 
+	base.GET("/feed.xml", frontAPI.GetFeed)  // RSS 订阅
+	base.GET("/atom.xml", frontAPI.GetFeed)  // Atom 订阅
+	base.GET("/feed.json", frontAPI.GetFeed) // JSON Feed 订阅
+
+	// 搜索引擎只在网站根路径下查找 robots.txt, sitemap 也只能包含其所在路径下的地址
+	// 部署时需要将前台网站的这几个路径转发到后台
+	r.GET("/robots.txt", frontAPI.GetRobots)         // robots.txt
+	r.GET("/sitemap.xml", frontAPI.GetSitemap)       // sitemap 或 sitemap 索引
+	r.GET("/sitemap/:page", frontAPI.GetSitemapPage) // sitemap 索引中的各部分: /sitemap/1.xml
+	// 其他服务从约定的路径获取公钥验证 token
+	r.GET("/.well-known/jwks.json", userAuthAPI.GetJWKS) // JWKS
+
 	article := base.Group("/article")
 	{
-		article.GET("/list", frontAPI.GetArticleList)    // 前台文章列表
-		article.GET("/:id", frontAPI.GetArticleInfo)     // 前台文章详情
-		article.GET("/archive", frontAPI.GetArchiveList) // 前台文章归档
-		article.GET("/search", frontAPI.SearchArticle)   // 前台文章搜索
+		article.GET("/list", frontAPI.GetArticleList)       // 前台文章列表
+		article.GET("/:id", frontAPI.GetArticleInfo)        // 前台文章详情
+		article.GET("/archive", frontAPI.GetArchiveList)    // 前台文章归档
+		article.GET("/search", frontAPI.SearchArticle)      // 前台文章搜索
+		article.POST("/:id/unlock", frontAPI.UnlockArticle) // 前台解锁私密文章
 	}
 
 	category := base.Group("/category")
 	{
-		category.GET("/list", frontAPI.GetCategoryList) // 前台分类列表
+		category.GET("/list", frontAPI.GetCategoryList)        // 前台分类列表
+		category.GET("/:category/feed.xml", frontAPI.GetFeed)  // 分类 RSS 订阅
+		category.GET("/:category/atom.xml", frontAPI.GetFeed)  // 分类 Atom 订阅
+		category.GET("/:category/feed.json", frontAPI.GetFeed) // 分类 JSON Feed 订阅
 	}
 
 	tag := base.Group("/tag")
 	{
-		tag.GET("/list", frontAPI.GetTagList) // 前台标签列表
+		tag.GET("/list", frontAPI.GetTagList)        // 前台标签列表
+		tag.GET("/:tag/feed.xml", frontAPI.GetFeed)  // 标签 RSS 订阅
+		tag.GET("/:tag/atom.xml", frontAPI.GetFeed)  // 标签 Atom 订阅
+		tag.GET("/:tag/feed.json", frontAPI.GetFeed) // 标签 JSON Feed 订阅
 	}
 
 	link := base.Group("/link")
@@ -235,8 +314,11 @@ func registerBlogHandler(r *gin.Engine) {
 		comment.GET("/list", frontAPI.GetCommentList)                         // 前台评论列表
 		comment.GET("/replies/:comment_id", frontAPI.GetReplyListByCommentId) // 根据评论 id 查询回复
 	}
+}
 
-	// 需要登录才能进行的操作
+// 博客前台需要登录才能进行的操作
+func registerBlogAuthHandler(r *gin.Engine) {
+	base := r.Group("/api/front")
 	base.Use(middleware.JWTAuth())
 	{
 		base.POST("/upload", uploadAPI.UploadFile)    // 文件上传
@@ -9,15 +9,16 @@ import (
 	"gin-blog-server/internal/utils/jwt"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 	"log/slog"
 	"strings"
-	"time"
 )
 
 // JWTAuth 基于 jwt 实现鉴权
 // TODO: 如果存在 session, 则直接从 session 中获取用户信息
 // 从 Authorization 中获取 token, 并解析 token 获取用户信息, 并设置到 session 中
+// 没有对应资源的接口按照配置中的策略处理: allow 跳过鉴权, deny 拒绝访问, authenticated-only 只需要登录
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// FIXME: 前后台 session 混乱, 暂时无法将用户信息挂载在 gin context 缓存
@@ -25,29 +26,36 @@ func JWTAuth() gin.HandlerFunc {
 
 		db := c.MustGet(global.CTX_DB).(*gorm.DB)
 
-		// 系统管理的资源需要进行用户鉴权，其他资源不需要鉴权
-		// TODO: 其实可以要所有的资源都需要鉴权，不然新资源有时候会有 bug
 		url, method := c.FullPath()[4:], c.Request.Method
+		if handle.IsPublicRoute(method, url) {
+			skipCheck(c)
+			return
+		}
+
 		resource, err := model.GetResource(db, url, method)
 		if err != nil {
-			// 没有找到的资源，不需要鉴权，跳过后续的验证过程
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				slog.Debug("[middleware-JWTAuth] resource not exist, skip jwt auth")
-				c.Set("skip_check", true)
-				c.Next()
-				c.Set("skip_check", false)
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				handle.ReturnError(c, global.ErrDbOp, err)
 				return
 			}
-			handle.ReturnError(c, global.ErrDbOp, err)
-			return
-		}
 
-		// 匿名资源，不需要鉴权，跳过后续的验证过程
-		if resource.Anonymous {
+			// 没有找到的资源, 可能是新增路由后没有同步资源表
+			policy := global.GetConfig().UnregisteredPolicy()
+			slog.Warn("[middleware-JWTAuth] resource not exist",
+				"policy", policy, "method", method, "url", url, "path", c.Request.URL.Path, "ip", c.ClientIP())
+			switch policy {
+			case global.POLICY_ALLOW:
+				skipCheck(c)
+				return
+			case global.POLICY_AUTHENTICATED: // 没有资源无法校验角色权限, 登录即可访问
+				c.Set("skip_check", true)
+			default:
+				handle.ReturnError(c, global.ErrUnregistered, method+" "+url)
+				return
+			}
+		} else if resource.Anonymous { // 匿名资源，不需要鉴权，跳过后续的验证过程
 			slog.Debug(fmt.Sprintf("[middleware-JWTAuth] resouce: %s %s is anonymous, skip jwt auth!", url, method))
-			c.Set("skip_check", true)
-			c.Next()
-			c.Set("skip_check", false)
+			skipCheck(c)
 			return
 		}
 
@@ -64,15 +72,27 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := jwt.ParseToken(global.Conf.JWT.Secret, parts[1])
+		claims, err := handle.ParseToken(db, parts[1])
 		if err != nil {
-			handle.ReturnError(c, global.ErrTokenWrong, err)
+			switch {
+			case errors.Is(err, handle.ErrSigningKey):
+				handle.ReturnError(c, global.ErrDbOp, err)
+			case errors.Is(err, jwt.ErrTokenExpired): // token 已经过期, 前端可以使用 refresh token 刷新
+				handle.ReturnError(c, global.ErrTokenRuntime, nil)
+			default:
+				handle.ReturnError(c, global.ErrTokenWrong, err)
+			}
 			return
 		}
 
-		// 判断 token 已经过期
-		if time.Now().Unix() > claims.ExpiresAt.Unix() {
-			handle.ReturnError(c, global.ErrTokenRuntime, nil)
+		// 判断 token 是否已经被吊销: 退出登录, 修改密码, 强制下线
+		revoked, err := handle.IsTokenRevoked(c.MustGet(global.CTX_RDB).(*redis.Client), claims)
+		if err != nil {
+			handle.ReturnError(c, global.ErrRedisOp, err)
+			return
+		}
+		if revoked {
+			handle.ReturnError(c, global.ErrTokenRevoked, nil)
 			return
 		}
 
@@ -93,6 +113,13 @@ func JWTAuth() gin.HandlerFunc {
 	}
 }
 
+// skipCheck 跳过 jwt 鉴权和权限验证
+func skipCheck(c *gin.Context) {
+	c.Set("skip_check", true)
+	c.Next()
+	c.Set("skip_check", false)
+}
+
 // PermissionCheck 资源访问权限验证
 func PermissionCheck() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -117,20 +144,20 @@ func PermissionCheck() gin.HandlerFunc {
 		url := c.FullPath()[4:]
 		method := c.Request.Method
 
-		slog.Debug(fmt.Sprintf("[middleware-PermissionCheck] %v, %v, %v\n", auth.Username, url, method))
+		roleIds := make([]int, 0, len(auth.Roles))
 		for _, role := range auth.Roles {
-			slog.Debug(fmt.Sprintf("[middleware-PermissionCheck] %v\n", role.Name))
-			pass, err := model.CheckRoleAuth(db, role.ID, url, method)
-			if err != nil {
-				handle.ReturnError(c, global.ErrDbOp, err)
-				return
-			}
-			if !pass { // 不能通过验证
-				handle.ReturnError(c, global.ErrPermission, nil)
-				return
-			} else { // 可以通过验证
-				break
-			}
+			roleIds = append(roleIds, role.ID)
+		}
+		slog.Debug(fmt.Sprintf("[middleware-PermissionCheck] %v, %v, %v, %v\n", auth.Username, roleIds, url, method))
+
+		pass, err := handle.CheckPermission(db, roleIds, method, url)
+		if err != nil {
+			handle.ReturnError(c, global.ErrDbOp, err)
+			return
+		}
+		if !pass {
+			handle.ReturnError(c, global.ErrPermission, nil)
+			return
 		}
 
 		slog.Debug("[middleware-PermissionCheck]: pass")
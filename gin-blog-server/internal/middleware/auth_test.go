@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/handle"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newAuthRouter 与 internal/manager.go 中需要登录的接口使用相同的中间件
+// /login 为基础接口, /registered 需要权限, /anonymous 为匿名资源, /unregistered 没有对应的资源
+func newAuthRouter(t *testing.T, db *gorm.DB, rdb *redis.Client) *gin.Engine {
+	assert.Nil(t, db.Create(&model.Resource{Name: "registered", Url: "/registered", Method: http.MethodGet}).Error)
+	assert.Nil(t, db.Create(&model.Resource{Name: "anonymous", Url: "/anonymous", Method: http.MethodGet, Anonymous: true}).Error)
+	handle.InvalidatePolicy(rdb)
+
+	r := gin.New()
+	r.Use(WithGormDB(db), WithRedisDB(rdb), WithCookieStore("test", "secret"))
+	api := r.Group("/api")
+	api.Use(JWTAuth(), PermissionCheck())
+	api.POST("/login", okHandler)
+	api.GET("/registered", okHandler)
+	api.GET("/anonymous", okHandler)
+	api.GET("/unregistered", okHandler)
+	return r
+}
+
+// genToken 为用户签发 access token
+func genToken(t *testing.T, userId int, family string) (string, *jwt.MyClaims) {
+	token, claims, err := jwt.GenToken(jwt.HMACKeySet(global.Conf.JWT.Secret), "test", time.Minute, userId, nil, family)
+	assert.Nil(t, err)
+	return token, claims
+}
+
+func TestJWTAuthUnregistered(t *testing.T) {
+	cases := []struct {
+		policy   string
+		noToken  int // 没有登录时的业务码
+		badToken int // token 错误时的业务码
+	}{
+		{"", global.ErrUnregistered.Code(), global.ErrUnregistered.Code()}, // 默认拒绝访问
+		{"unknown", global.ErrUnregistered.Code(), global.ErrUnregistered.Code()},
+		{global.POLICY_DENY, global.ErrUnregistered.Code(), global.ErrUnregistered.Code()},
+		{global.POLICY_AUTHENTICATED, global.ErrTokenNotExist.Code(), global.ErrTokenWrong.Code()},
+		{global.POLICY_ALLOW, global.SUCCESS, global.SUCCESS},
+	}
+	for _, tc := range cases {
+		testConfig(tc.policy)
+		r := newAuthRouter(t, newTestDB(t), unavailableRedis(t))
+		assert.Equal(t, tc.noToken, doRequest(t, r, http.MethodGet, "/api/unregistered", ""), tc.policy)
+		assert.Equal(t, tc.badToken, doRequest(t, r, http.MethodGet, "/api/unregistered", "bad"), tc.policy)
+	}
+}
+
+func TestJWTAuthPublicRoute(t *testing.T) {
+	// 基础接口不受未注册资源访问策略的影响, 也不需要登录
+	testConfig(global.POLICY_DENY)
+	r := newAuthRouter(t, newTestDB(t), unavailableRedis(t))
+	assert.True(t, handle.IsPublicRoute(http.MethodPost, "/login"))
+	assert.Equal(t, global.SUCCESS, doRequest(t, r, http.MethodPost, "/api/login", ""))
+
+	// 匿名资源不需要登录, 其他资源需要登录
+	assert.Equal(t, global.SUCCESS, doRequest(t, r, http.MethodGet, "/api/anonymous", ""))
+	assert.Equal(t, global.ErrTokenNotExist.Code(), doRequest(t, r, http.MethodGet, "/api/registered", ""))
+}
+
+func TestJWTAuthRevoked(t *testing.T) {
+	testConfig(global.POLICY_AUTHENTICATED)
+	db, rdb := newTestDB(t), newTestRedis(t)
+	r := newAuthRouter(t, db, rdb)
+
+	user := model.UserAuth{Username: "user", IsSuper: true}
+	assert.Nil(t, db.Create(&user).Error)
+
+	// 登录即可访问没有资源的接口
+	token, claims := genToken(t, user.ID, "")
+	assert.Equal(t, global.SUCCESS, doRequest(t, r, http.MethodGet, "/api/unregistered", token))
+	assert.Equal(t, global.SUCCESS, doRequest(t, r, http.MethodGet, "/api/registered", token))
+
+	// 吊销 jti 后拒绝访问
+	assert.Nil(t, rdb.Set(rctx, global.REVOKED_TOKEN+claims.ID, 1, time.Minute).Err())
+	t.Cleanup(func() { rdb.Del(rctx, global.REVOKED_TOKEN+claims.ID) })
+	assert.Equal(t, global.ErrTokenRevoked.Code(), doRequest(t, r, http.MethodGet, "/api/registered", token))
+	assert.Equal(t, global.ErrTokenRevoked.Code(), doRequest(t, r, http.MethodGet, "/api/unregistered", token))
+
+	// 吊销令牌族后拒绝访问
+	token, claims = genToken(t, user.ID, "family-"+claims.ID)
+	assert.Equal(t, global.SUCCESS, doRequest(t, r, http.MethodGet, "/api/registered", token))
+	assert.Nil(t, rdb.Set(rctx, global.REVOKED_FAMILY+claims.Family, 1, time.Minute).Err())
+	t.Cleanup(func() { rdb.Del(rctx, global.REVOKED_FAMILY+claims.Family) })
+	assert.Equal(t, global.ErrTokenRevoked.Code(), doRequest(t, r, http.MethodGet, "/api/registered", token))
+}
+
+func TestPermissionCheck(t *testing.T) {
+	testConfig(global.POLICY_DENY)
+	db := newTestDB(t)
+
+	// 角色 1, 2 分别拥有 /a, /b; 角色 3 继承角色 2; 角色 4 拥有 /c 但已禁用
+	roles := []model.Role{
+		{Model: model.Model{ID: 1}, Name: "a", Label: "a"},
+		{Model: model.Model{ID: 2}, Name: "b", Label: "b"},
+		{Model: model.Model{ID: 3}, Name: "child", Label: "child", ParentId: 2},
+		{Model: model.Model{ID: 4}, Name: "disabled", Label: "disabled", IsDisable: true},
+	}
+	assert.Nil(t, db.Create(&roles).Error)
+	for i, path := range []string{"/a", "/b", "/c"} {
+		resource := model.Resource{Model: model.Model{ID: i + 1}, Name: path, Url: path, Method: http.MethodGet}
+		assert.Nil(t, db.Create(&resource).Error)
+	}
+	grants := []model.RoleResource{{RoleId: 1, ResourceId: 1}, {RoleId: 2, ResourceId: 2}, {RoleId: 4, ResourceId: 3}}
+	assert.Nil(t, db.Create(&grants).Error)
+
+	past := time.Now().Add(-time.Hour)
+	users := map[string][]model.UserAuthRole{
+		"multi":     {{RoleId: 1}, {RoleId: 2}},
+		"inherited": {{RoleId: 3}},
+		"disabled":  {{RoleId: 4}},
+		"expired":   {{RoleId: 1, ValidUntil: &past}, {RoleId: 2}},
+	}
+	userIds := make(map[string]int)
+	for name, userRoles := range users {
+		user := model.UserAuth{Username: name}
+		assert.Nil(t, db.Create(&user).Error)
+		for _, ur := range userRoles {
+			ur.UserAuthId = user.ID
+			assert.Nil(t, db.Create(&ur).Error)
+		}
+		userIds[name] = user.ID
+	}
+	handle.InvalidatePolicy(unavailableRedis(t))
+
+	// 与 JWTAuth 一样在 gin context 中设置当前用户, 之后由 PermissionCheck 校验权限
+	r := gin.New()
+	r.Use(WithGormDB(db), WithCookieStore("test", "secret"))
+	api := r.Group("/api")
+	api.Use(func(c *gin.Context) {
+		user, err := model.GetUserAuthInfoById(db, userIds[c.Query("user")])
+		assert.Nil(t, err)
+		c.Set(global.CTX_USER_AUTH, user)
+	}, PermissionCheck())
+	api.GET("/a", okHandler)
+	api.GET("/b", okHandler)
+	api.GET("/c", okHandler)
+
+	allowed := map[string][]bool{ // 用户 => 能否访问 /a, /b, /c
+		"multi":     {true, true, false},   // 多个角色取并集
+		"inherited": {false, true, false},  // 继承上级角色的权限
+		"disabled":  {false, false, false}, // 禁用的角色没有权限
+		"expired":   {false, true, false},  // 过期的角色没有权限
+	}
+	for name, want := range allowed {
+		for i, path := range []string{"/a", "/b", "/c"} {
+			code := global.ErrPermission.Code()
+			if want[i] {
+				code = global.SUCCESS
+			}
+			assert.Equal(t, code, doRequest(t, r, http.MethodGet, "/api"+path+"?user="+name, ""), name+" "+path)
+		}
+	}
+}
@@ -31,7 +31,7 @@ func CORS() gin.HandlerFunc {
 		AllowMethods: []string{"PUT", "POST", "GET", "DELETE", "OPTIONS", "PATCH"},
 
 		// AllowHeaders 配置了允许的请求头，表示在跨域请求中可以携带哪些请求头。
-		AllowHeaders: []string{"Origin", "Authorization", "Content-Type", "X-Requested-With"},
+		AllowHeaders: []string{"Origin", "Authorization", "Content-Type", "X-Requested-With", "X-Article-Token"},
 
 		// ExposeHeaders 配置了允许客户端访问的响应头，这里暴露了 "Content-Type" 头。
 		ExposeHeaders: []string{"Content-Type"},
@@ -32,6 +32,7 @@ var optMap = map[string]string{
 
 	"POST":   "新增或修改",
 	"PUT":    "修改",
+	"PATCH":  "修改",
 	"DELETE": "删除",
 }
 
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var rctx = context.Background()
+
+// newTestDB 创建临时的 sqlite 数据库并完成迁移, 配置与 internal/helper.go 中一致
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+		SkipDefaultTransaction:                   true,
+		NamingStrategy:                           schema.NamingStrategy{SingularTable: true},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, model.MakeMigrate(db))
+	return db
+}
+
+// newTestRedis 连接测试使用的 Redis (环境变量 TEST_REDIS_ADDR, 默认 localhost:6379), 无法连接时跳过测试
+func newTestRedis(t *testing.T) *redis.Client {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	if err := rdb.Ping(rctx).Err(); err != nil {
+		rdb.Close()
+		t.Skipf("无法连接 Redis %s: %v", addr, err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// unavailableRedis 无法连接的 Redis, 用于不会访问 Redis 的测试
+func unavailableRedis(t *testing.T) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// testConfig 测试使用的配置, 使用 HS256 签名
+func testConfig(unregistered string) {
+	global.Conf = &global.Config{}
+	global.Conf.JWT.Secret = "test-secret"
+	global.Conf.Auth.Unregistered = unregistered
+	gin.SetMode(gin.TestMode)
+}
+
+// doRequest 发送请求, 返回业务码, 处理函数执行成功时为 global.SUCCESS
+func doRequest(t *testing.T, r *gin.Engine, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Code int `json:"code"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	return resp.Code
+}
+
+// okHandler 通过鉴权后执行的处理函数
+func okHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": global.SUCCESS})
+}
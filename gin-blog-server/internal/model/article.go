@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"gin-blog-server/internal/utils/markdown"
 	"gorm.io/gorm"
 	"time"
 )
@@ -17,6 +19,20 @@ const (
 	TYPE_TRANSLATE            // 翻译
 )
 
+// ArticleStatusNames 文章状态对应的名称, 用于文章的导入导出
+var ArticleStatusNames = map[int]string{
+	STATUS_PUBLIC: "public",
+	STATUS_SECRET: "secret",
+	STATUS_DRAFT:  "draft",
+}
+
+// ArticleTypeNames 文章类型对应的名称, 用于文章的导入导出
+var ArticleTypeNames = map[int]string{
+	TYPE_ORIGINAL:  "original",
+	TYPE_REPRINT:   "reprint",
+	TYPE_TRANSLATE: "translate",
+}
+
 // Article
 // belongTo: 一个文章 属于 一个分类
 // belongTo: 一个文章 属于 一个用户
@@ -25,15 +41,20 @@ type Article struct {
 	Model
 
 	Title       string `gorm:"type:varchar(100);not null" json:"title"`
+	Slug        string `gorm:"type:varchar(100);uniqueIndex" json:"slug"`
 	Desc        string `json:"desc"`
 	Content     string `json:"content"`
 	Img         string `json:"img"`
 	Type        int    `gorm:"type:tinyint;comment:类型(1-原创 2-转载 3-翻译)" json:"type"`
 	Status      int    `gorm:"type:tinyint;comment:状态(1-公开 2-私密)" json:"status"`
+	Password    string `gorm:"type:varchar(100)" json:"-"` // 私密文章的访问密码 (bcrypt)
 	IsTop       bool   `json:"is_top"`
 	IsDelete    bool   `json:"is_delete"`
 	OriginalUrl string `json:"original_url"`
 
+	PublishAt   *time.Time `json:"publish_at"`   // 定时发布时间, 到达后自动公开
+	UnpublishAt *time.Time `json:"unpublish_at"` // 定时下线时间, 到达后自动转为草稿
+
 	CategoryId int `json:"category_id"`
 	UserId     int `json:"-"` // user_auth_id
 
@@ -54,6 +75,12 @@ type BlogArticleVO struct {
 	CommentCount int64 `json:"comment_count"` // 评论数量
 	LikeCount    int64 `json:"like_count"`    // 点赞数量
 	ViewCount    int64 `json:"view_count"`    // 访问数量
+	IsLocked     bool  `json:"is_locked"`     // 私密文章未解锁, 不返回正文
+
+	HTML           string             `gorm:"-" json:"html"`            // 正文渲染后的 HTML (已过滤)
+	TOC            []markdown.Heading `gorm:"-" json:"toc"`             // 目录
+	WordCount      int                `gorm:"-" json:"word_count"`      // 字数
+	ReadingMinutes int                `gorm:"-" json:"reading_minutes"` // 阅读时长 (分钟)
 
 	LastArticle       ArticlePaginationVO  `gorm:"-" json:"last_article"`       // 上一篇
 	NextArticle       ArticlePaginationVO  `gorm:"-" json:"next_article"`       // 下一篇
@@ -63,20 +90,53 @@ type BlogArticleVO struct {
 
 type ArticlePaginationVO struct {
 	ID    int    `json:"id"`
+	Slug  string `json:"slug"`
 	Img   string `json:"img"`
 	Title string `json:"title"`
 }
 
 type RecommendArticleVO struct {
 	ID        int       `json:"id"`
+	Slug      string    `json:"slug"`
 	Img       string    `json:"img"`
 	Title     string    `json:"title"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// GetArticleList 获取文章列表
-func GetArticleList(db *gorm.DB, page, size int, title string, isDelete *bool, status, typ, categoryId, tagId int) (list []Article, total int64, err error) {
-	db = db.Model(Article{})
+// visibleArticleCond 前台可见文章的查询条件: 未删除, 公开, 已到发布时间, 未到下线时间
+// alias 为文章表的别名前缀, 例如 "a."
+func visibleArticleCond(alias string, now time.Time) (string, []any) {
+	return articleCond(alias, now, STATUS_PUBLIC)
+}
+
+func articleCond(alias string, now time.Time, statuses ...int) (string, []any) {
+	query := fmt.Sprintf("%[1]sis_delete = 0 AND %[1]sstatus IN ?"+
+		" AND (%[1]spublish_at IS NULL OR %[1]spublish_at <= ?)"+
+		" AND (%[1]sunpublish_at IS NULL OR %[1]sunpublish_at > ?)", alias)
+	return query, []any{statuses, now, now}
+}
+
+// VisibleArticle 筛选前台可见的文章
+// 定时任务存在时间间隔, 所以查询时也需要判断 发布/下线 时间
+func VisibleArticle(alias string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		query, args := visibleArticleCond(alias, time.Now())
+		return db.Where(query, args...)
+	}
+}
+
+// ListedArticle 筛选前台文章列表中展示的文章: 公开文章 + 私密文章
+// 私密文章需要密码访问, 由调用方去除正文
+func ListedArticle(alias string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		query, args := articleCond(alias, time.Now(), STATUS_PUBLIC, STATUS_SECRET)
+		return db.Where(query, args...)
+	}
+}
+
+// GetArticleList 获取文章列表, scope 为数据权限范围
+func GetArticleList(db *gorm.DB, scope DataScope, page, size int, title string, isDelete *bool, status, typ, categoryId, tagId int) (list []Article, total int64, err error) {
+	db = db.Model(Article{}).Scopes(scope.Filter("article."))
 
 	if title != "" {
 		db = db.Where("title LIKE ?", "%"+title+"%")
@@ -109,10 +169,10 @@ func GetArticleList(db *gorm.DB, page, size int, title string, isDelete *bool, s
 	return list, total, result.Error
 }
 
-// GetBlogArticleList 前台文章列表（不在回收站并且状态为公开）
+// GetBlogArticleList 前台文章列表（不在回收站并且状态为公开或私密）
 func GetBlogArticleList(db *gorm.DB, page, size, categoryId, tagId int) (data []Article, total int64, err error) {
 	db = db.Model(Article{})
-	db = db.Where("is_delete = 0 AND status = 1")
+	db = db.Scopes(ListedArticle(""))
 
 	if categoryId != 0 {
 		db = db.Where("category_id", categoryId)
@@ -132,31 +192,67 @@ func GetBlogArticleList(db *gorm.DB, page, size, categoryId, tagId int) (data []
 }
 
 // SaveOrUpdateArticle 新增/编辑文章, 同时根据 分类名称, 标签名称 维护关联表
-func SaveOrUpdateArticle(db *gorm.DB, article *Article, categoryName string, tagNames []string) error {
+// article.Slug 为空时根据标题生成 slug, slug 变化时记录旧 slug 的重定向
+// 每次保存都会记录一个文章版本, editorId 为编辑者的 user_auth_id
+// 只能编辑数据权限范围内的文章, 编辑时不会修改文章的作者
+func SaveOrUpdateArticle(db *gorm.DB, scope DataScope, article *Article, categoryName string, tagNames []string, editorId int) error {
+	if article.ID == 0 && !scope.CanCreate() {
+		return ErrOutOfScope
+	}
+	if article.ID != 0 {
+		if err := checkScope(db, &Article{}, scope, []int{article.ID}); err != nil {
+			return err
+		}
+	}
+
 	// 由于要操作多个数据库表，所以要开启事务
 	return db.Transaction(func(tx *gorm.DB) error {
 		// 分类不存在则创建
-		category := Category{Name: categoryName}
-		result := db.Model(&Category{}).Where("name", categoryName).FirstOrCreate(&category)
-		if result.Error != nil {
-			return result.Error
+		category, err := firstOrCreateCategory(tx, categoryName)
+		if err != nil {
+			return err
 		}
 
 		// 设置文章的分类
 		article.CategoryId = category.ID
 
+		// 生成 slug
+		oldSlug, err := currentSlug(tx, SLUG_ARTICLE, article.ID)
+		if err != nil {
+			return err
+		}
+		source := article.Slug
+		if source == "" {
+			source = article.Title
+		}
+		article.Slug, err = uniqueSlug(tx, SLUG_ARTICLE, article.ID, source)
+		if err != nil {
+			return err
+		}
+
 		// 先 添加/更新 文章, 获取到其 ID
+		var result *gorm.DB
 		if article.ID == 0 {
-			result = db.Create(article)
+			result = tx.Create(article)
 		} else {
-			result = db.Model(article).Where("id", article.ID).Updates(article)
+			result = tx.Model(article).Where("id", article.ID).Omit("user_id").Updates(article)
+			if result.Error != nil {
+				return result.Error
+			}
+			// Updates 会忽略零值, 定时时间需要单独更新以支持取消
+			result = tx.Model(article).Where("id", article.ID).
+				Select("publish_at", "unpublish_at").Updates(article)
 		}
 		if result.Error != nil {
 			return result.Error
 		}
 
+		if err := saveSlugRedirect(tx, SLUG_ARTICLE, article.ID, oldSlug, article.Slug); err != nil {
+			return err
+		}
+
 		// 清空文章标签关联
-		result = db.Delete(&ArticleTag{}, "article_id", article.ID)
+		result = tx.Delete(&ArticleTag{}, "article_id", article.ID)
 		if result.Error != nil {
 			return result.Error
 		}
@@ -165,10 +261,9 @@ func SaveOrUpdateArticle(db *gorm.DB, article *Article, categoryName string, tag
 		var articleTags []ArticleTag
 		for _, tagName := range tagNames {
 			// 标签不存在则创建
-			tag := Tag{Name: tagName}
-			result := db.Model(&Tag{}).Where("name", tagName).FirstOrCreate(&tag)
-			if result.Error != nil {
-				return result.Error
+			tag, err := firstOrCreateTag(tx, tagName)
+			if err != nil {
+				return err
 			}
 			articleTags = append(articleTags, ArticleTag{
 				ArticleId: article.ID,
@@ -176,30 +271,83 @@ func SaveOrUpdateArticle(db *gorm.DB, article *Article, categoryName string, tag
 			})
 		}
 
-		result = db.Create(&articleTags)
-		return result.Error
+		if len(articleTags) > 0 {
+			result = tx.Create(&articleTags)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		// 记录文章版本
+		return CreateArticleRevision(tx, article, categoryName, tagNames, editorId)
 	})
 }
 
+// GetArticleSearchDocs 获取建立全文索引需要的文章数据 (包括回收站中的文章, 由调用方判断)
+// ids 为空时获取所有不在回收站的文章
+func GetArticleSearchDocs(db *gorm.DB, ids []int) (list []Article, err error) {
+	db = db.Model(&Article{}).Select("id", "title", "content", "is_delete")
+	if len(ids) > 0 {
+		db = db.Where("id IN ?", ids)
+	} else {
+		db = db.Where("is_delete = 0")
+	}
+	result := db.Find(&list)
+	return list, result.Error
+}
+
+// GetVisibleArticleIds 从 ids 中筛选出前台可见的文章 id
+func GetVisibleArticleIds(db *gorm.DB, ids []int) (list []int, err error) {
+	if len(ids) == 0 {
+		return list, nil
+	}
+	result := db.Model(&Article{}).Where("id IN ?", ids).Scopes(VisibleArticle("")).Pluck("id", &list)
+	return list, result.Error
+}
+
+// HasArticlePassword 判断文章是否已经设置了访问密码, 新增文章 (id 为 0) 返回 false
+func HasArticlePassword(db *gorm.DB, id int) (bool, error) {
+	if id == 0 {
+		return false, nil
+	}
+	var count int64
+	result := db.Model(&Article{}).Where("id = ? AND password != ''", id).Count(&count)
+	return count > 0, result.Error
+}
+
 // UpdateArticleTop 修改置顶信息
-func UpdateArticleTop(db *gorm.DB, id int, isTop bool) error {
+func UpdateArticleTop(db *gorm.DB, scope DataScope, id int, isTop bool) error {
+	if err := checkScope(db, &Article{}, scope, []int{id}); err != nil {
+		return err
+	}
 	result := db.Model(&Article{Model: Model{ID: id}}).Update("is_top", isTop)
 	return result.Error
 }
 
-// GetArticle 文章的详细信息
-func GetArticle(db *gorm.DB, id int) (data *Article, err error) {
+// GetArticle 文章的详细信息, 不在数据权限范围内时返回 gorm.ErrRecordNotFound
+func GetArticle(db *gorm.DB, scope DataScope, id int) (data *Article, err error) {
 	result := db.Preload("Category").Preload("Tags").
 		Where(Article{Model: Model{ID: id}}).
+		Scopes(scope.Filter("")).
 		First(&data)
 	return data, result.Error
 }
 
-// GetBlogArticle 获取文章的详细内容，但是该文章需要不在回收站并且状态为公开
+// GetArticleListByIds 根据 id 列表获取文章的详细信息 (包含分类和标签), 忽略数据权限范围外的文章
+func GetArticleListByIds(db *gorm.DB, scope DataScope, ids []int) (list []Article, err error) {
+	result := db.Preload("Category").Preload("Tags").
+		Where("id IN ?", ids).
+		Scopes(scope.Filter("")).
+		Order("id ASC").
+		Find(&list)
+	return list, result.Error
+}
+
+// GetBlogArticle 获取文章的详细内容，但是该文章需要不在回收站并且状态为公开或私密
 func GetBlogArticle(db *gorm.DB, id int) (data *Article, err error) {
 	result := db.Preload("Category").Preload("Tags").
 		Where(Article{Model: Model{ID: id}}).
-		Where("is_delete = 0 AND status = 1").
+		Scopes(ListedArticle("")).
 		First(&data)
 	return data, result.Error
 }
@@ -221,9 +369,9 @@ func GetRecommendList(db *gorm.DB, id, n int) (list []RecommendArticleVO, err er
 
 	// 根据 文章id列表 查出文章信息 (前 n 个)
 	result := db.Table("(?) t2", sub2).
-		Select("id, title, img, created_at").
+		Select("id, slug, title, img, created_at").
 		Joins("JOIN article a ON t2.article_id = a.id").
-		Where("a.is_delete = 0 AND a.status = 1").
+		Scopes(VisibleArticle("a.")).
 		Order("is_top, id DESC").
 		Limit(n).
 		Find(&list)
@@ -233,8 +381,8 @@ func GetRecommendList(db *gorm.DB, id, n int) (list []RecommendArticleVO, err er
 // GetNewestList 查询最新的 n 篇文章
 func GetNewestList(db *gorm.DB, n int) (data []RecommendArticleVO, err error) {
 	result := db.Model(&Article{}).
-		Select("id, title, img, created_at").
-		Where("is_delete = 0 AND status = 1").
+		Select("id, slug, title, img, created_at").
+		Scopes(VisibleArticle("")).
 		Order("created_at DESC, id ASC").
 		Limit(n).
 		Find(&data)
@@ -243,10 +391,12 @@ func GetNewestList(db *gorm.DB, n int) (data []RecommendArticleVO, err error) {
 
 // GetLastArticle 查询上一篇文章 (id < 当前文章 id)
 func GetLastArticle(db *gorm.DB, id int) (val ArticlePaginationVO, err error) {
-	sub := db.Table("article").Select("max(id)").Where("id < ?", id)
-	result := db.Table("article").
-		Select("id, title, img").
-		Where("is_delete = 0 AND status = 1 AND id = (?)", sub).
+	result := db.Model(&Article{}).
+		Select("id, slug, title, img").
+		Where("id < ?", id).
+		Scopes(VisibleArticle("")).
+		Order("id DESC").
+		Limit(1).
 		Find(&val)
 	return val, result.Error
 }
@@ -254,15 +404,20 @@ func GetLastArticle(db *gorm.DB, id int) (val ArticlePaginationVO, err error) {
 // GetNextArticle 查询下一篇文章 (id > 当前文章 id)
 func GetNextArticle(db *gorm.DB, id int) (data ArticlePaginationVO, err error) {
 	result := db.Model(&Article{}).
-		Select("id, title, img").
-		Where("is_delete = 0 AND status = 1 AND id > ?", id).
+		Select("id, slug, title, img").
+		Where("id > ?", id).
+		Scopes(VisibleArticle("")).
+		Order("id ASC").
 		Limit(1).
 		Find(&data)
 	return data, result.Error
 }
 
 // UpdateArticleSoftDelete 软删除文章（修改）
-func UpdateArticleSoftDelete(db *gorm.DB, ids []int, isDelete bool) (int64, error) {
+func UpdateArticleSoftDelete(db *gorm.DB, scope DataScope, ids []int, isDelete bool) (int64, error) {
+	if err := checkScope(db, &Article{}, scope, ids); err != nil {
+		return 0, err
+	}
 	result := db.Model(Article{}).
 		Where("id IN ?", ids).
 		Update("is_delete", isDelete)
@@ -272,14 +427,23 @@ func UpdateArticleSoftDelete(db *gorm.DB, ids []int, isDelete bool) (int64, erro
 	return result.RowsAffected, nil
 }
 
-// DeleteArticle 物理删除文章
-func DeleteArticle(db *gorm.DB, ids []int) (int64, error) {
+// DeleteArticle 物理删除文章, 文章版本作为审计记录保留
+func DeleteArticle(db *gorm.DB, scope DataScope, ids []int) (int64, error) {
+	if err := checkScope(db, &Article{}, scope, ids); err != nil {
+		return 0, err
+	}
+
 	// 删除 [文章-标签] 关联
 	result := db.Where("article_id IN ?", ids).Delete(&ArticleTag{})
 	if result.Error != nil {
 		return 0, result.Error
 	}
 
+	// 删除 [slug 重定向]
+	if err := DeleteSlugRedirects(db, SLUG_ARTICLE, ids); err != nil {
+		return 0, err
+	}
+
 	// 删除 [文章]
 	result = db.Where("id IN ?", ids).Delete(&Article{})
 	if result.Error != nil {
@@ -289,44 +453,40 @@ func DeleteArticle(db *gorm.DB, ids []int) (int64, error) {
 	return result.RowsAffected, nil
 }
 
-// ImportArticle 导入文章：题目 + 内容
+// ImportArticle 导入文章: 文章信息 + 分类名称 + 标签名称, 分类和标签不存在时自动创建
 // TODO：如果原来的文件中有图片的话，直接上传图片会由于链接错误无法显示？如何解决图片的自动化上传云+正常显示
-func ImportArticle(db *gorm.DB, userAuthId int, title string, content string, img string, categoryName string, tagName string) error {
-	article := Article{
-		Title:   title,
-		Content: content,
-		Img:     img,
-		Status:  STATUS_DRAFT,
-		Type:    TYPE_ORIGINAL,
-		UserId:  userAuthId,
-	}
-
-	// 生成对应的分类
-	category := Category{Name: categoryName}
-	result := db.Model(&Category{}).Where("name", categoryName).FirstOrCreate(&category)
-	if result.Error != nil {
-		return result.Error
-	}
-	article.CategoryId = category.ID
+func ImportArticle(db *gorm.DB, scope DataScope, article *Article, categoryName string, tagNames []string, editorId int) error {
+	article.ID = 0
+	return SaveOrUpdateArticle(db, scope, article, categoryName, tagNames, editorId)
+}
 
-	// 插入文章
-	result = db.Create(&article)
-	if result.Error != nil {
-		return result.Error
-	}
+// PublishScheduledArticles 处理已到发布时间的文章, 返回受影响的文章数量 (不处理回收站中的文章)
+// 草稿转为公开并清除发布时间; 私密文章只清除发布时间, 仍然需要密码访问
+func PublishScheduledArticles(db *gorm.DB, now time.Time) (int64, error) {
+	var rows int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Article{}).
+			Where("publish_at <= ? AND status = ? AND is_delete = 0", now, STATUS_DRAFT).
+			Updates(map[string]any{"status": STATUS_PUBLIC, "publish_at": nil})
+		if result.Error != nil {
+			return result.Error
+		}
+		rows = result.RowsAffected
 
-	// 生成对应的文章-标签记录
-	var articleTag ArticleTag
-	tag := Tag{Name: tagName}
-	result = db.Model(&Tag{}).Where("name", tagName).FirstOrCreate(&tag)
-	if result.Error != nil {
+		result = tx.Model(&Article{}).
+			Where("publish_at <= ? AND status = ? AND is_delete = 0", now, STATUS_SECRET).
+			Update("publish_at", nil)
+		rows += result.RowsAffected
 		return result.Error
-	}
-
-	// 插入 文章-标签
-	articleTag.ArticleId = article.ID
-	articleTag.TagId = tag.ID
-	result = db.Create(&articleTag)
+	})
+	return rows, err
+}
 
-	return result.Error
+// UnpublishExpiredArticles 将已到下线时间的公开和私密文章转为草稿, 并清除下线时间, 返回受影响的文章数量
+// 不处理草稿和回收站中的文章
+func UnpublishExpiredArticles(db *gorm.DB, now time.Time) (int64, error) {
+	result := db.Model(&Article{}).
+		Where("unpublish_at <= ? AND status IN ? AND is_delete = 0", now, []int{STATUS_PUBLIC, STATUS_SECRET}).
+		Updates(map[string]any{"status": STATUS_DRAFT, "unpublish_at": nil})
+	return result.RowsAffected, result.Error
 }
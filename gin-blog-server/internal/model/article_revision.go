@@ -0,0 +1,74 @@
+package model
+
+import "gorm.io/gorm"
+
+// ArticleRevision 文章版本, 每次保存文章时记录一份快照, 用于审计和回滚
+// belongTo: 一个版本 属于 一个文章
+// belongTo: 一个版本 属于 一个编辑者
+type ArticleRevision struct {
+	Model
+
+	ArticleId    int      `gorm:"index" json:"article_id"`
+	UserId       int      `json:"user_id"` // 编辑者 user_auth_id
+	Title        string   `gorm:"type:varchar(100);not null" json:"title"`
+	Desc         string   `json:"desc"`
+	Content      string   `json:"content"`
+	CategoryName string   `gorm:"type:varchar(20)" json:"category_name"`
+	TagNames     []string `gorm:"serializer:json" json:"tag_names"`
+
+	User *UserAuth `gorm:"foreignkey:UserId" json:"user"`
+}
+
+// CreateArticleRevision 根据文章当前的内容记录一个版本
+func CreateArticleRevision(db *gorm.DB, article *Article, categoryName string, tagNames []string, editorId int) error {
+	if tagNames == nil {
+		tagNames = []string{}
+	}
+	revision := ArticleRevision{
+		ArticleId:    article.ID,
+		UserId:       editorId,
+		Title:        article.Title,
+		Desc:         article.Desc,
+		Content:      article.Content,
+		CategoryName: categoryName,
+		TagNames:     tagNames,
+	}
+	return db.Create(&revision).Error
+}
+
+// GetArticleRevisionList 获取文章的版本列表 (不包含正文), 按时间倒序
+func GetArticleRevisionList(db *gorm.DB, scope DataScope, articleId, page, size int) (list []ArticleRevision, total int64, err error) {
+	if err := checkScope(db, &Article{}, scope, []int{articleId}); err != nil {
+		return nil, 0, err
+	}
+	db = db.Model(&ArticleRevision{}).Where("article_id", articleId)
+	result := db.Count(&total).
+		Omit("content").
+		Preload("User").Preload("User.UserInfo").
+		Order("id DESC").
+		Scopes(Paginate(page, size)).
+		Find(&list)
+	return list, total, result.Error
+}
+
+// GetArticleRevision 获取文章版本详情, 文章不在数据权限范围内时返回 ErrOutOfScope
+func GetArticleRevision(db *gorm.DB, scope DataScope, id int) (*ArticleRevision, error) {
+	var revision ArticleRevision
+	result := db.Preload("User").Preload("User.UserInfo").First(&revision, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &revision, checkScope(db, &Article{}, scope, []int{revision.ArticleId})
+}
+
+// RestoreArticleRevision 将文章恢复到指定版本 (标题, 摘要, 正文, 分类, 标签), 并记录为一个新版本
+// SaveOrUpdateArticle 中的 Updates 会忽略零值, 版本中的摘要和正文可能为空, 需要显式更新
+func RestoreArticleRevision(db *gorm.DB, scope DataScope, article *Article, revision *ArticleRevision, editorId int) error {
+	article.Title, article.Desc, article.Content = revision.Title, revision.Desc, revision.Content
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := SaveOrUpdateArticle(tx, scope, article, revision.CategoryName, revision.TagNames, editorId); err != nil {
+			return err
+		}
+		return tx.Model(article).Where("id", article.ID).Select("title", "desc", "content").Updates(article).Error
+	})
+}
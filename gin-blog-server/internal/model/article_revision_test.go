@@ -0,0 +1,47 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRestoreArticleRevision(t *testing.T) {
+	db := newTestDB(t)
+
+	article := &Article{Title: "v1", Content: "", Status: STATUS_PUBLIC, Type: 1}
+	assert.Nil(t, SaveOrUpdateArticle(db, ScopeAll, article, "go", []string{"a"}, 1))
+	var first ArticleRevision
+	assert.Nil(t, db.Where("article_id", article.ID).First(&first).Error)
+
+	article = &Article{Model: Model{ID: article.ID}, Title: "v2", Desc: "desc", Content: "content", Status: STATUS_PUBLIC, Type: 1}
+	assert.Nil(t, SaveOrUpdateArticle(db, ScopeAll, article, "go", []string{"b"}, 1))
+
+	// 恢复到摘要和正文为空的版本
+	article, err := GetArticle(db, ScopeAll, article.ID)
+	assert.Nil(t, err)
+	article.Category, article.Tags, article.User = nil, nil, nil
+	assert.Nil(t, RestoreArticleRevision(db, ScopeAll, article, &first, 2))
+
+	article, err = GetArticle(db, ScopeAll, article.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", article.Title)
+	assert.Empty(t, article.Desc)
+	assert.Empty(t, article.Content)
+	assert.Len(t, article.Tags, 1)
+	assert.Equal(t, "a", article.Tags[0].Name)
+
+	// 新版本的记录与文章一致
+	var last ArticleRevision
+	assert.Nil(t, db.Where("article_id", article.ID).Last(&last).Error)
+	assert.Equal(t, "v1", last.Title)
+	assert.Empty(t, last.Desc)
+	assert.Empty(t, last.Content)
+	assert.Equal(t, 2, last.UserId)
+
+	// 物理删除文章后版本仍然保留
+	_, err = DeleteArticle(db, ScopeAll, []int{article.ID})
+	assert.Nil(t, err)
+	var count int64
+	db.Model(&ArticleRevision{}).Where("article_id", article.ID).Count(&count)
+	assert.EqualValues(t, 3, count)
+}
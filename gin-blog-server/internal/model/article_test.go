@@ -0,0 +1,62 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestArticleSchedule(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+	n := 0
+	create := func(status int, isDelete bool, publishAt, unpublishAt *time.Time) int {
+		n++
+		article := Article{Title: "t", Slug: "t-" + strconv.Itoa(n), Status: status, Type: 1, IsDelete: isDelete, PublishAt: publishAt, UnpublishAt: unpublishAt}
+		assert.Nil(t, db.Create(&article).Error)
+		return article.ID
+	}
+	get := func(id int) Article {
+		var article Article
+		assert.Nil(t, db.First(&article, id).Error)
+		return article
+	}
+
+	draft := create(STATUS_DRAFT, false, &past, nil)
+	secret := create(STATUS_SECRET, false, &past, nil)
+	trashed := create(STATUS_DRAFT, true, &past, nil)
+	later := create(STATUS_DRAFT, false, &future, nil)
+
+	rows, err := PublishScheduledArticles(db, now)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, rows)
+
+	assert.Equal(t, STATUS_PUBLIC, get(draft).Status)
+	assert.Nil(t, get(draft).PublishAt)
+	// 私密文章只清除发布时间, 不会公开
+	assert.Equal(t, STATUS_SECRET, get(secret).Status)
+	assert.Nil(t, get(secret).PublishAt)
+	// 回收站中的文章和未到时间的文章不变
+	assert.Equal(t, STATUS_DRAFT, get(trashed).Status)
+	assert.NotNil(t, get(trashed).PublishAt)
+	assert.Equal(t, STATUS_DRAFT, get(later).Status)
+
+	public := create(STATUS_PUBLIC, false, nil, &past)
+	secret = create(STATUS_SECRET, false, nil, &past)
+	trashed = create(STATUS_PUBLIC, true, nil, &past)
+	later = create(STATUS_PUBLIC, false, nil, &future)
+
+	rows, err = UnpublishExpiredArticles(db, now)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, rows)
+
+	assert.Equal(t, STATUS_DRAFT, get(public).Status)
+	assert.Nil(t, get(public).UnpublishAt)
+	assert.Equal(t, STATUS_DRAFT, get(secret).Status)
+	assert.Equal(t, STATUS_PUBLIC, get(trashed).Status)
+	assert.NotNil(t, get(trashed).UnpublishAt)
+	assert.Equal(t, STATUS_PUBLIC, get(later).Status)
+}
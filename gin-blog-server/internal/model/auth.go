@@ -2,7 +2,9 @@ package model
 
 import (
 	"encoding/json"
-	"gin-blog-server/internal/utils"
+	"errors"
+	"fmt"
+	"gin-blog-server/internal/utils/policy"
 	"gorm.io/gorm"
 	"log/slog"
 	"strconv"
@@ -12,19 +14,32 @@ import (
 // UserAuth 代表用户认证信息
 type UserAuth struct {
 	Model
-	Username      string     `gorm:"unique;type:varchar(50)" json:"username"`           // 用户名，唯一，最大长度为50
-	Password      string     `gorm:"type:varchar(100)" json:"-"`                        // 密码，最大长度为100，不会被JSON序列化
-	LoginType     int        `gorm:"type:tinyint(1);comment:登录类型" json:"login_type"`    // 登录类型，Tinyint 类型，表示不同的登录方式（例如：用户名/密码、第三方登录等）
-	IpAddress     string     `gorm:"type:varchar(20);comment:登录IP地址" json:"ip_address"` // 登录IP地址，最大长度为20
-	IpSource      string     `gorm:"type:varchar(50);comment:IP来源" json:"ip_source"`    // IP来源，最大长度为50
-	LastLoginTime *time.Time `json:"last_login_time"`                                   // 上次登录时间，类型为指针，以便为null
-	IsDisable     bool       `json:"is_disable"`                                        // 是否禁用，布尔值，表示该用户是否被禁用
-	IsSuper       bool       `json:"is_super"`                                          // 是否超级管理员，布尔值，超级管理员只能由后台设置
-	UserInfoId    int        `json:"user_info_id"`                                      // 关联的用户信息表ID
-	UserInfo      *UserInfo  `json:"info"`                                              // 关联的用户信息
-	Roles         []*Role    `json:"roles" gorm:"many2many:user_auth_role"`             // 用户角色，表示与角色的多对多关系
+	Username      string         `gorm:"unique;type:varchar(50)" json:"username"`           // 用户名，唯一，最大长度为50
+	Password      string         `gorm:"type:varchar(100)" json:"-"`                        // 密码，最大长度为100，不会被JSON序列化
+	LoginType     int            `gorm:"type:tinyint(1);comment:登录类型" json:"login_type"`    // 登录类型，Tinyint 类型，表示不同的登录方式（例如：用户名/密码、第三方登录等）
+	IpAddress     string         `gorm:"type:varchar(20);comment:登录IP地址" json:"ip_address"` // 登录IP地址，最大长度为20
+	IpSource      string         `gorm:"type:varchar(50);comment:IP来源" json:"ip_source"`    // IP来源，最大长度为50
+	LastLoginTime *time.Time     `json:"last_login_time"`                                   // 上次登录时间，类型为指针，以便为null
+	IsDisable     bool           `json:"is_disable"`                                        // 是否禁用，布尔值，表示该用户是否被禁用
+	IsSuper       bool           `json:"is_super"`                                          // 是否超级管理员，布尔值，超级管理员只能由后台设置
+	TotpSecret    string         `gorm:"type:varchar(64)" json:"-"`                         // TOTP 密钥 (base32)，开启两步验证后设置
+	TotpEnabled   bool           `json:"totp_enabled"`                                      // 是否开启两步验证
+	LockedUntil   *time.Time     `json:"locked_until,omitempty" gorm:"-"`                   // 登录失败次数过多被临时锁定的截止时间，保存在 Redis 中
+	UserInfoId    int            `json:"user_info_id"`                                      // 关联的用户信息表ID
+	UserInfo      *UserInfo      `json:"info"`                                              // 关联的用户信息
+	Roles         []*Role        `json:"roles" gorm:"many2many:user_auth_role"`             // 用户角色，表示与角色的多对多关系
+	Grants        []UserAuthRole `json:"grants,omitempty" gorm:"foreignKey:UserAuthId"`     // 用户-角色 关联, 包含授权的有效期
 }
 
+// 登录类型 UserAuth.LoginType
+const (
+	LOGIN_EMAIL  = iota + 1 // 邮箱
+	LOGIN_QQ                // QQ
+	LOGIN_WEIBO             // 微博
+	LOGIN_GITHUB            // GitHub
+	LOGIN_OIDC              // OpenID Connect
+)
+
 func (u *UserAuth) MarshalBinary() (data []byte, err error) {
 	return json.Marshal(u)
 }
@@ -32,9 +47,11 @@ func (u *UserAuth) MarshalBinary() (data []byte, err error) {
 // Role 代表系统中的角色
 type Role struct {
 	Model
-	Name      string `gorm:"unique" json:"name"`  // 角色名称，唯一
-	Label     string `gorm:"unique" json:"label"` // 角色标签，唯一
-	IsDisable bool   `json:"is_disable"`          // 是否禁用该角色，布尔值
+	Name       string `gorm:"unique" json:"name"`                    // 角色名称，唯一
+	Label      string `gorm:"unique" json:"label"`                   // 角色标签，唯一
+	IsDisable  bool   `json:"is_disable"`                            // 是否禁用该角色，布尔值
+	ParentId   int    `json:"parent_id"`                             // 上级角色ID, 角色继承上级角色 (以及更上级) 的资源和菜单
+	Require2FA bool   `json:"require_2fa" gorm:"column:require_2fa"` // 拥有该角色的用户必须开启两步验证才能登录
 
 	Resources []Resource `json:"resources" gorm:"many2many:role_resource"` // 角色拥有的资源，表示与资源的多对多关系
 	Menus     []Menu     `json:"menus" gorm:"many2many:role_menu"`         // 角色拥有的菜单，表示与菜单的多对多关系
@@ -49,6 +66,7 @@ type Resource struct {
 	Url       string `gorm:"type:varchar(255)" json:"url"`           // 资源的URL地址
 	Method    string `gorm:"type:varchar(10)" json:"request_method"` // 请求方法，例如 GET, POST, PUT, DELETE 等
 	Anonymous bool   `json:"is_anonymous"`                           // 是否是匿名访问的资源，布尔值，表示该资源是否不需要登录
+	Stale     bool   `json:"is_stale"`                               // 是否已失效, 即路由中已不存在该接口, 由启动时的资源同步标记
 
 	Roles []*Role `json:"roles" gorm:"many2many:role_resource"` // 资源关联的角色，表示与角色的多对多关系
 }
@@ -87,9 +105,22 @@ type Menu struct {
 	Roles []*Role `json:"roles" gorm:"many2many:role_menu"` // 菜单关联的角色，表示与角色的多对多关系
 }
 
+// ErrRoleCycle 角色的继承关系形成了循环
+var ErrRoleCycle = errors.New("角色继承关系不能形成循环")
+
+// UserAuthRole 用户-角色 关联, 可以设置授权的有效期, 不在有效期内的授权在鉴权和加载菜单时被忽略
 type UserAuthRole struct {
-	UserAuthId int `gorm:"primaryKey;uniqueIndex:idx_user_auth_role"`
-	RoleId     int `gorm:"primaryKey;uniqueIndex:idx_user_auth_role"`
+	UserAuthId int        `json:"-" gorm:"primaryKey;uniqueIndex:idx_user_auth_role"`
+	RoleId     int        `json:"role_id" gorm:"primaryKey;uniqueIndex:idx_user_auth_role"`
+	ValidFrom  *time.Time `json:"valid_from"`               // 生效时间, 为空表示立即生效
+	ValidUntil *time.Time `json:"valid_until" gorm:"index"` // 失效时间, 为空表示永久有效
+}
+
+// ActiveGrant 筛选有效期内的 用户-角色 关联
+// 定时任务存在时间间隔, 所以查询时也需要判断有效期
+func ActiveGrant(db *gorm.DB) *gorm.DB {
+	now := time.Now()
+	return db.Where("(valid_from IS NULL OR valid_from <= ?) AND (valid_until IS NULL OR valid_until > ?)", now, now)
 }
 
 type RoleResource struct {
@@ -103,13 +134,17 @@ type RoleMenu struct {
 }
 
 type RoleVO struct {
-	ID          int       `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	Name        string    `json:"name"`
-	Label       string    `json:"label"`
-	IsDisable   bool      `json:"is_disable"`
-	ResourceIds []int     `json:"resource_ids" gorm:"-"`
-	MenuIds     []int     `json:"menu_ids" gorm:"-"`
+	ID          int               `json:"id"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Name        string            `json:"name"`
+	Label       string            `json:"label"`
+	IsDisable   bool              `json:"is_disable"`
+	ParentId    int               `json:"parent_id"`
+	Require2FA  bool              `json:"require_2fa" gorm:"column:require_2fa"`
+	ResourceIds []int             `json:"resource_ids" gorm:"-"`
+	MenuIds     []int             `json:"menu_ids" gorm:"-"`
+	Scopes      map[string]string `json:"scopes" gorm:"-"`             // 数据类型 => 数据权限范围
+	Children    []RoleVO          `json:"children,omitempty" gorm:"-"` // 继承该角色的下级角色
 }
 
 // GetRoleIdsByUserId 根据用户的 UserAuthId 查询该用户拥有的角色 ID 列表
@@ -123,9 +158,11 @@ type RoleVO struct {
 //   - err：查询过程中发生的错误。如果没有错误，则返回 nil
 func GetRoleIdsByUserId(db *gorm.DB, userAuthId int) (ids []int, err error) {
 	// 使用 GORM 查询方式，获取用户角色表（UserAuthRole）中与 userAuthId 对应的所有 role_id
-	// Model(&UserAuthRole{UserAuthId: userAuthId})：指定查询的目标表为 UserAuthRole 表，并且条件是 UserAuthId 等于传入的 userAuthId
+	// Model(&UserAuthRole{})：指定查询的目标表为 UserAuthRole 表
+	// Where("user_auth_id = ?", userAuthId)：条件是 UserAuthId 等于传入的 userAuthId (复合主键不完整时 gorm 不会把 Model 中的字段作为条件)
 	// Pluck("role_id", &ids)：查询所有的 role_id，并将结果存入 ids 切片
-	result := db.Model(&UserAuthRole{UserAuthId: userAuthId}).Pluck("role_id", &ids)
+	// Scopes(ActiveGrant)：忽略不在有效期内的角色
+	result := db.Model(&UserAuthRole{}).Where("user_auth_id = ?", userAuthId).Scopes(ActiveGrant).Pluck("role_id", &ids)
 
 	// 返回查询结果：ids 包含所有角色 ID，result.Error 包含可能发生的错误
 	return ids, result.Error
@@ -138,8 +175,8 @@ func GetUserAuthInfoById(db *gorm.DB, id int) (*UserAuth, error) {
 	}
 	// 查询 userAuth 表中的第一条记录，并且通过 Preload 预加载关联的 Roles 和 UserInfo 数据
 	result := db.Model(&userAuth).
-		// Preload("Roles") 会将 userAuth 关联的 Roles 数据一并查询出来
-		Preload("Roles").
+		// Preload("Roles") 会将 userAuth 关联的 Roles 数据一并查询出来, 只包含有效期内的角色
+		Preload("Roles", "id IN (?)", db.Model(&UserAuthRole{}).Select("role_id").Where("user_auth_id = ?", id).Scopes(ActiveGrant)).
 		// Preload("UserInfo") 会将 userAuth 关联的 UserInfo 数据一并查询出来
 		Preload("UserInfo").
 		// First(&userAuth) 查询 userAuth 表中第一条符合条件的记录，并将结果存入 userAuth 变量
@@ -148,8 +185,17 @@ func GetUserAuthInfoById(db *gorm.DB, id int) (*UserAuth, error) {
 	return &userAuth, result.Error
 }
 
-// CreateNewUser 传入用户名和密码注册新用户
-func CreateNewUser(db *gorm.DB, username, password string) (*UserAuth, *UserInfo, *UserAuthRole, error) {
+// defaultAvatar 新用户的默认头像
+const defaultAvatar = "https://www.bing.com/rp/ar_9isCNU2Q-VG1yEDDHnx8HAFQ.png"
+
+// CreateNewUser 传入用户名和密码哈希 (bcrypt) 注册新用户, roleLabel 为新用户的默认角色
+func CreateNewUser(db *gorm.DB, username, passwordHash, roleLabel string) (*UserAuth, *UserInfo, *UserAuthRole, error) {
+	// 默认角色不存在时不创建用户, 避免产生没有角色的用户
+	var role Role
+	if err := db.Select("id").Where("label = ?", roleLabel).First(&role).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("默认角色 %s 不存在: %w", roleLabel, err)
+	}
+
 	// 创建 userinfo
 	num, err := Count(db, &UserInfo{})
 	if err != nil {
@@ -160,7 +206,7 @@ func CreateNewUser(db *gorm.DB, username, password string) (*UserAuth, *UserInfo
 	userinfo := &UserInfo{
 		Email:    username,
 		Nickname: "游客" + number,
-		Avatar:   "https://www.bing.com/rp/ar_9isCNU2Q-VG1yEDDHnx8HAFQ.png",
+		Avatar:   defaultAvatar,
 		Intro:    "我是这个程序的第" + number + "个用户",
 	}
 	// 在 user 表中创建对应的记录
@@ -170,10 +216,10 @@ func CreateNewUser(db *gorm.DB, username, password string) (*UserAuth, *UserInfo
 	}
 
 	// 创建 userAuth
-	pass, _ := utils.BcryptHash(password)
 	userAuth := &UserAuth{
 		Username:   username,
-		Password:   pass,
+		Password:   passwordHash,
+		LoginType:  LOGIN_EMAIL,
 		UserInfoId: userinfo.ID,
 	}
 	result = db.Create(&userAuth)
@@ -184,7 +230,7 @@ func CreateNewUser(db *gorm.DB, username, password string) (*UserAuth, *UserInfo
 	// 创建 user - auth 关联表
 	userRole := &UserAuthRole{
 		UserAuthId: userAuth.ID,
-		RoleId:     2, // 默认身份为游客
+		RoleId:     role.ID,
 	}
 
 	result = db.Create(&userRole)
@@ -200,28 +246,20 @@ func GetAllMenuList(db *gorm.DB) (menu []Menu, err error) {
 	return menu, result.Error
 }
 
-// GetMenuListByUserId 根据 user id 获取菜单列表
+// GetMenuListByUserId 根据 user id 获取菜单列表, 包括从上级角色继承的菜单
 func GetMenuListByUserId(db *gorm.DB, id int) (menus []Menu, err error) {
-	var userAuth UserAuth
-	result := db.Where(&UserAuth{Model: Model{ID: id}}).
-		Preload("Roles").Preload("Roles.Menus").First(&userAuth)
-
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	set := make(map[int]Menu)
-	for _, role := range userAuth.Roles {
-		for _, menu := range role.Menus {
-			set[menu.ID] = menu
-		}
+	roleIds, err := GetRoleIdsByUserId(db, id)
+	if err != nil {
+		return nil, err
 	}
-
-	for _, menu := range set {
-		menus = append(menus, menu)
+	roleIds, err = GetEffectiveRoleIds(db, roleIds)
+	if err != nil {
+		return nil, err
 	}
 
-	return menus, nil
+	result := db.Where("id IN (?)", db.Model(&RoleMenu{}).Select("menu_id").Where("role_id IN ?", roleIds)).
+		Find(&menus)
+	return menus, result.Error
 }
 
 // GetMenuList 根据 keyword 从数据库中获取 menu 菜单
@@ -317,17 +355,15 @@ func SaveOrUpdateResource(db *gorm.DB, id, pid int, name, url, method string) er
 	return result.Error
 }
 
-// GetRoleList 获取角色列表
-func GetRoleList(db *gorm.DB, num, size int, keyword string) (list []RoleVO, total int64, err error) {
+// GetRoleList 获取角色列表, 角色数量较少, 不分页, 由调用方组装成树
+func GetRoleList(db *gorm.DB, keyword string) (list []RoleVO, err error) {
 	db = db.Model(&Role{})
 	if keyword != "" {
 		db = db.Where("name like ?", "%"+keyword+"%")
 	}
-	db.Count(&total)
-	result := db.Select("id", "name", "label", "created_at", "is_disable").
-		Scopes(Paginate(num, size)).
+	result := db.Select("id", "name", "label", "created_at", "is_disable", "parent_id", "require_2fa").
 		Find(&list)
-	return list, total, result.Error
+	return list, result.Error
 }
 
 func GetResourceIdsByRoleId(db *gorm.DB, roleId int) (ids []int, err error) {
@@ -344,45 +380,65 @@ func GetMenuIdsByRoleId(db *gorm.DB, roleId int) (ids []int, err error) {
 	return ids, result.Error
 }
 
-func SaveRole(db *gorm.DB, name, label string) error {
+func SaveRole(db *gorm.DB, name, label string, parentId int) error {
 	role := Role{
-		Name:  name,
-		Label: label,
+		Name:     name,
+		Label:    label,
+		ParentId: parentId,
 	}
-	result := db.Create(&role)
-	return result.Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := checkRoleParent(tx, 0, parentId); err != nil {
+			return err
+		}
+		return tx.Create(&role).Error
+	})
 }
 
-func UpdateRole(db *gorm.DB, id int, name, label string, isDisable bool, resourceIds, menuIds []int) error {
+func UpdateRole(db *gorm.DB, id int, name, label string, isDisable, require2FA bool, parentId int, resourceIds, menuIds []int, scopes map[string]string) error {
 	role := Role{
-		Model:     Model{ID: id},
-		Name:      name,
-		Label:     label,
-		IsDisable: isDisable,
+		Model:      Model{ID: id},
+		Name:       name,
+		Label:      label,
+		IsDisable:  isDisable,
+		ParentId:   parentId,
+		Require2FA: require2FA,
 	}
 
 	// 同时更新多个数据库表
 	return db.Transaction(func(tx *gorm.DB) error {
-		if err := db.Model(&role).Select("name", "label", "is_disable").Updates(&role).Error; err != nil {
+		if err := checkRoleParent(tx, id, parentId); err != nil {
+			return err
+		}
+		if err := tx.Model(&role).Select("name", "label", "is_disable", "parent_id", "require_2fa").Updates(&role).Error; err != nil {
 			return err
 		}
 
 		// role_resource
-		if err := db.Delete(&RoleResource{}, "role_id = ?", id).Error; err != nil {
+		if err := tx.Delete(&RoleResource{}, "role_id = ?", id).Error; err != nil {
 			return err
 		}
 		for _, rid := range resourceIds {
-			if err := db.Create(&RoleResource{RoleId: role.ID, ResourceId: rid}).Error; err != nil {
+			if err := tx.Create(&RoleResource{RoleId: role.ID, ResourceId: rid}).Error; err != nil {
 				return err
 			}
 		}
 
 		// role_menu
-		if err := db.Delete(&RoleMenu{}, "role_id = ?", id).Error; err != nil {
+		if err := tx.Delete(&RoleMenu{}, "role_id = ?", id).Error; err != nil {
 			return err
 		}
 		for _, mid := range menuIds {
-			if err := db.Create(&RoleMenu{RoleId: role.ID, MenuId: mid}).Error; err != nil {
+			if err := tx.Create(&RoleMenu{RoleId: role.ID, MenuId: mid}).Error; err != nil {
+				return err
+			}
+		}
+
+		// role_scope
+		if err := tx.Delete(&RoleScope{}, "role_id = ?", id).Error; err != nil {
+			return err
+		}
+		for data, scope := range scopes {
+			if err := tx.Create(&RoleScope{RoleId: role.ID, Data: data, Scope: scope}).Error; err != nil {
 				return err
 			}
 		}
@@ -391,20 +447,51 @@ func UpdateRole(db *gorm.DB, id int, name, label string, isDisable bool, resourc
 	})
 }
 
-// DeleteRoles 删除角色: 事务删除 role, role_resource, role_menu
+// DeleteRoles 删除角色: 事务删除 role, role_resource, role_menu, role_scope
+// 被删除角色的下级角色改为继承最近的未被删除的上级角色, 保持其原有的权限
 func DeleteRoles(db *gorm.DB, ids []int) error {
 	return db.Transaction(func(tx *gorm.DB) error {
-		result := db.Delete(&Role{}, "id in ?", ids)
+		parents, err := GetRoleParents(tx)
+		if err != nil {
+			return err
+		}
+		deleted := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			deleted[id] = true
+		}
+		for id, pid := range parents {
+			if deleted[id] || !deleted[pid] {
+				continue
+			}
+			newPid, seen := pid, make(map[int]bool)
+			for deleted[newPid] && !seen[newPid] {
+				seen[newPid] = true
+				newPid = parents[newPid]
+			}
+			if deleted[newPid] { // 上级角色全部被删除且形成循环
+				newPid = 0
+			}
+			if err := tx.Model(&Role{}).Where("id = ?", id).Update("parent_id", newPid).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Delete(&Role{}, "id in ?", ids)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		result = tx.Delete(&RoleResource{}, "role_id in ?", ids)
 		if result.Error != nil {
 			return result.Error
 		}
 
-		result = db.Delete(&RoleResource{}, "role_id in ?", ids)
+		result = tx.Delete(&RoleMenu{}, "role_id in ?", ids)
 		if result.Error != nil {
 			return result.Error
 		}
 
-		result = db.Delete(&RoleMenu{}, "role_id in ?", ids)
+		result = tx.Delete(&RoleScope{}, "role_id in ?", ids)
 		if result.Error != nil {
 			return result.Error
 		}
@@ -412,3 +499,71 @@ func DeleteRoles(db *gorm.DB, ids []int) error {
 		return nil
 	})
 }
+
+// GetRoleParents 获取所有角色的上级角色: role id => 上级角色 id, 只包含有上级角色的角色
+func GetRoleParents(db *gorm.DB) (map[int]int, error) {
+	var list []Role
+	result := db.Model(&Role{}).Select("id", "parent_id").Where("parent_id <> 0").Find(&list)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	parents := make(map[int]int, len(list))
+	for _, role := range list {
+		parents[role.ID] = role.ParentId
+	}
+	return parents, nil
+}
+
+// GetEffectiveRoleIds 角色及其所有上级角色的 id, 角色拥有的资源和菜单为其中所有角色的并集
+// 与权限表一致: 禁用的角色不生效, 也不继承上级角色, 继承关系在禁用的上级角色处中断
+func GetEffectiveRoleIds(db *gorm.DB, roleIds []int) ([]int, error) {
+	enabledIds, err := GetEnabledRoleIds(db)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[int]bool, len(enabledIds))
+	for _, id := range enabledIds {
+		enabled[id] = true
+	}
+
+	parents, err := GetRoleParents(db)
+	if err != nil {
+		return nil, err
+	}
+	for id, pid := range parents {
+		if !enabled[id] || !enabled[pid] {
+			delete(parents, id)
+		}
+	}
+
+	ids := make([]int, 0, len(roleIds))
+	for _, id := range roleIds {
+		if enabled[id] {
+			ids = append(ids, id)
+		}
+	}
+	return policy.Ancestors(parents, ids), nil
+}
+
+// checkRoleParent 检查上级角色是否存在, 以及设置后是否会形成循环继承
+func checkRoleParent(db *gorm.DB, id, parentId int) error {
+	if parentId == 0 {
+		return nil
+	}
+	if parentId == id {
+		return ErrRoleCycle
+	}
+	if err := db.Select("id").First(&Role{}, parentId).Error; err != nil {
+		return err
+	}
+
+	parents, err := GetRoleParents(db)
+	if err != nil {
+		return err
+	}
+	if policy.HasCycle(parents, id, parentId) {
+		return ErrRoleCycle
+	}
+	return nil
+}
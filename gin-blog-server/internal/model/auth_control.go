@@ -1,6 +1,10 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+)
 
 func GetResource(db *gorm.DB, uri, method string) (resource Resource, err error) {
 	result := db.Where(&Resource{Url: uri, Method: method}).First(&resource)
@@ -26,19 +30,22 @@ func GetResourceByRole(db *gorm.DB, rid int) (resources []Resource, err error) {
 	return role.Resources, result.Error
 }
 
-// CheckRoleAuth 根据 role id 判断是否有运行 uri+method 的权限
-func CheckRoleAuth(db *gorm.DB, rid int, uri, method string) (bool, error) {
-	resources, err := GetResourceByRole(db, rid)
-	if err != nil {
-		return false, err
-	}
+// RoleGrant 角色拥有的接口权限
+type RoleGrant struct {
+	RoleId int
+	Url    string
+	Method string
+}
 
-	for _, r := range resources {
-		if r.Url == uri && r.Method == method {
-			return true, nil
-		}
-	}
-	return false, nil
+// GetRoleGrants 获取所有启用的角色拥有的接口权限, 禁用的角色不拥有任何权限
+func GetRoleGrants(db *gorm.DB) (list []RoleGrant, err error) {
+	result := db.Table("role_resource").
+		Select("role_resource.role_id, resource.url, resource.method").
+		Joins("JOIN role ON role.id = role_resource.role_id").
+		Joins("JOIN resource ON resource.id = role_resource.resource_id").
+		Where("role.is_disable = ? AND resource.url <> ''", false).
+		Scan(&list)
+	return list, result.Error
 }
 
 // CheckResourceInUse 检查资源是否在使用中
@@ -75,3 +82,128 @@ func UpdateResourceAnonymous(db *gorm.DB, id int, anonymous bool) error {
 	result := db.Model(&Resource{}).Where("id = ?", id).Update("anonymous", anonymous)
 	return result.Error
 }
+
+// Route 接口路由, Url 不含 /api 前缀, 与 Resource 的 Url 格式一致
+type Route struct {
+	Method string `json:"request_method"`
+	Url    string `json:"url"`
+}
+
+// ResourceSyncResult 路由与资源表的同步结果
+type ResourceSyncResult struct {
+	Routes  int        `json:"routes"`  // 路由数量
+	Added   []Resource `json:"added"`   // 新增的资源
+	Stale   []Resource `json:"stale"`   // 路由中已不存在的资源
+	Modules []Resource `json:"modules"` // 新增的模块
+}
+
+// SyncResources 将接口路由同步到资源表:
+// 新增缺失的资源, 挂在同一路由分组 (如 /article/list => article) 已有资源所在的模块下, 没有时新建模块
+// 路由中已不存在的资源标记为失效 (不删除, 可能已分配给角色), 重新出现时取消标记
+func SyncResources(db *gorm.DB, routes []Route) (*ResourceSyncResult, error) {
+	result := &ResourceSyncResult{
+		Routes:  len(routes),
+		Added:   make([]Resource, 0),
+		Stale:   make([]Resource, 0),
+		Modules: make([]Resource, 0),
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var resources []Resource
+		if err := tx.Find(&resources).Error; err != nil {
+			return err
+		}
+
+		exists := make(map[Route]bool, len(resources))
+		names := make(map[string]bool, len(resources))
+		modules := make(map[string]int) // 路由分组 => 模块 id
+		for _, r := range resources {
+			names[r.Name] = true
+			if r.Url == "" { // 模块
+				continue
+			}
+			exists[Route{Method: r.Method, Url: r.Url}] = true
+			if group := routeGroup(r.Url); r.ParentId != 0 && modules[group] == 0 {
+				modules[group] = r.ParentId
+			}
+		}
+
+		for _, route := range routes {
+			if exists[route] {
+				continue
+			}
+			group := routeGroup(route.Url)
+			if modules[group] == 0 {
+				module := Resource{Name: uniqueResourceName(names, group+" 模块")}
+				if err := tx.Create(&module).Error; err != nil {
+					return err
+				}
+				modules[group] = module.ID
+				result.Modules = append(result.Modules, module)
+			}
+
+			resource := Resource{
+				Name:     uniqueResourceName(names, route.Method+" "+route.Url),
+				ParentId: modules[group],
+				Url:      route.Url,
+				Method:   route.Method,
+			}
+			if err := tx.Create(&resource).Error; err != nil {
+				return err
+			}
+			exists[route] = true
+			result.Added = append(result.Added, resource)
+		}
+
+		live := make(map[Route]bool, len(routes))
+		for _, route := range routes {
+			live[route] = true
+		}
+		var staleIds, liveIds []int
+		for _, r := range resources {
+			if r.Url == "" {
+				continue
+			}
+			if live[Route{Method: r.Method, Url: r.Url}] {
+				if r.Stale {
+					liveIds = append(liveIds, r.ID)
+				}
+				continue
+			}
+			r.Stale = true
+			staleIds = append(staleIds, r.ID)
+			result.Stale = append(result.Stale, r)
+		}
+		if len(staleIds) > 0 {
+			if err := tx.Model(&Resource{}).Where("id IN ?", staleIds).Update("stale", true).Error; err != nil {
+				return err
+			}
+		}
+		if len(liveIds) > 0 {
+			if err := tx.Model(&Resource{}).Where("id IN ?", liveIds).Update("stale", false).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// routeGroup 路由分组, 即路由的第一段: /article/list => article
+func routeGroup(url string) string {
+	group, _, _ := strings.Cut(strings.TrimPrefix(url, "/"), "/")
+	return group
+}
+
+// uniqueResourceName 资源名称唯一且最长 50 个字符, 重复时添加数字后缀
+func uniqueResourceName(names map[string]bool, name string) string {
+	if runes := []rune(name); len(runes) > 45 {
+		name = string(runes[:45])
+	}
+	unique := name
+	for i := 1; names[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	names[unique] = true
+	return unique
+}
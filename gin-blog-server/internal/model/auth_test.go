@@ -0,0 +1,49 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func TestGetMenuListByUserId(t *testing.T) {
+	db := newTestDB(t)
+
+	// 角色继承关系: 3 -> 2 -> 1, 4 -> 2; 每个角色各有一个菜单
+	roles := []Role{
+		{Model: Model{ID: 1}, Name: "root", Label: "root"},
+		{Model: Model{ID: 2}, Name: "parent", Label: "parent", ParentId: 1},
+		{Model: Model{ID: 3}, Name: "child", Label: "child", ParentId: 2},
+		{Model: Model{ID: 4}, Name: "other", Label: "other", ParentId: 2},
+	}
+	assert.Nil(t, db.Create(&roles).Error)
+	for i := 1; i <= 4; i++ {
+		assert.Nil(t, db.Create(&Menu{Model: Model{ID: i}, Name: "menu", Path: "/menu/" + strconv.Itoa(i)}).Error)
+		assert.Nil(t, db.Create(&RoleMenu{RoleId: i, MenuId: i}).Error)
+	}
+	assert.Nil(t, db.Create(&UserAuthRole{UserAuthId: 1, RoleId: 3}).Error)
+	assert.Nil(t, db.Create(&UserAuthRole{UserAuthId: 2, RoleId: 4}).Error)
+
+	menuIds := func(userId int) []int {
+		menus, err := GetMenuListByUserId(db, userId)
+		assert.Nil(t, err)
+		ids := make([]int, 0, len(menus))
+		for _, m := range menus {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []int{1, 2, 3}, menuIds(1))
+	assert.ElementsMatch(t, []int{1, 2, 4}, menuIds(2))
+
+	// 禁用上级角色, 继承关系在禁用的角色处中断
+	assert.Nil(t, db.Model(&Role{}).Where("id", 2).Update("is_disable", true).Error)
+	assert.ElementsMatch(t, []int{3}, menuIds(1))
+	assert.ElementsMatch(t, []int{4}, menuIds(2))
+
+	// 禁用用户的角色, 不再拥有任何菜单
+	assert.Nil(t, db.Model(&Role{}).Where("id", 2).Update("is_disable", false).Error)
+	assert.Nil(t, db.Model(&Role{}).Where("id", 3).Update("is_disable", true).Error)
+	assert.Empty(t, menuIds(1))
+	assert.ElementsMatch(t, []int{1, 2, 4}, menuIds(2))
+}
@@ -1,10 +1,14 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 type Category struct {
 	Model
 	Name     string    `gorm:"unique;type:varchar(20);not null" json:"name"`
+	Slug     string    `gorm:"type:varchar(100);uniqueIndex" json:"slug"`
 	Articles []Article `gorm:"foreignKey:CategoryId"`
 }
 
@@ -13,14 +17,21 @@ type CategoryVO struct {
 	ArticleCount int `json:"article_count"`
 }
 
+// GetCategoryById 根据 id 获取分类
+func GetCategoryById(db *gorm.DB, id int) (category *Category, err error) {
+	result := db.First(&category, id)
+	return category, result.Error
+}
+
 // GetCategoryList 获取分类列表
 func GetCategoryList(db *gorm.DB, num, size int, keyword string) ([]CategoryVO, int64, error) {
 	var list = make([]CategoryVO, 0)
 	var total int64
 
+	visible, args := visibleArticleCond("a.", time.Now())
 	db = db.Table("category c").
-		Joins("LEFT JOIN article a ON c.id = a.category_id AND a.is_delete = 0 AND a.status = 1").
-		Select("c.id", "c.name", "COUNT(a.id) as article_count", "c.created_at", "c.updated_at")
+		Joins("LEFT JOIN article a ON c.id = a.category_id AND "+visible, args...).
+		Select("c.id", "c.name", "c.slug", "COUNT(a.id) as article_count", "c.created_at", "c.updated_at")
 
 	if keyword != "" {
 		db = db.Where("name LIKE ?", "%"+keyword+"%")
@@ -35,19 +46,57 @@ func GetCategoryList(db *gorm.DB, num, size int, keyword string) ([]CategoryVO,
 }
 
 // SaveOrUpdateCategory 添加或修改分类
-func SaveOrUpdateCategory(db *gorm.DB, id int, name string) (*Category, error) {
+// slugSource 为自定义的 slug, 为空时根据名称生成, slug 变化时记录旧 slug 的重定向
+func SaveOrUpdateCategory(db *gorm.DB, id int, name, slugSource string) (*Category, error) {
 	category := Category{
 		Model: Model{ID: id},
 		Name:  name,
 	}
 
-	var result *gorm.DB
-	if id > 0 {
-		result = db.Updates(&category)
-	} else {
-		result = db.Create(&category)
+	if slugSource == "" {
+		slugSource = name
 	}
 
+	err := db.Transaction(func(tx *gorm.DB) error {
+		oldSlug, err := currentSlug(tx, SLUG_CATEGORY, id)
+		if err != nil {
+			return err
+		}
+		category.Slug, err = uniqueSlug(tx, SLUG_CATEGORY, id, slugSource)
+		if err != nil {
+			return err
+		}
+
+		var result *gorm.DB
+		if id > 0 {
+			result = tx.Updates(&category)
+		} else {
+			result = tx.Create(&category)
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return saveSlugRedirect(tx, SLUG_CATEGORY, category.ID, oldSlug, category.Slug)
+	})
+
+	return &category, err
+}
+
+// firstOrCreateCategory 根据名称获取分类, 不存在则创建
+func firstOrCreateCategory(db *gorm.DB, name string) (*Category, error) {
+	var category Category
+	result := db.Where("name", name).Limit(1).Find(&category)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return &category, result.Error
+	}
+
+	slug, err := uniqueSlug(db, SLUG_CATEGORY, 0, name)
+	if err != nil {
+		return nil, err
+	}
+	category = Category{Name: name, Slug: slug}
+	result = db.Create(&category)
 	return &category, result.Error
 }
 
@@ -57,6 +106,9 @@ func DeleteCategory(db *gorm.DB, ids []int) (int64, error) {
 	if result.Error != nil {
 		return 0, result.Error
 	}
+	if err := DeleteSlugRedirects(db, SLUG_CATEGORY, ids); err != nil {
+		return 0, err
+	}
 	return result.RowsAffected, nil
 }
 
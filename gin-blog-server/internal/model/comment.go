@@ -44,8 +44,10 @@ func GetArticleCommentCount(db *gorm.DB, articleId int) (count int64, err error)
 	return count, result.Error
 }
 
-// GetCommentList 根据 用户名称 获取后台评论列表
-func GetCommentList(db *gorm.DB, page, size, typ int, isReview *bool, nickname string) (data []Comment, total int64, err error) {
+// GetCommentList 根据 用户名称 获取后台评论列表, scope 为数据权限范围
+func GetCommentList(db *gorm.DB, scope DataScope, page, size, typ int, isReview *bool, nickname string) (data []Comment, total int64, err error) {
+	db = db.Scopes(scope.Filter(""))
+
 	// 先获取用户名称对应的用户 id
 	// SELECT UID FROM user_info WHERE nikename LIKE nickname
 	var uid int
@@ -75,6 +77,24 @@ func GetCommentList(db *gorm.DB, page, size, typ int, isReview *bool, nickname s
 	return data, total, result.Error
 }
 
+// DeleteComments 删除评论
+func DeleteComments(db *gorm.DB, scope DataScope, ids []int) (int64, error) {
+	if err := checkScope(db, &Comment{}, scope, ids); err != nil {
+		return 0, err
+	}
+	result := db.Delete(&Comment{}, "id in ?", ids)
+	return result.RowsAffected, result.Error
+}
+
+// UpdateCommentsReview 修改评论审核
+func UpdateCommentsReview(db *gorm.DB, scope DataScope, ids []int, isReview bool) (int64, error) {
+	if err := checkScope(db, &Comment{}, scope, ids); err != nil {
+		return 0, err
+	}
+	result := db.Model(&Comment{}).Where("id in ?", ids).Update("is_review", isReview)
+	return result.RowsAffected, result.Error
+}
+
 // AddComment 新增评论
 func AddComment(db *gorm.DB, userId, typ, topicId int, content string, isReview bool) (*Comment, error) {
 	comment := Comment{
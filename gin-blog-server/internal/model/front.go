@@ -1,6 +1,9 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 type FrontHomeVO struct {
 	ArticleCount  int64             `json:"article_count"`  // 文章数量
@@ -12,9 +15,46 @@ type FrontHomeVO struct {
 	Config        map[string]string `json:"blog_config"`    // 博客信息
 }
 
+// SitemapVO sitemap 中的一条数据
+type SitemapVO struct {
+	ID        int
+	Slug      string
+	UpdatedAt time.Time
+}
+
+// SitemapData sitemap 需要的数据: 前台可见的文章, 分类, 标签, 页面
+type SitemapData struct {
+	Articles   []SitemapVO
+	Categories []SitemapVO
+	Tags       []SitemapVO
+	Pages      []Page
+}
+
+// GetSitemapData 获取生成 sitemap 需要的数据
+func GetSitemapData(db *gorm.DB) (data SitemapData, err error) {
+	result := db.Model(&Article{}).Select("id", "slug", "updated_at").
+		Scopes(VisibleArticle("")).Order("id DESC").Find(&data.Articles)
+	if result.Error != nil {
+		return data, result.Error
+	}
+
+	result = db.Model(&Category{}).Select("id", "slug", "updated_at").Order("id").Find(&data.Categories)
+	if result.Error != nil {
+		return data, result.Error
+	}
+
+	result = db.Model(&Tag{}).Select("id", "slug", "updated_at").Order("id").Find(&data.Tags)
+	if result.Error != nil {
+		return data, result.Error
+	}
+
+	data.Pages, _, err = GetPageList(db)
+	return data, err
+}
+
 // GetFrontStatistics 获取前台静态统计数据
 func GetFrontStatistics(db *gorm.DB) (data FrontHomeVO, err error) {
-	result := db.Model(&Article{}).Where("status = ? AND is_delete = ?", 1, 0).Count(&data.ArticleCount)
+	result := db.Model(&Article{}).Scopes(VisibleArticle("")).Count(&data.ArticleCount)
 	if result.Error != nil {
 		return data, result.Error
 	}
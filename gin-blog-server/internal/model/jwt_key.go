@@ -0,0 +1,71 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// JWTKey RS256/EdDSA 签名密钥, 多个实例共享
+// 轮换后旧密钥保留到用它签名的 token 全部过期, 验证时根据 token 头部的 kid 选择密钥
+type JWTKey struct {
+	Model
+	Kid        string     `gorm:"unique;type:varchar(64)" json:"kid"`
+	Alg        string     `gorm:"type:varchar(10)" json:"alg"`
+	PrivateKey string     `gorm:"type:text" json:"-"` // 使用 JWT.KeySecret 加密的 PKCS #8 PEM 格式私钥
+	RetiredAt  *time.Time `json:"retired_at"`         // 被新密钥替换的时间, 为空表示当前使用的密钥
+	ExpiresAt  *time.Time `json:"expires_at"`         // 之后不再接受该密钥签名的 token
+}
+
+// GetJWTKeys 获取未过期的签名密钥, 按创建顺序排列, 最后一个未替换的密钥为当前密钥
+func GetJWTKeys(db *gorm.DB, now time.Time) (list []JWTKey, err error) {
+	result := db.Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("id").
+		Find(&list)
+	return list, result.Error
+}
+
+// GetActiveJWTKey 获取当前的签名密钥
+func GetActiveJWTKey(db *gorm.DB) (*JWTKey, error) {
+	var key JWTKey
+	result := db.Where("retired_at IS NULL").Order("id DESC").First(&key)
+	return &key, result.Error
+}
+
+// RotateJWTKey 使用 key 替换当前的签名密钥 current (为空表示还没有密钥), 旧密钥在 expiresAt 之前仍然可以用于验证
+// 当前密钥已经被其他实例替换时不做修改, 返回 false
+func RotateJWTKey(db *gorm.DB, key *JWTKey, current string, now, expiresAt time.Time) (bool, error) {
+	var rotated bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if current == "" {
+			var count int64
+			if err := tx.Model(&JWTKey{}).Where("retired_at IS NULL").Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+		} else {
+			result := tx.Model(&JWTKey{}).
+				Where("kid = ? AND retired_at IS NULL", current).
+				Updates(map[string]any{"retired_at": now, "expires_at": expiresAt})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+		}
+
+		rotated = true
+		return tx.Create(key).Error
+	})
+	return rotated, err
+}
+
+// UpdateJWTKeyPrivate 更新签名密钥保存的私钥, 用于加密之前以明文保存的私钥
+func UpdateJWTKeyPrivate(db *gorm.DB, id int, private string) error {
+	return db.Model(&JWTKey{}).Where("id", id).Update("private_key", private).Error
+}
+
+// DeleteExpiredJWTKeys 删除已经过期的签名密钥, 返回删除的数量
+func DeleteExpiredJWTKeys(db *gorm.DB, now time.Time) (int64, error) {
+	result := db.Where("expires_at <= ?", now).Delete(&JWTKey{})
+	return result.RowsAffected, result.Error
+}
@@ -11,10 +11,12 @@ type Message struct {
 	IpSource  string `gorm:"type:varchar(255);comment:IP 来源" json:"ipSource"`
 	Speed     int    `gorm:"type:tinyint(1);comment:弹幕速度" json:"speed"`
 	IsReview  bool   `json:"is_review"`
+	UserId    int    `json:"-"` // 留言者 user_auth_id
 }
 
-func GetMessageList(db *gorm.DB, num, size int, nickname string, isReview *bool) (list []Message, total int64, err error) {
-	db = db.Model(&Message{})
+// GetMessageList 获取留言列表, scope 为数据权限范围
+func GetMessageList(db *gorm.DB, scope DataScope, num, size int, nickname string, isReview *bool) (list []Message, total int64, err error) {
+	db = db.Model(&Message{}).Scopes(scope.Filter(""))
 
 	if nickname != "" {
 		db = db.Where("nickname LIKE ?", "%"+nickname+"%")
@@ -29,18 +31,24 @@ func GetMessageList(db *gorm.DB, num, size int, nickname string, isReview *bool)
 	return list, total, result.Error
 }
 
-func DeleteMessages(db *gorm.DB, ids []int) (int64, error) {
+func DeleteMessages(db *gorm.DB, scope DataScope, ids []int) (int64, error) {
+	if err := checkScope(db, &Message{}, scope, ids); err != nil {
+		return 0, err
+	}
 	result := db.Where("id in ?", ids).Delete(&Message{})
 	return result.RowsAffected, result.Error
 }
 
-func UpdateMessagesReview(db *gorm.DB, ids []int, isReview bool) (int64, error) {
+func UpdateMessagesReview(db *gorm.DB, scope DataScope, ids []int, isReview bool) (int64, error) {
+	if err := checkScope(db, &Message{}, scope, ids); err != nil {
+		return 0, err
+	}
 	result := db.Model(&Message{}).Where("id in ?", ids).Update("is_review", isReview)
 	return result.RowsAffected, result.Error
 }
 
-// SaveMessage 保存留言功能
-func SaveMessage(db *gorm.DB, nickname, avatar, content, address, source string, speed int, isReview bool) (*Message, error) {
+// SaveMessage 保存留言功能, userId 为留言者的 user_auth_id
+func SaveMessage(db *gorm.DB, userId int, nickname, avatar, content, address, source string, speed int, isReview bool) (*Message, error) {
 	message := Message{
 		Nickname:  nickname,
 		Avatar:    avatar,
@@ -49,6 +57,7 @@ func SaveMessage(db *gorm.DB, nickname, avatar, content, address, source string,
 		IpSource:  source,
 		Speed:     speed,
 		IsReview:  isReview,
+		UserId:    userId,
 	}
 
 	result := db.Create(&message)
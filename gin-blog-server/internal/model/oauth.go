@@ -0,0 +1,140 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gorm.io/gorm"
+	"strconv"
+	"unicode/utf8"
+)
+
+// UserOauth 用户绑定的第三方账号, 每个用户在每种第三方登录方式中只能绑定一个账号
+type UserOauth struct {
+	Model
+	UserAuthId int    `json:"-" gorm:"uniqueIndex:idx_user_oauth_user"`
+	Provider   string `json:"provider" gorm:"type:varchar(30);uniqueIndex:idx_user_oauth_user;uniqueIndex:idx_user_oauth_subject"` // 第三方登录方式的名称 (配置中的 Name)
+	Subject    string `json:"-" gorm:"type:varchar(255);uniqueIndex:idx_user_oauth_subject"`                                       // 第三方平台中的用户唯一标识
+	Nickname   string `json:"nickname" gorm:"type:varchar(50)"`                                                                    // 第三方平台中的昵称, 最后一次登录时更新
+	Avatar     string `json:"avatar" gorm:"type:varchar(1024)"`
+	Email      string `json:"email" gorm:"type:varchar(50)"`
+}
+
+// NewUserOauth 根据第三方资料创建绑定关系, 超过字段长度的资料被截断或忽略
+func NewUserOauth(provider, subject, nickname, avatar, email string) *UserOauth {
+	binding := &UserOauth{Provider: provider, Subject: subject, Nickname: truncate(nickname, 50)}
+	if len(avatar) <= 1024 {
+		binding.Avatar = avatar
+	}
+	if len(email) <= 50 {
+		binding.Email = email
+	}
+	return binding
+}
+
+// GetUserOauth 根据第三方登录方式和第三方用户标识查询绑定关系
+func GetUserOauth(db *gorm.DB, provider, subject string) (*UserOauth, error) {
+	var binding UserOauth
+	result := db.Where("provider = ? AND subject = ?", provider, subject).First(&binding)
+	return &binding, result.Error
+}
+
+// GetUserOauthList 获取用户绑定的所有第三方账号
+func GetUserOauthList(db *gorm.DB, userAuthId int) (list []UserOauth, err error) {
+	result := db.Where("user_auth_id = ?", userAuthId).Order("id").Find(&list)
+	return list, result.Error
+}
+
+// LinkOauth 绑定第三方账号, 第三方账号已被绑定或用户已绑定该登录方式时违反唯一索引
+func LinkOauth(db *gorm.DB, binding *UserOauth) error {
+	return db.Create(binding).Error
+}
+
+// UnlinkOauth 解绑第三方账号, 返回删除的数量
+func UnlinkOauth(db *gorm.DB, userAuthId int, provider string) (int64, error) {
+	result := db.Where("user_auth_id = ? AND provider = ?", userAuthId, provider).Delete(&UserOauth{})
+	return result.RowsAffected, result.Error
+}
+
+// UpdateOauthProfile 第三方登录时更新绑定中保存的第三方资料
+func UpdateOauthProfile(db *gorm.DB, binding *UserOauth) error {
+	return db.Model(binding).Select("nickname", "avatar", "email").Updates(binding).Error
+}
+
+// CreateOauthUser 第三方登录的新用户: 使用第三方资料创建没有密码的用户, 并绑定第三方账号
+// 用户名为 <登录方式>:<第三方用户标识>, 昵称重复时添加数字后缀
+func CreateOauthUser(db *gorm.DB, binding *UserOauth, loginType int, roleLabel string) (*UserAuth, error) {
+	var role Role
+	if err := db.Select("id").Where("label = ?", roleLabel).First(&role).Error; err != nil {
+		return nil, fmt.Errorf("默认角色 %s 不存在: %w", roleLabel, err)
+	}
+
+	userAuth := &UserAuth{Username: oauthUsername(binding.Provider, binding.Subject), LoginType: loginType}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		nickname, err := uniqueNickname(tx, binding.Nickname)
+		if err != nil {
+			return err
+		}
+		userInfo := &UserInfo{Nickname: nickname, Avatar: binding.Avatar}
+		if userInfo.Avatar == "" {
+			userInfo.Avatar = defaultAvatar
+		}
+		if len(binding.Email) <= 30 { // UserInfo.Email 的长度限制
+			userInfo.Email = binding.Email
+		}
+		if err := tx.Create(userInfo).Error; err != nil {
+			return err
+		}
+
+		userAuth.UserInfoId = userInfo.ID
+		if err := tx.Create(userAuth).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&UserAuthRole{UserAuthId: userAuth.ID, RoleId: role.ID}).Error; err != nil {
+			return err
+		}
+		binding.UserAuthId = userAuth.ID
+		return tx.Create(binding).Error
+	})
+	return userAuth, err
+}
+
+// oauthUsername 第三方登录用户的用户名, 超过 50 个字符时使用第三方用户标识的摘要
+func oauthUsername(provider, subject string) string {
+	name := provider + ":" + subject
+	if len(name) > 50 {
+		sum := sha256.Sum256([]byte(subject))
+		name = provider + ":" + hex.EncodeToString(sum[:])[:min(32, 49-len(provider))]
+	}
+	return name
+}
+
+// uniqueNickname 返回没有被使用的昵称, 昵称为空时使用 "游客"
+func uniqueNickname(db *gorm.DB, nickname string) (string, error) {
+	if nickname == "" {
+		nickname = "游客"
+	}
+	for i := 0; i < 100; i++ {
+		suffix := ""
+		if i > 0 {
+			suffix = strconv.Itoa(i)
+		}
+		candidate := truncate(nickname, 30-len(suffix)) + suffix
+		var count int64
+		if err := db.Model(&UserInfo{}).Where("nickname = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("昵称 %s 已被使用", nickname)
+}
+
+// truncate 截取前 n 个字符
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -7,6 +7,8 @@ type Page struct {
 	Name  string `gorm:"unique;type:varchar(20)" json:"name"`
 	Label string `gorm:"unique;type:varchar(20)" json:"label"`
 	Cover string `gorm:"type:varchar(255)" json:"cover"`
+
+	UserId int `json:"-"` // 创建者 user_auth_id
 }
 
 // GetPageList 获取数据库中的所有 Page 记录
@@ -18,8 +20,8 @@ func GetPageList(db *gorm.DB) ([]Page, int64, error) {
 	return pages, total, result.Error
 }
 
-// SaveOrUpdatePage 保存或更新一个新的 Page 记录
-func SaveOrUpdatePage(db *gorm.DB, id int, name, label, cover string) (*Page, error) {
+// SaveOrUpdatePage 保存或更新一个新的 Page 记录, 新增时 scope.UserId 为创建者
+func SaveOrUpdatePage(db *gorm.DB, scope DataScope, id int, name, label, cover string) (*Page, error) {
 	page := Page{
 		Model: Model{ID: id},
 		Name:  name,
@@ -29,10 +31,26 @@ func SaveOrUpdatePage(db *gorm.DB, id int, name, label, cover string) (*Page, er
 
 	var result *gorm.DB
 	if id > 0 {
+		if err := checkScope(db, &Page{}, scope, []int{id}); err != nil {
+			return nil, err
+		}
 		result = db.Updates(&page)
 	} else {
+		if !scope.CanCreate() {
+			return nil, ErrOutOfScope
+		}
+		page.UserId = scope.UserId
 		result = db.Create(&page)
 	}
 
 	return &page, result.Error
 }
+
+// DeletePages 删除页面
+func DeletePages(db *gorm.DB, scope DataScope, ids []int) (int64, error) {
+	if err := checkScope(db, &Page{}, scope, ids); err != nil {
+		return 0, err
+	}
+	result := db.Delete(&Page{}, "id in ?", ids)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,737 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gin-blog-server/internal/utils/policy"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"slices"
+	"sort"
+	"strings"
+)
+
+/*
+权限配置文档: 菜单, 资源, 角色以及角色与菜单, 资源的关联, 使用自然键代替数据库 id, 可以在不同环境之间迁移
+
+自然键:
+  - 菜单: 完整路径, 子菜单的相对路径拼接在上级菜单的路径后面, 例如 /article/list
+  - 资源: 接口为 "METHOD url", 例如 "GET /article/list"; 模块 (没有 url) 为名称, 例如 "文章模块"
+  - 角色: label
+*/
+
+// RBACDocument 权限配置文档
+type RBACDocument struct {
+	Menus     []RBACMenu     `yaml:"menus"`
+	Resources []RBACResource `yaml:"resources"`
+	Roles     []RBACRole     `yaml:"roles"`
+}
+
+// RBACMenu 菜单, 子菜单在 children 中
+type RBACMenu struct {
+	Path         string     `yaml:"path"` // 一级菜单为完整路径, 子菜单为相对路径
+	Name         string     `yaml:"name"`
+	Component    string     `yaml:"component,omitempty"`
+	Icon         string     `yaml:"icon,omitempty"`
+	OrderNum     int8       `yaml:"order_num"`
+	Redirect     string     `yaml:"redirect,omitempty"`
+	Catalogue    bool       `yaml:"catalogue,omitempty"`
+	Hidden       bool       `yaml:"hidden,omitempty"`
+	KeepAlive    bool       `yaml:"keep_alive,omitempty"`
+	External     bool       `yaml:"external,omitempty"`
+	ExternalLink string     `yaml:"external_link,omitempty"`
+	Children     []RBACMenu `yaml:"children,omitempty"`
+}
+
+// RBACResource 资源: 没有 url 的为模块, 接口在模块的 children 中
+type RBACResource struct {
+	Name      string         `yaml:"name"`
+	Method    string         `yaml:"method,omitempty"`
+	Url       string         `yaml:"url,omitempty"`
+	Anonymous bool           `yaml:"anonymous,omitempty"`
+	Children  []RBACResource `yaml:"children,omitempty"`
+}
+
+// RBACRole 角色, 关联的菜单和资源使用自然键
+type RBACRole struct {
+	Label      string            `yaml:"label"`
+	Name       string            `yaml:"name"`
+	Disable    bool              `yaml:"disable,omitempty"`
+	Require2FA bool              `yaml:"require_2fa,omitempty"` // 必须开启两步验证
+	Parent     string            `yaml:"parent,omitempty"`      // 上级角色的 label
+	Menus      []string          `yaml:"menus,omitempty"`       // 菜单完整路径
+	Resources  []string          `yaml:"resources,omitempty"`   // 接口 "METHOD url", 模块的关联不导出
+	Scopes     map[string]string `yaml:"scopes,omitempty"`      // 数据权限, 没有设置的数据类型为 all
+}
+
+// RBACChange 导入时的一项变化
+type RBACChange struct {
+	Type   string   `json:"type"`             // menu, resource, role, role_menu, role_resource, role_scope
+	Action string   `json:"action"`           // create, update, delete
+	Key    string   `json:"key"`              // 自然键, 关联为 "角色 label => 自然键"
+	Fields []string `json:"fields,omitempty"` // 更新的字段
+}
+
+// RBACImportResult 导入结果, dry run 时只计算变化, 不修改数据库
+type RBACImportResult struct {
+	DryRun  bool         `json:"dry_run"`
+	Changes []RBACChange `json:"changes"`
+}
+
+// errRBACDryRun 用于在 dry run 结束后回滚事务
+var errRBACDryRun = errors.New("rbac dry run")
+
+// MarshalRBAC 将权限配置文档输出为 YAML
+func MarshalRBAC(doc *RBACDocument) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseRBAC 解析 YAML 格式的权限配置文档
+func ParseRBAC(data []byte) (*RBACDocument, error) {
+	var doc RBACDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析权限配置失败: %w", err)
+	}
+	return &doc, nil
+}
+
+// menuKey 菜单的完整路径, 绝对路径和外链直接使用自身的路径
+func menuKey(parentKey, path string) string {
+	if parentKey == "" || strings.HasPrefix(path, "/") || strings.Contains(path, "://") {
+		return path
+	}
+	return strings.TrimSuffix(parentKey, "/") + "/" + path
+}
+
+// resourceKey 资源的自然键, 模块为名称, 接口为 "METHOD url"
+func resourceKey(r Resource) string {
+	if r.Url == "" {
+		return r.Name
+	}
+	return r.Method + " " + r.Url
+}
+
+// rbacState 数据库中的菜单, 资源, 角色, 按照自然键索引
+type rbacState struct {
+	menuList     []Menu
+	resourceList []Resource
+	roleList     []Role
+
+	menus     map[string]Menu
+	menuKeys  map[int]string
+	resources map[string]Resource
+	resKeys   map[int]string
+	roles     map[string]Role
+	roleKeys  map[int]string
+}
+
+func loadRBACState(db *gorm.DB) (*rbacState, error) {
+	var menus []Menu
+	if err := db.Order("order_num, id").Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	var resources []Resource
+	if err := db.Order("id").Find(&resources).Error; err != nil {
+		return nil, err
+	}
+	var roles []Role
+	if err := db.Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	s := &rbacState{
+		menuList:     menus,
+		resourceList: resources,
+		roleList:     roles,
+		menus:        make(map[string]Menu),
+		menuKeys:     make(map[int]string),
+		resources:    make(map[string]Resource),
+		resKeys:      make(map[int]string),
+		roles:        make(map[string]Role),
+		roleKeys:     make(map[int]string),
+	}
+
+	byId := make(map[int]Menu, len(menus))
+	for _, m := range menus {
+		byId[m.ID] = m
+	}
+	var keyOf func(m Menu, depth int) string
+	keyOf = func(m Menu, depth int) string {
+		parent, ok := byId[m.ParentId]
+		if m.ParentId == 0 || !ok || depth > len(menus) {
+			return m.Path
+		}
+		return menuKey(keyOf(parent, depth+1), m.Path)
+	}
+	for _, m := range menus {
+		key := keyOf(m, 0)
+		s.menus[key], s.menuKeys[m.ID] = m, key
+	}
+	for _, r := range resources {
+		key := resourceKey(r)
+		s.resources[key], s.resKeys[r.ID] = r, key
+	}
+	for _, r := range roles {
+		s.roles[r.Label], s.roleKeys[r.ID] = r, r.Label
+	}
+	return s, nil
+}
+
+// ExportRBAC 导出权限配置
+func ExportRBAC(db *gorm.DB) (*RBACDocument, error) {
+	s, err := loadRBACState(db)
+	if err != nil {
+		return nil, err
+	}
+
+	menus := s.menuList
+	var buildMenus func(pid int) []RBACMenu
+	buildMenus = func(pid int) []RBACMenu {
+		var list []RBACMenu
+		for _, m := range menus {
+			if m.ParentId != pid || m.ID == pid {
+				continue
+			}
+			list = append(list, RBACMenu{
+				Path:         m.Path,
+				Name:         m.Name,
+				Component:    m.Component,
+				Icon:         m.Icon,
+				OrderNum:     m.OrderNum,
+				Redirect:     m.Redirect,
+				Catalogue:    m.Catalogue,
+				Hidden:       m.Hidden,
+				KeepAlive:    m.KeepAlive,
+				External:     m.External,
+				ExternalLink: m.ExternalLink,
+				Children:     buildMenus(m.ID),
+			})
+		}
+		return list
+	}
+
+	resources := s.resourceList
+	var buildResources func(pid int) []RBACResource
+	buildResources = func(pid int) []RBACResource {
+		var list []RBACResource
+		for _, r := range resources {
+			if r.ParentId != pid || r.ID == pid {
+				continue
+			}
+			list = append(list, RBACResource{
+				Name:      r.Name,
+				Method:    r.Method,
+				Url:       r.Url,
+				Anonymous: r.Anonymous,
+				Children:  buildResources(r.ID),
+			})
+		}
+		return list
+	}
+
+	doc := &RBACDocument{
+		Menus:     buildMenus(0),
+		Resources: buildResources(0),
+	}
+
+	for _, role := range s.roleList {
+		r := RBACRole{
+			Label:      role.Label,
+			Name:       role.Name,
+			Disable:    role.IsDisable,
+			Require2FA: role.Require2FA,
+			Parent:     s.roleKeys[role.ParentId],
+		}
+
+		menuIds, err := GetMenuIdsByRoleId(db, role.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range menuIds {
+			if key, ok := s.menuKeys[id]; ok {
+				r.Menus = append(r.Menus, key)
+			}
+		}
+		sort.Strings(r.Menus)
+
+		resourceIds, err := GetResourceIdsByRoleId(db, role.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range resourceIds {
+			if key, ok := s.resKeys[id]; ok && s.resources[key].Url != "" {
+				r.Resources = append(r.Resources, key)
+			}
+		}
+		sort.Strings(r.Resources)
+
+		var scopes []RoleScope
+		if err := db.Where("role_id = ?", role.ID).Find(&scopes).Error; err != nil {
+			return nil, err
+		}
+		if len(scopes) > 0 {
+			r.Scopes = make(map[string]string, len(scopes))
+			for _, scope := range scopes {
+				r.Scopes[scope.Data] = scope.Scope
+			}
+		}
+
+		doc.Roles = append(doc.Roles, r)
+	}
+	return doc, nil
+}
+
+// ImportRBAC 导入权限配置, 按照自然键新增或更新菜单, 资源, 角色, 多次导入同一份配置的结果相同
+// 文档中的角色关联的菜单, 接口, 数据权限以文档为准; prune 为 true 时删除文档中没有的菜单, 资源, 角色
+// dryRun 为 true 时只计算变化, 不修改数据库
+func ImportRBAC(db *gorm.DB, doc *RBACDocument, dryRun, prune bool) (*RBACImportResult, error) {
+	result := &RBACImportResult{DryRun: dryRun, Changes: make([]RBACChange, 0)}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		im := &rbacImporter{tx: tx, result: result}
+		if err := im.run(doc, prune); err != nil {
+			return err
+		}
+		if dryRun {
+			return errRBACDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRBACDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+type rbacImporter struct {
+	tx     *gorm.DB
+	state  *rbacState
+	result *RBACImportResult
+}
+
+func (im *rbacImporter) change(typ, action, key string, fields ...string) {
+	im.result.Changes = append(im.result.Changes, RBACChange{Type: typ, Action: action, Key: key, Fields: fields})
+}
+
+func (im *rbacImporter) run(doc *RBACDocument, prune bool) error {
+	state, err := loadRBACState(im.tx)
+	if err != nil {
+		return err
+	}
+	im.state = state
+
+	menuIds := make(map[string]int)
+	if err := im.importMenus(doc.Menus, 0, "", menuIds); err != nil {
+		return err
+	}
+	resourceIds := make(map[string]int)
+	if err := im.importResources(doc.Resources, 0, resourceIds); err != nil {
+		return err
+	}
+	roleIds, err := im.importRoles(doc.Roles, menuIds, resourceIds)
+	if err != nil {
+		return err
+	}
+
+	if prune {
+		return im.prune(menuIds, resourceIds, roleIds)
+	}
+	return nil
+}
+
+func (im *rbacImporter) importMenus(list []RBACMenu, pid int, parentKey string, ids map[string]int) error {
+	for _, item := range list {
+		key := menuKey(parentKey, item.Path)
+		if _, ok := ids[key]; ok {
+			return fmt.Errorf("菜单重复: %s", key)
+		}
+		menu := Menu{
+			ParentId:     pid,
+			Name:         item.Name,
+			Path:         item.Path,
+			Component:    item.Component,
+			Icon:         item.Icon,
+			OrderNum:     item.OrderNum,
+			Redirect:     item.Redirect,
+			Catalogue:    item.Catalogue,
+			Hidden:       item.Hidden,
+			KeepAlive:    item.KeepAlive,
+			External:     item.External,
+			ExternalLink: item.ExternalLink,
+		}
+
+		if old, ok := im.state.menus[key]; ok {
+			menu.ID = old.ID
+			fields := changedFields(
+				"parent_id", old.ParentId == menu.ParentId,
+				"name", old.Name == menu.Name,
+				"path", old.Path == menu.Path,
+				"component", old.Component == menu.Component,
+				"icon", old.Icon == menu.Icon,
+				"order_num", old.OrderNum == menu.OrderNum,
+				"redirect", old.Redirect == menu.Redirect,
+				"catalogue", old.Catalogue == menu.Catalogue,
+				"hidden", old.Hidden == menu.Hidden,
+				"keep_alive", old.KeepAlive == menu.KeepAlive,
+				"external", old.External == menu.External,
+				"external_link", old.ExternalLink == menu.ExternalLink,
+			)
+			if len(fields) > 0 {
+				if err := im.tx.Model(&menu).Select(fields).Updates(&menu).Error; err != nil {
+					return err
+				}
+				im.change("menu", "update", key, fields...)
+			}
+		} else {
+			if err := im.tx.Create(&menu).Error; err != nil {
+				return err
+			}
+			im.change("menu", "create", key)
+		}
+
+		ids[key] = menu.ID
+		if err := im.importMenus(item.Children, menu.ID, key, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *rbacImporter) importResources(list []RBACResource, pid int, ids map[string]int) error {
+	for _, item := range list {
+		resource := Resource{
+			ParentId:  pid,
+			Name:      item.Name,
+			Method:    strings.ToUpper(item.Method),
+			Url:       item.Url,
+			Anonymous: item.Anonymous,
+		}
+		key := resourceKey(resource)
+		if _, ok := ids[key]; ok {
+			return fmt.Errorf("资源重复: %s", key)
+		}
+
+		if old, ok := im.state.resources[key]; ok {
+			resource.ID = old.ID
+			fields := changedFields(
+				"parent_id", old.ParentId == resource.ParentId,
+				"name", old.Name == resource.Name,
+				"anonymous", old.Anonymous == resource.Anonymous,
+			)
+			if len(fields) > 0 {
+				if err := im.tx.Model(&resource).Select(fields).Updates(&resource).Error; err != nil {
+					return err
+				}
+				im.change("resource", "update", key, fields...)
+			}
+		} else {
+			if err := im.tx.Create(&resource).Error; err != nil {
+				return err
+			}
+			im.change("resource", "create", key)
+		}
+
+		ids[key] = resource.ID
+		if err := im.importResources(item.Children, resource.ID, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *rbacImporter) importRoles(list []RBACRole, menuIds, resourceIds map[string]int) (map[string]int, error) {
+	ids := make(map[string]int, len(list))
+
+	// 先保存所有角色, 再设置上级角色, 上级角色可以在文档中的任意位置
+	for _, item := range list {
+		if _, ok := ids[item.Label]; ok {
+			return nil, fmt.Errorf("角色重复: %s", item.Label)
+		}
+		role := Role{Name: item.Name, Label: item.Label, IsDisable: item.Disable, Require2FA: item.Require2FA}
+		if old, ok := im.state.roles[item.Label]; ok {
+			role.ID = old.ID
+			fields := changedFields(
+				"name", old.Name == role.Name,
+				"is_disable", old.IsDisable == role.IsDisable,
+				"require_2fa", old.Require2FA == role.Require2FA,
+			)
+			if len(fields) > 0 {
+				if err := im.tx.Model(&role).Select(fields).Updates(&role).Error; err != nil {
+					return nil, err
+				}
+				im.change("role", "update", item.Label, fields...)
+			}
+		} else {
+			if err := im.tx.Create(&role).Error; err != nil {
+				return nil, err
+			}
+			im.change("role", "create", item.Label)
+		}
+		ids[item.Label] = role.ID
+	}
+
+	parents, err := GetRoleParents(im.tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range list {
+		id, pid := ids[item.Label], 0
+		if item.Parent != "" {
+			var ok bool
+			if pid, ok = ids[item.Parent]; !ok {
+				if pid, ok = im.state.roleId(item.Parent); !ok {
+					return nil, fmt.Errorf("角色 %s 的上级角色不存在: %s", item.Label, item.Parent)
+				}
+			}
+		}
+		if parents[id] == pid {
+			continue
+		}
+		if policy.HasCycle(parents, id, pid) {
+			return nil, fmt.Errorf("%w: %s => %s", ErrRoleCycle, item.Label, item.Parent)
+		}
+		if err := im.tx.Model(&Role{}).Where("id = ?", id).Update("parent_id", pid).Error; err != nil {
+			return nil, err
+		}
+		parents[id] = pid
+		im.change("role", "update", item.Label, "parent_id")
+	}
+
+	for _, item := range list {
+		if err := im.importRoleLinks(item, ids[item.Label], menuIds, resourceIds); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func (s *rbacState) roleId(label string) (int, bool) {
+	role, ok := s.roles[label]
+	return role.ID, ok
+}
+
+// importRoleLinks 以文档为准更新角色关联的菜单, 接口和数据权限, 模块的关联保持不变
+func (im *rbacImporter) importRoleLinks(item RBACRole, roleId int, menuIds, resourceIds map[string]int) error {
+	lookup := func(typ string, keys []string, ids map[string]int, state map[string]int) (map[int]string, error) {
+		want := make(map[int]string, len(keys))
+		for _, key := range keys {
+			id, ok := ids[key]
+			if !ok {
+				if id, ok = state[key]; !ok {
+					return nil, fmt.Errorf("角色 %s 关联的%s不存在: %s", item.Label, typ, key)
+				}
+			}
+			want[id] = key
+		}
+		return want, nil
+	}
+
+	stateMenus := make(map[string]int, len(im.state.menus))
+	for key, m := range im.state.menus {
+		stateMenus[key] = m.ID
+	}
+	wantMenus, err := lookup("菜单", item.Menus, menuIds, stateMenus)
+	if err != nil {
+		return err
+	}
+	haveMenus, err := GetMenuIdsByRoleId(im.tx, roleId)
+	if err != nil {
+		return err
+	}
+	for _, id := range haveMenus {
+		if _, ok := wantMenus[id]; ok {
+			delete(wantMenus, id)
+			continue
+		}
+		if err := im.tx.Delete(&RoleMenu{}, "role_id = ? AND menu_id = ?", roleId, id).Error; err != nil {
+			return err
+		}
+		im.change("role_menu", "delete", item.Label+" => "+im.menuKeyById(id, menuIds))
+	}
+	for _, id := range sortedIds(wantMenus) {
+		if err := im.tx.Create(&RoleMenu{RoleId: roleId, MenuId: id}).Error; err != nil {
+			return err
+		}
+		im.change("role_menu", "create", item.Label+" => "+wantMenus[id])
+	}
+
+	stateResources := make(map[string]int, len(im.state.resources))
+	for key, r := range im.state.resources {
+		stateResources[key] = r.ID
+	}
+	normalized := make([]string, 0, len(item.Resources))
+	for _, key := range item.Resources {
+		method, url, _ := strings.Cut(strings.TrimSpace(key), " ")
+		if url = strings.TrimSpace(url); url == "" {
+			return fmt.Errorf("角色 %s 关联的接口格式错误, 应为 \"METHOD url\": %s", item.Label, key)
+		}
+		normalized = append(normalized, strings.ToUpper(method)+" "+url)
+	}
+	wantResources, err := lookup("接口", normalized, resourceIds, stateResources)
+	if err != nil {
+		return err
+	}
+	var haveResources []int
+	err = im.tx.Model(&RoleResource{}).
+		Joins("JOIN resource ON resource.id = role_resource.resource_id").
+		Where("role_resource.role_id = ? AND resource.url <> ''", roleId).
+		Pluck("role_resource.resource_id", &haveResources).Error
+	if err != nil {
+		return err
+	}
+	for _, id := range haveResources {
+		if _, ok := wantResources[id]; ok {
+			delete(wantResources, id)
+			continue
+		}
+		if err := im.tx.Delete(&RoleResource{}, "role_id = ? AND resource_id = ?", roleId, id).Error; err != nil {
+			return err
+		}
+		im.change("role_resource", "delete", item.Label+" => "+im.resourceKeyById(id))
+	}
+	for _, id := range sortedIds(wantResources) {
+		if err := im.tx.Create(&RoleResource{RoleId: roleId, ResourceId: id}).Error; err != nil {
+			return err
+		}
+		im.change("role_resource", "create", item.Label+" => "+wantResources[id])
+	}
+
+	var haveScopes []RoleScope
+	if err := im.tx.Where("role_id = ?", roleId).Find(&haveScopes).Error; err != nil {
+		return err
+	}
+	for data, scope := range item.Scopes {
+		if !slices.Contains(DataTypes, data) || !policy.ValidScope(scope) {
+			return fmt.Errorf("角色 %s 的数据权限错误: %s => %s", item.Label, data, scope)
+		}
+	}
+	for _, s := range haveScopes {
+		if scope, ok := item.Scopes[s.Data]; ok && scope == s.Scope {
+			continue
+		}
+		if err := im.tx.Delete(&RoleScope{}, "role_id = ? AND data = ?", roleId, s.Data).Error; err != nil {
+			return err
+		}
+		if _, ok := item.Scopes[s.Data]; !ok {
+			im.change("role_scope", "delete", item.Label+" => "+s.Data)
+		}
+	}
+	have := make(map[string]string, len(haveScopes))
+	for _, s := range haveScopes {
+		have[s.Data] = s.Scope
+	}
+	for _, data := range DataTypes {
+		scope, ok := item.Scopes[data]
+		if !ok || have[data] == scope {
+			continue
+		}
+		if err := im.tx.Create(&RoleScope{RoleId: roleId, Data: data, Scope: scope}).Error; err != nil {
+			return err
+		}
+		action := "create"
+		if _, ok := have[data]; ok {
+			action = "update"
+		}
+		im.change("role_scope", action, item.Label+" => "+data+": "+scope)
+	}
+	return nil
+}
+
+// prune 删除文档中没有的菜单, 资源, 角色以及它们的关联
+func (im *rbacImporter) prune(menuIds, resourceIds, roleIds map[string]int) error {
+	for _, key := range sortedKeys(im.state.menus) {
+		m := im.state.menus[key]
+		if _, ok := menuIds[key]; ok {
+			continue
+		}
+		if err := im.tx.Delete(&RoleMenu{}, "menu_id = ?", m.ID).Error; err != nil {
+			return err
+		}
+		if err := im.tx.Delete(&Menu{}, m.ID).Error; err != nil {
+			return err
+		}
+		im.change("menu", "delete", key)
+	}
+	for _, key := range sortedKeys(im.state.resources) {
+		r := im.state.resources[key]
+		if _, ok := resourceIds[key]; ok {
+			continue
+		}
+		if err := im.tx.Delete(&RoleResource{}, "resource_id = ?", r.ID).Error; err != nil {
+			return err
+		}
+		if err := im.tx.Delete(&Resource{}, r.ID).Error; err != nil {
+			return err
+		}
+		im.change("resource", "delete", key)
+	}
+
+	var ids []int
+	for _, label := range sortedKeys(im.state.roles) {
+		if _, ok := roleIds[label]; !ok {
+			ids = append(ids, im.state.roles[label].ID)
+			im.change("role", "delete", label)
+		}
+	}
+	if len(ids) > 0 {
+		return DeleteRoles(im.tx, ids)
+	}
+	return nil
+}
+
+func (im *rbacImporter) menuKeyById(id int, ids map[string]int) string {
+	if key, ok := im.state.menuKeys[id]; ok {
+		return key
+	}
+	for key, v := range ids {
+		if v == id {
+			return key
+		}
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+func (im *rbacImporter) resourceKeyById(id int) string {
+	if key, ok := im.state.resKeys[id]; ok {
+		return key
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+// changedFields 按照 "字段, 是否相同" 成对传入, 返回不同的字段
+func changedFields(pairs ...any) []string {
+	var fields []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if !pairs[i+1].(bool) {
+			fields = append(fields, pairs[i].(string))
+		}
+	}
+	return fields
+}
+
+func sortedIds(m map[int]string) []int {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
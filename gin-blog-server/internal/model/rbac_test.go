@@ -0,0 +1,180 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"testing"
+)
+
+const testRBAC = `
+menus:
+  - path: /home
+    name: 首页
+    component: /home
+    order_num: 1
+  - path: /auth
+    name: 权限管理
+    component: Layout
+    order_num: 2
+    children:
+      - path: role
+        name: 角色管理
+        component: /auth/role
+        order_num: 1
+resources:
+  - name: 角色模块
+    children:
+      - name: 角色列表
+        method: GET
+        url: /role/list
+      - name: 新增角色
+        method: POST
+        url: /role
+roles:
+  - label: admin
+    name: 管理员
+    menus: [/auth, /auth/role, /home]
+    resources: [GET /role/list, POST /role]
+  - label: editor
+    name: 编辑
+    parent: admin
+    menus: [/home]
+    scopes:
+      article: own
+  - label: guest
+    name: 游客
+    parent: editor
+    resources: [GET /role/list]
+`
+
+func parseTestRBAC(t *testing.T, data string) *RBACDocument {
+	doc, err := ParseRBAC([]byte(data))
+	assert.Nil(t, err)
+	return doc
+}
+
+// countRBAC 菜单, 资源, 角色, 角色-菜单, 角色-资源, 角色数据权限 的数量
+func countRBAC(t *testing.T, db *gorm.DB) []int64 {
+	models := []any{&Menu{}, &Resource{}, &Role{}, &RoleMenu{}, &RoleResource{}, &RoleScope{}}
+	counts := make([]int64, len(models))
+	for i, m := range models {
+		assert.Nil(t, db.Model(m).Count(&counts[i]).Error)
+	}
+	return counts
+}
+
+func TestImportRBAC(t *testing.T) {
+	db := newTestDB(t)
+	doc := parseTestRBAC(t, testRBAC)
+
+	result, err := ImportRBAC(db, doc, false, false)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, result.Changes)
+	// 2 个模块关联不导出, 只有接口: admin 2 个, guest 1 个
+	assert.Equal(t, []int64{3, 3, 3, 4, 3, 1}, countRBAC(t, db))
+
+	// 再次导入同一份配置没有任何变化
+	result, err = ImportRBAC(db, doc, false, false)
+	assert.Nil(t, err)
+	assert.Empty(t, result.Changes)
+
+	// 导出后再导入同样没有变化
+	exported, err := ExportRBAC(db)
+	assert.Nil(t, err)
+	result, err = ImportRBAC(db, exported, false, true)
+	assert.Nil(t, err)
+	assert.Empty(t, result.Changes)
+}
+
+func TestImportRBACDryRun(t *testing.T) {
+	db := newTestDB(t)
+	doc := parseTestRBAC(t, testRBAC)
+
+	result, err := ImportRBAC(db, doc, true, false)
+	assert.Nil(t, err)
+	assert.True(t, result.DryRun)
+	assert.Contains(t, result.Changes, RBACChange{Type: "role", Action: "create", Key: "admin"})
+	assert.Equal(t, []int64{0, 0, 0, 0, 0, 0}, countRBAC(t, db))
+
+	// dry run 的变化与实际导入一致
+	applied, err := ImportRBAC(db, doc, false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, result.Changes, applied.Changes)
+
+	// 已导入后 dry run 修改和删除同样不影响数据库
+	before := countRBAC(t, db)
+	doc.Roles = doc.Roles[:1]
+	doc.Roles[0].Name = "超级管理员"
+	result, err = ImportRBAC(db, doc, true, true)
+	assert.Nil(t, err)
+	assert.Contains(t, result.Changes, RBACChange{Type: "role", Action: "update", Key: "admin", Fields: []string{"name"}})
+	assert.Contains(t, result.Changes, RBACChange{Type: "role", Action: "delete", Key: "guest"})
+	assert.Equal(t, before, countRBAC(t, db))
+	var role Role
+	assert.Nil(t, db.Where("label = ?", "admin").First(&role).Error)
+	assert.Equal(t, "管理员", role.Name)
+}
+
+func TestImportRBACPrune(t *testing.T) {
+	db := newTestDB(t)
+	_, err := ImportRBAC(db, parseTestRBAC(t, testRBAC), false, false)
+	assert.Nil(t, err)
+
+	// 文档中没有 editor 和 /auth 菜单, guest 仍然声明继承数据库中的 editor
+	doc := parseTestRBAC(t, testRBAC)
+	doc.Roles = []RBACRole{doc.Roles[0], doc.Roles[2]}
+	doc.Menus = doc.Menus[:1]
+	doc.Roles[0].Menus = []string{"/home"}
+
+	// 不删除时文档中没有的数据保持不变
+	result, err := ImportRBAC(db, doc, false, false)
+	assert.Nil(t, err)
+	assert.NotContains(t, result.Changes, RBACChange{Type: "role", Action: "delete", Key: "editor"})
+	assert.Equal(t, int64(3), countRBAC(t, db)[2])
+
+	result, err = ImportRBAC(db, doc, false, true)
+	assert.Nil(t, err)
+	assert.Contains(t, result.Changes, RBACChange{Type: "role", Action: "delete", Key: "editor"})
+	assert.Contains(t, result.Changes, RBACChange{Type: "menu", Action: "delete", Key: "/auth"})
+	assert.Contains(t, result.Changes, RBACChange{Type: "menu", Action: "delete", Key: "/auth/role"})
+	// 菜单 1 个, 角色 2 个, 角色-菜单 1 个 (admin), editor 的数据权限被删除
+	assert.Equal(t, []int64{1, 3, 2, 1, 3, 0}, countRBAC(t, db))
+
+	// 被删除角色的下级角色改为继承被删除角色的上级
+	var admin, guest Role
+	assert.Nil(t, db.Where("label = ?", "admin").First(&admin).Error)
+	assert.Nil(t, db.Where("label = ?", "guest").First(&guest).Error)
+	assert.Equal(t, admin.ID, guest.ParentId)
+}
+
+func TestImportRBACCycle(t *testing.T) {
+	db := newTestDB(t)
+
+	// 文档内形成循环
+	doc := parseTestRBAC(t, testRBAC)
+	doc.Roles[0].Parent = "guest"
+	_, err := ImportRBAC(db, doc, false, false)
+	assert.ErrorIs(t, err, ErrRoleCycle)
+	// 导入失败时回滚, 数据库不变
+	assert.Equal(t, []int64{0, 0, 0, 0, 0, 0}, countRBAC(t, db))
+
+	// 与数据库中已有的继承关系形成循环: 数据库中 guest => editor => admin
+	_, err = ImportRBAC(db, parseTestRBAC(t, testRBAC), false, false)
+	assert.Nil(t, err)
+	doc = parseTestRBAC(t, `
+roles:
+  - label: admin
+    name: 管理员
+    parent: guest
+`)
+	_, err = ImportRBAC(db, doc, false, false)
+	assert.ErrorIs(t, err, ErrRoleCycle)
+	var admin Role
+	assert.Nil(t, db.Where("label = ?", "admin").First(&admin).Error)
+	assert.Zero(t, admin.ParentId)
+
+	// 自己作为上级
+	doc.Roles[0].Parent = "admin"
+	_, err = ImportRBAC(db, doc, false, false)
+	assert.ErrorIs(t, err, ErrRoleCycle)
+}
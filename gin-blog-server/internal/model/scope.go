@@ -0,0 +1,106 @@
+package model
+
+import (
+	"errors"
+	"gin-blog-server/internal/utils/policy"
+	"gorm.io/gorm"
+)
+
+// 需要控制数据权限的数据类型
+const (
+	DATA_ARTICLE = "article"
+	DATA_COMMENT = "comment"
+	DATA_MESSAGE = "message"
+	DATA_PAGE    = "page"
+)
+
+var DataTypes = []string{DATA_ARTICLE, DATA_COMMENT, DATA_MESSAGE, DATA_PAGE}
+
+// ErrOutOfScope 操作的数据超出了数据权限范围
+var ErrOutOfScope = errors.New("没有操作该数据的权限")
+
+// RoleScope 角色的数据权限, 没有设置的数据类型默认为 all
+type RoleScope struct {
+	RoleId int    `json:"-" gorm:"primaryKey"`
+	Data   string `json:"data" gorm:"primaryKey;type:varchar(20)"` // 数据类型: article, comment, message, page
+	Scope  string `json:"scope" gorm:"type:varchar(10)"`           // 数据权限范围: all, own, none
+}
+
+// DataScope 当前用户对某类数据的权限范围
+// own 表示只能查看和管理 user_id 为自己 (user_auth_id) 的数据
+type DataScope struct {
+	Scope  string
+	UserId int
+}
+
+// ScopeAll 不限制数据权限, 用于前台等不需要数据权限的场景
+var ScopeAll = DataScope{Scope: policy.ScopeAll}
+
+// Filter 按照数据权限筛选数据, alias 为表的别名前缀, 例如 "article."
+func (s DataScope) Filter(alias string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch s.Scope {
+		case policy.ScopeAll:
+			return db
+		case policy.ScopeOwn:
+			return db.Where(alias+"user_id = ?", s.UserId)
+		default:
+			return db.Where("1 = 0")
+		}
+	}
+}
+
+// CanCreate 是否可以新增数据, 新增的数据属于自己, 只要有数据权限即可
+func (s DataScope) CanCreate() bool {
+	return s.Scope == policy.ScopeAll || s.Scope == policy.ScopeOwn
+}
+
+// checkScope 检查 ids 对应的数据是否都在数据权限范围内, 不存在的数据忽略
+func checkScope(db *gorm.DB, model any, scope DataScope, ids []int) error {
+	switch scope.Scope {
+	case policy.ScopeAll:
+		return nil
+	case policy.ScopeOwn:
+		var count int64
+		result := db.Model(model).Where("id IN ? AND user_id <> ?", ids, scope.UserId).Count(&count)
+		if result.Error != nil {
+			return result.Error
+		}
+		if count > 0 {
+			return ErrOutOfScope
+		}
+		return nil
+	default:
+		return ErrOutOfScope
+	}
+}
+
+// GetRoleScopes 获取所有启用的角色设置的数据权限
+func GetRoleScopes(db *gorm.DB) (list []RoleScope, err error) {
+	result := db.Model(&RoleScope{}).
+		Joins("JOIN role ON role.id = role_scope.role_id").
+		Where("role.is_disable = ?", false).
+		Find(&list)
+	return list, result.Error
+}
+
+// GetEnabledRoleIds 获取所有启用的角色 id
+func GetEnabledRoleIds(db *gorm.DB) (ids []int, err error) {
+	result := db.Model(&Role{}).Where("is_disable = ?", false).Pluck("id", &ids)
+	return ids, result.Error
+}
+
+// GetScopesByRoleId 获取角色的数据权限, 没有设置的数据类型为 all
+func GetScopesByRoleId(db *gorm.DB, roleId int) (map[string]string, error) {
+	var list []RoleScope
+	result := db.Where("role_id = ?", roleId).Find(&list)
+
+	scopes := make(map[string]string, len(DataTypes))
+	for _, data := range DataTypes {
+		scopes[data] = policy.ScopeAll
+	}
+	for _, s := range list {
+		scopes[s.Data] = s.Scope
+	}
+	return scopes, result.Error
+}
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"fmt"
+	"gin-blog-server/internal/utils/slug"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// slug 所属的数据类型
+const (
+	SLUG_ARTICLE  = "article"
+	SLUG_CATEGORY = "category"
+	SLUG_TAG      = "tag"
+)
+
+// 与前台文章静态路由冲突的 slug, 例如 /api/front/article/list
+var reservedSlugs = map[string]bool{
+	"list":    true,
+	"archive": true,
+	"search":  true,
+	"like":    true,
+}
+
+// SlugRedirect 旧 slug 到数据 id 的映射, 修改 slug 后旧链接仍然可以访问
+type SlugRedirect struct {
+	Model
+	Type     string `gorm:"type:varchar(20);uniqueIndex:idx_slug_redirect;not null" json:"type"`
+	OldSlug  string `gorm:"type:varchar(100);uniqueIndex:idx_slug_redirect;not null" json:"old_slug"`
+	TargetId int    `gorm:"index" json:"target_id"`
+}
+
+// slugModels slug 类型对应的数据表
+var slugModels = map[string]func() any{
+	SLUG_ARTICLE:  func() any { return &Article{} },
+	SLUG_CATEGORY: func() any { return &Category{} },
+	SLUG_TAG:      func() any { return &Tag{} },
+}
+
+// uniqueSlug 根据 source 生成 typ 类型下唯一的 slug, id 为当前数据的 id (新增时为 0)
+// slug 为空, 纯数字, 或与前台文章路由冲突时添加类型前缀; 与其他数据重复时添加数字后缀: go-2, go-3
+func uniqueSlug(db *gorm.DB, typ string, id int, source string) (string, error) {
+	base := slug.Make(source)
+	switch {
+	case base == "":
+		base = typ
+	case slug.IsNumeric(base), typ == SLUG_ARTICLE && reservedSlugs[base]:
+		base = typ + "-" + base
+	}
+
+	val := base
+	for i := 2; ; i++ {
+		var count int64
+		result := db.Model(slugModels[typ]()).Where("slug = ? AND id != ?", val, id).Count(&count)
+		if result.Error != nil {
+			return "", result.Error
+		}
+		if count == 0 {
+			return val, nil
+		}
+		val = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// saveSlugRedirect slug 从 oldSlug 修改为 newSlug 后, 记录 oldSlug => id 的重定向
+// newSlug 如果是其他数据的旧 slug, 删除对应的重定向, 以当前数据为准
+func saveSlugRedirect(db *gorm.DB, typ string, id int, oldSlug, newSlug string) error {
+	if oldSlug == newSlug {
+		return nil
+	}
+
+	result := db.Where("type = ? AND old_slug = ?", typ, newSlug).Delete(&SlugRedirect{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if oldSlug == "" {
+		return nil
+	}
+
+	redirect := SlugRedirect{Type: typ, OldSlug: oldSlug}
+	result = db.Where(redirect).Assign(SlugRedirect{TargetId: id}).FirstOrCreate(&redirect)
+	return result.Error
+}
+
+// currentSlug 获取数据当前的 slug, 新增数据 (id 为 0) 返回空字符串
+func currentSlug(db *gorm.DB, typ string, id int) (string, error) {
+	if id == 0 {
+		return "", nil
+	}
+	var slugs []string
+	result := db.Model(slugModels[typ]()).Where("id", id).Limit(1).Pluck("slug", &slugs)
+	if result.Error != nil || len(slugs) == 0 {
+		return "", result.Error
+	}
+	return slugs[0], nil
+}
+
+// ResolveSlug 根据 id 或 slug 查找数据的 id, slug 不存在时查找重定向表
+// 都找不到时返回 gorm.ErrRecordNotFound
+func ResolveSlug(db *gorm.DB, typ string, key string) (int, error) {
+	if id, err := strconv.Atoi(key); err == nil {
+		return id, nil
+	}
+
+	var ids []int
+	result := db.Model(slugModels[typ]()).Where("slug", key).Limit(1).Pluck("id", &ids)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(ids) > 0 {
+		return ids[0], nil
+	}
+
+	var redirect SlugRedirect
+	result = db.Where("type = ? AND old_slug = ?", typ, key).First(&redirect)
+	return redirect.TargetId, result.Error
+}
+
+// DeleteSlugRedirects 删除数据时, 删除指向这些数据的重定向
+func DeleteSlugRedirects(db *gorm.DB, typ string, ids []int) error {
+	return db.Where("type = ? AND target_id IN ?", typ, ids).Delete(&SlugRedirect{}).Error
+}
+
+// migrateSlug 为已有数据补充 slug
+// slug 字段有唯一索引, 需要在 AutoMigrate 创建索引之前先添加字段并填充数据
+func migrateSlug(db *gorm.DB) error {
+	for _, typ := range []string{SLUG_ARTICLE, SLUG_CATEGORY, SLUG_TAG} {
+		m := slugModels[typ]()
+		if !db.Migrator().HasTable(m) || db.Migrator().HasColumn(m, "slug") {
+			continue
+		}
+		if err := db.Migrator().AddColumn(m, "Slug"); err != nil {
+			return err
+		}
+
+		nameField := "name"
+		if typ == SLUG_ARTICLE {
+			nameField = "title"
+		}
+
+		var rows []struct {
+			ID   int
+			Name string
+		}
+		result := db.Model(m).Select("id", nameField+" AS name").Order("id").Find(&rows)
+		if result.Error != nil {
+			return result.Error
+		}
+		for _, row := range rows {
+			val, err := uniqueSlug(db, typ, row.ID, row.Name)
+			if err != nil {
+				return err
+			}
+			if err := db.Model(m).Where("id", row.ID).UpdateColumn("slug", val).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 type Tag struct {
 	Model
 	Name     string    `gorm:"unique;type:varchar(20);not null" json:"name"`
+	Slug     string    `gorm:"type:varchar(100);uniqueIndex" json:"slug"`
 	Articles []Article `gorm:"many2many:article_tag;" json:"articles,omitempty"`
 }
 
@@ -17,14 +18,21 @@ type TagVO struct {
 	UpdatedAt time.Time `json:"updated_at"`
 
 	Name         string `json:"name"`
+	Slug         string `json:"slug"`
 	ArticleCount int    `json:"article_count"`
 }
 
+// GetTagById 根据 id 获取标签
+func GetTagById(db *gorm.DB, id int) (tag *Tag, err error) {
+	result := db.First(&tag, id)
+	return tag, result.Error
+}
+
 // GetTagList 获取标签列表
 func GetTagList(db *gorm.DB, page, size int, keyword string) (list []TagVO, total int64, err error) {
 	db = db.Table("tag t").
 		Joins("LEFT JOIN article_tag at ON t.id = at.tag_id").
-		Select("t.id", "t.name", "COUNT(at.article_id) AS article_count", "t.created_at", "t.updated_at")
+		Select("t.id", "t.name", "t.slug", "COUNT(at.article_id) AS article_count", "t.created_at", "t.updated_at")
 
 	if keyword != "" {
 		db = db.Where("name LIKE ?", "%"+keyword+"%")
@@ -38,22 +46,72 @@ func GetTagList(db *gorm.DB, page, size int, keyword string) (list []TagVO, tota
 }
 
 // SaveOrUpdateTag 添加或者修改标签
-func SaveOrUpdateTag(db *gorm.DB, id int, name string) (*Tag, error) {
+// slugSource 为自定义的 slug, 为空时根据名称生成, slug 变化时记录旧 slug 的重定向
+func SaveOrUpdateTag(db *gorm.DB, id int, name, slugSource string) (*Tag, error) {
 	tag := Tag{
 		Model: Model{ID: id},
 		Name:  name,
 	}
 
-	var result *gorm.DB
-	if id > 0 {
-		result = db.Updates(&tag)
-	} else {
-		result = db.Create(&tag)
+	if slugSource == "" {
+		slugSource = name
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		oldSlug, err := currentSlug(tx, SLUG_TAG, id)
+		if err != nil {
+			return err
+		}
+		tag.Slug, err = uniqueSlug(tx, SLUG_TAG, id, slugSource)
+		if err != nil {
+			return err
+		}
+
+		var result *gorm.DB
+		if id > 0 {
+			result = tx.Updates(&tag)
+		} else {
+			result = tx.Create(&tag)
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return saveSlugRedirect(tx, SLUG_TAG, tag.ID, oldSlug, tag.Slug)
+	})
+
+	return &tag, err
+}
+
+// firstOrCreateTag 根据名称获取标签, 不存在则创建
+func firstOrCreateTag(db *gorm.DB, name string) (*Tag, error) {
+	var tag Tag
+	result := db.Where("name", name).Limit(1).Find(&tag)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return &tag, result.Error
 	}
 
+	slug, err := uniqueSlug(db, SLUG_TAG, 0, name)
+	if err != nil {
+		return nil, err
+	}
+	tag = Tag{Name: name, Slug: slug}
+	result = db.Create(&tag)
 	return &tag, result.Error
 }
 
+// DeleteTag 删除标签（批量）
+func DeleteTag(db *gorm.DB, ids []int) (int64, error) {
+	result := db.Where("id IN ?", ids).Delete(Tag{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if err := DeleteSlugRedirects(db, SLUG_TAG, ids); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
 // GetTagOption 获取标签选项列表
 func GetTagOption(db *gorm.DB) ([]OptionVO, error) {
 	list := make([]OptionVO, 0)
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// RecoveryCode 两步验证的恢复码, 只保存摘要, 每个恢复码只能使用一次
+type RecoveryCode struct {
+	Model
+	UserAuthId int        `gorm:"index" json:"-"`
+	CodeHash   string     `gorm:"type:varchar(64)" json:"-"`
+	UsedAt     *time.Time `json:"used_at"`
+}
+
+// IsTwoFactorRequired 用户是否必须开启两步验证: 当前有效的角色或者其上级角色中有要求两步验证的角色
+func IsTwoFactorRequired(db *gorm.DB, userAuthId int) (bool, error) {
+	roleIds, err := GetRoleIdsByUserId(db, userAuthId)
+	if err != nil || len(roleIds) == 0 {
+		return false, err
+	}
+	roleIds, err = GetEffectiveRoleIds(db, roleIds)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	result := db.Model(&Role{}).
+		Where("id IN ? AND require_2fa = ? AND is_disable = ?", roleIds, true, false).
+		Count(&count)
+	return count > 0, result.Error
+}
+
+// EnableTwoFactor 开启两步验证, 保存 TOTP 密钥并替换所有恢复码
+func EnableTwoFactor(db *gorm.DB, userAuthId int, secret string, codeHashes []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&UserAuth{}).Where("id = ?", userAuthId).
+			Updates(map[string]any{"totp_secret": secret, "totp_enabled": true})
+		if result.Error != nil {
+			return result.Error
+		}
+		return replaceRecoveryCodes(tx, userAuthId, codeHashes)
+	})
+}
+
+// DisableTwoFactor 关闭两步验证, 删除 TOTP 密钥和所有恢复码
+func DisableTwoFactor(db *gorm.DB, userAuthId int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&UserAuth{}).Where("id = ?", userAuthId).
+			Updates(map[string]any{"totp_secret": "", "totp_enabled": false})
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Where("user_auth_id = ?", userAuthId).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// ReplaceRecoveryCodes 重新生成恢复码, 之前的恢复码全部失效
+func ReplaceRecoveryCodes(db *gorm.DB, userAuthId int, codeHashes []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userAuthId, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userAuthId int, codeHashes []string) error {
+	if err := tx.Where("user_auth_id = ?", userAuthId).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = RecoveryCode{UserAuthId: userAuthId, CodeHash: hash}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode 使用恢复码, 恢复码不存在或者已经使用时返回 false
+func UseRecoveryCode(db *gorm.DB, userAuthId int, codeHash string) (bool, error) {
+	result := db.Model(&RecoveryCode{}).
+		Where("user_auth_id = ? AND code_hash = ? AND used_at IS NULL", userAuthId, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes 未使用的恢复码数量
+func CountRecoveryCodes(db *gorm.DB, userAuthId int) (int64, error) {
+	var count int64
+	result := db.Model(&RecoveryCode{}).
+		Where("user_auth_id = ? AND used_at IS NULL", userAuthId).
+		Count(&count)
+	return count, result.Error
+}
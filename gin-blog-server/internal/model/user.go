@@ -101,6 +101,7 @@ func GetUserList(db *gorm.DB, page, size int, loginType int8, nickname, username
 		Where("user_info.nickname LIKE ?", "%"+nickname+"%").
 		Preload("UserInfo").
 		Preload("Roles").
+		Preload("Grants").
 		Count(&total).
 		Scopes(Paginate(page, size)).
 		Find(&list)
@@ -108,8 +109,8 @@ func GetUserList(db *gorm.DB, page, size int, loginType int8, nickname, username
 	return list, total, result.Error
 }
 
-// UpdateUserNicknameAndRole 更新用户昵称及角色信息
-func UpdateUserNicknameAndRole(db *gorm.DB, authId int, nickname string, roleIds []int) error {
+// UpdateUserNicknameAndRole 更新用户昵称及角色信息, grants 中的 UserAuthId 不需要设置
+func UpdateUserNicknameAndRole(db *gorm.DB, authId int, nickname string, grants []UserAuthRole) error {
 	userAuth, err := GetUserAuthInfoById(db, authId)
 	if err != nil {
 		return err
@@ -127,25 +128,32 @@ func UpdateUserNicknameAndRole(db *gorm.DB, authId int, nickname string, roleIds
 	}
 
 	// 判断，至少有一个角色，一个用户也可以有多个角色
-	if len(roleIds) == 0 {
+	if len(grants) == 0 {
 		return nil
 	}
 
 	// 更新用户角色, 清空原本的 user_role 关系, 添加新的关系
-	result = db.Where(UserAuthRole{UserAuthId: userAuth.ID}).Delete(UserAuthRole{})
-	if result.Error != nil {
-		return result.Error
-	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(UserAuthRole{UserAuthId: userAuth.ID}).Delete(UserAuthRole{}).Error; err != nil {
+			return err
+		}
+
+		userRoles := make([]UserAuthRole, 0, len(grants))
+		for _, grant := range grants {
+			grant.UserAuthId = userAuth.ID
+			userRoles = append(userRoles, grant)
+		}
+		return tx.Create(&userRoles).Error
+	})
+}
 
-	var userRoles []UserAuthRole
-	for _, id := range roleIds {
-		userRoles = append(userRoles, UserAuthRole{
-			RoleId:     id,
-			UserAuthId: userAuth.ID,
-		})
-	}
-	result = db.Create(&userRoles)
-	return result.Error
+// GetExpiredGrantUserIds 获取在 (from, to] 时间段内有角色授权到期的用户 id
+func GetExpiredGrantUserIds(db *gorm.DB, from, to time.Time) (ids []int, err error) {
+	result := db.Model(&UserAuthRole{}).
+		Where("valid_until > ? AND valid_until <= ?", from, to).
+		Distinct().
+		Pluck("user_auth_id", &ids)
+	return ids, result.Error
 }
 
 // UpdateUserDisable 更新用户的禁用信息
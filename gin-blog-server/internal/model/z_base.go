@@ -16,23 +16,34 @@ func MakeMigrate(db *gorm.DB) error {
 	db.SetupJoinTable(&Role{}, "Resources", &RoleResource{})
 	db.SetupJoinTable(&Role{}, "Users", &UserAuthRole{})
 
+	// 已有数据需要先补充 slug, 再创建唯一索引
+	if err := migrateSlug(db); err != nil {
+		return err
+	}
+
 	return db.AutoMigrate(
-		&Article{},      // 文章
-		&Category{},     // 分类
-		&Tag{},          // 标签
-		&Comment{},      // 评论
-		&Message{},      // 消息
-		&FriendLink{},   // 友链
-		&Page{},         // 页面
-		&Config{},       // 网站设置
-		&OperationLog{}, // 操作日志
-		&UserInfo{},     // 用户信息
+		&Article{},         // 文章
+		&ArticleRevision{}, // 文章版本
+		&Category{},        // 分类
+		&Tag{},             // 标签
+		&Comment{},         // 评论
+		&Message{},         // 消息
+		&FriendLink{},      // 友链
+		&Page{},            // 页面
+		&Config{},          // 网站设置
+		&OperationLog{},    // 操作日志
+		&UserInfo{},        // 用户信息
+		&SlugRedirect{},    // slug 重定向
 
 		&UserAuth{},     // 用户验证
 		&Role{},         // 角色
 		&Menu{},         // 菜单
 		&Resource{},     // 资源（接口）
 		&UserAuthRole{}, // 用户-角色 关联
+		&RoleScope{},    // 角色数据权限
+		&JWTKey{},       // JWT 签名密钥
+		&RecoveryCode{}, // 两步验证恢复码
+		&UserOauth{},    // 第三方账号绑定
 	)
 }
 
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"path/filepath"
+	"testing"
+)
+
+// newTestDB 创建临时的 sqlite 数据库并完成迁移, 配置与 internal/helper.go 中一致
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+		SkipDefaultTransaction:                   true,
+		NamingStrategy:                           schema.NamingStrategy{SingularTable: true},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, MakeMigrate(db))
+	return db
+}
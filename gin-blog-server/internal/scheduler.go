@@ -0,0 +1,122 @@
+package ginblog
+
+import (
+	"context"
+	"errors"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/handle"
+	"gin-blog-server/internal/model"
+	"gin-blog-server/internal/utils/jwt"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"log/slog"
+	"time"
+)
+
+// 后台定时任务的执行间隔
+const scheduleInterval = time.Minute
+
+// scheduledJob 后台定时任务, now 为本次执行的时间
+type scheduledJob func(db *gorm.DB, rdb *redis.Client, now time.Time) error
+
+// 需要定时执行的任务
+var scheduledJobs = map[string]scheduledJob{
+	"article_schedule":  runArticleSchedule,
+	"role_grant_expire": runRoleGrantExpire,
+	"jwt_key_rotate":    runJWTKeyRotate,
+}
+
+// StartScheduler 在后台启动定时任务, 启动时立即执行一次, ctx 结束时停止
+func StartScheduler(ctx context.Context, db *gorm.DB, rdb *redis.Client) {
+	go func() {
+		ticker := time.NewTicker(scheduleInterval)
+		defer ticker.Stop()
+
+		for {
+			runScheduledJobs(db, rdb, time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func runScheduledJobs(db *gorm.DB, rdb *redis.Client, now time.Time) {
+	for name, job := range scheduledJobs {
+		if err := job(db, rdb, now); err != nil {
+			slog.Error("定时任务执行失败", "job", name, "err", err)
+		}
+	}
+}
+
+// runArticleSchedule 文章的定时 发布/下线, 有文章状态变化时删除文章相关缓存
+func runArticleSchedule(db *gorm.DB, rdb *redis.Client, now time.Time) error {
+	published, err := model.PublishScheduledArticles(db, now)
+	if err != nil {
+		return err
+	}
+	unpublished, err := model.UnpublishExpiredArticles(db, now)
+	if err != nil {
+		return err
+	}
+
+	if published+unpublished == 0 {
+		return nil
+	}
+	slog.Info("文章定时发布/下线", "published", published, "unpublished", unpublished)
+	return handle.RemoveArticleCache(rdb)
+}
+
+// runRoleGrantExpire 强制角色授权到期的用户下线, 用户重新登录后按照剩余的角色鉴权
+// 上次检查的时间保存在 Redis 中, 服务停止期间到期的授权在重启后同样会强制下线
+func runRoleGrantExpire(db *gorm.DB, rdb *redis.Client, now time.Time) error {
+	ctx := context.Background()
+
+	// 没有检查记录时, 检查所有已经到期的授权
+	var from time.Time
+	checked, err := rdb.Get(ctx, global.ROLE_GRANT_CHECKED).Int64()
+	switch {
+	case err == nil:
+		from = time.UnixMilli(checked)
+	case !errors.Is(err, redis.Nil):
+		return err
+	}
+
+	ids, err := model.GetExpiredGrantUserIds(db, from, now)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		slog.Info("角色授权到期, 强制用户下线", "users", ids)
+		if err := handle.ForceOfflineUsers(rdb, ids, "角色授权到期"); err != nil {
+			return err
+		}
+	}
+
+	return rdb.Set(ctx, global.ROLE_GRANT_CHECKED, now.UnixMilli(), 0).Err()
+}
+
+// runJWTKeyRotate 删除过期的签名密钥, 当前密钥使用时间超过 JWT.RotateInterval 时轮换
+func runJWTKeyRotate(db *gorm.DB, rdb *redis.Client, now time.Time) error {
+	conf := global.GetConfig()
+	if conf.SigningAlg() == jwt.AlgHS256 {
+		return nil
+	}
+
+	deleted, err := model.DeleteExpiredJWTKeys(db, now)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		slog.Info("删除过期的 JWT 签名密钥", "count", deleted)
+	}
+
+	if conf.JWT.RotateInterval <= 0 {
+		return nil
+	}
+	_, err = handle.RotateSigningKey(db, time.Duration(conf.JWT.RotateInterval)*time.Hour)
+	return err
+}
@@ -0,0 +1,72 @@
+package ginblog
+
+import (
+	"context"
+	"gin-blog-server/internal/global"
+	"gin-blog-server/internal/model"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 服务停止期间到期的授权, 重启后同样强制下线, 已经检查过的授权不再重复下线
+func TestRunRoleGrantExpire(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+		SkipDefaultTransaction:                   true,
+		NamingStrategy:                           schema.NamingStrategy{SingularTable: true},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, model.MakeMigrate(db))
+
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
+		t.Skipf("无法连接 Redis %s: %v", addr, err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	offline := func(uid int) bool {
+		return rdb.Exists(ctx, global.OFFLINE_USER+strconv.Itoa(uid)).Val() == 1
+	}
+	cleanup := func() {
+		rdb.Del(ctx, global.ROLE_GRANT_CHECKED, global.OFFLINE_USER+"1", global.OFFLINE_USER+"2")
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	now := time.Now()
+	longAgo := now.Add(-24 * time.Hour)
+	soon := now.Add(time.Hour)
+	assert.Nil(t, db.Create(&model.UserAuthRole{UserAuthId: 1, RoleId: 2, ValidUntil: &longAgo}).Error)
+	assert.Nil(t, db.Create(&model.UserAuthRole{UserAuthId: 2, RoleId: 2, ValidUntil: &soon}).Error)
+
+	// 第一次执行时检查所有已经到期的授权
+	assert.Nil(t, runRoleGrantExpire(db, rdb, now))
+	assert.True(t, offline(1))
+	assert.False(t, offline(2))
+	checked, err := rdb.Get(ctx, global.ROLE_GRANT_CHECKED).Int64()
+	assert.Nil(t, err)
+	assert.Equal(t, now.UnixMilli(), checked)
+
+	// 重启后从上次检查的时间继续, 不重复下线
+	rdb.Del(ctx, global.OFFLINE_USER+"1")
+	later := now.Add(2 * time.Hour)
+	assert.Nil(t, runRoleGrantExpire(db, rdb, later))
+	assert.False(t, offline(1))
+	assert.True(t, offline(2))
+}
@@ -0,0 +1,46 @@
+package captcha
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code := Generate(Length)
+		assert.Len(t, code, Length)
+		for _, c := range code {
+			assert.True(t, strings.ContainsRune(Alphabet, c), code)
+		}
+	}
+}
+
+// 字符集中的每个字符都有对应的点阵
+func TestGlyphs(t *testing.T) {
+	for i := 0; i < len(Alphabet); i++ {
+		_, ok := glyphs[Alphabet[i]]
+		assert.True(t, ok, string(Alphabet[i]))
+	}
+}
+
+func TestVerify(t *testing.T) {
+	assert.True(t, Verify("A2B3", "A2B3"))
+	assert.True(t, Verify("A2B3", " a2b3 "))
+	assert.False(t, Verify("A2B3", "A2B4"))
+	assert.False(t, Verify("", ""))
+}
+
+func TestDraw(t *testing.T) {
+	code, data, err := New(Length)
+	assert.Nil(t, err)
+	assert.Len(t, code, Length)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	assert.Nil(t, err)
+	w, h := Size(Length)
+	assert.Equal(t, w, img.Bounds().Dx())
+	assert.Equal(t, h, img.Bounds().Dy())
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"github.com/pmezard/go-difflib/difflib"
+	"strings"
+)
+
+// 行差异的类型
+const (
+	DiffEqual  = "equal"  // 未改变
+	DiffInsert = "insert" // 新增
+	DiffDelete = "delete" // 删除
+)
+
+// DiffLine 行差异, OldLine/NewLine 为该行在旧/新文本中的行号 (从 1 开始, 不存在时为 0)
+type DiffLine struct {
+	Type    string `json:"type"`
+	OldLine int    `json:"old_line"`
+	NewLine int    `json:"new_line"`
+	Content string `json:"content"`
+}
+
+// LineDiff 按行比较两段文本, 返回逐行的差异, 修改的行表示为 删除 + 新增
+func LineDiff(oldText, newText string) []DiffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	matcher := difflib.NewMatcher(a, b)
+	for _, op := range matcher.GetOpCodes() {
+		if op.Tag == 'e' {
+			for i, j := op.I1, op.J1; i < op.I2; i, j = i+1, j+1 {
+				lines = append(lines, DiffLine{Type: DiffEqual, OldLine: i + 1, NewLine: j + 1, Content: a[i]})
+			}
+			continue
+		}
+		// 'r' (替换) 表示为 删除 + 新增
+		if op.Tag == 'd' || op.Tag == 'r' {
+			for i := op.I1; i < op.I2; i++ {
+				lines = append(lines, DiffLine{Type: DiffDelete, OldLine: i + 1, Content: a[i]})
+			}
+		}
+		if op.Tag == 'i' || op.Tag == 'r' {
+			for j := op.J1; j < op.J2; j++ {
+				lines = append(lines, DiffLine{Type: DiffInsert, NewLine: j + 1, Content: b[j]})
+			}
+		}
+	}
+	return lines
+}
+
+// UnifiedDiff 生成 unified 格式的差异文本 (同 diff -u), context 为上下文行数
+func UnifiedDiff(oldText, newText, oldName, newName string, context int) (string, error) {
+	// difflib.SplitLines 会在末尾多出一个空行, 这里自行拆分并补上换行符
+	withNewline := func(lines []string) []string {
+		for i := range lines {
+			lines[i] += "\n"
+		}
+		return lines
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        withNewline(splitLines(oldText)),
+		B:        withNewline(splitLines(newText)),
+		FromFile: oldName,
+		ToFile:   newName,
+		Context:  context,
+	})
+}
+
+// splitLines 拆分文本为行 (不包含换行符), 空文本返回空切片
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	lines := LineDiff("a\nb\nc\n", "a\nB\nc\nd\n")
+	assert.Equal(t, []DiffLine{
+		{Type: DiffEqual, OldLine: 1, NewLine: 1, Content: "a"},
+		{Type: DiffDelete, OldLine: 2, Content: "b"},
+		{Type: DiffInsert, NewLine: 2, Content: "B"},
+		{Type: DiffEqual, OldLine: 3, NewLine: 3, Content: "c"},
+		{Type: DiffInsert, NewLine: 4, Content: "d"},
+	}, lines)
+
+	assert.Empty(t, LineDiff("", ""))
+	assert.Equal(t, []DiffLine{{Type: DiffInsert, NewLine: 1, Content: "a"}}, LineDiff("", "a"))
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	diff, err := UnifiedDiff("a\nb\n", "a\nc\n", "v1", "v2", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, "--- v1\n+++ v2\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n", diff)
+}
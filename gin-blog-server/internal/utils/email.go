@@ -3,29 +3,28 @@ package utils
 import (
 	"bytes"
 	"crypto/tls"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"gin-blog-server/internal/global"
 	"github.com/k3a/html2text"
-	"github.com/thanhpk/randstr"
 	"github.com/vanng822/go-premailer/premailer"
 	"gopkg.in/gomail.v2"
 	"html/template"
-	"io/fs"
 	"log/slog"
+	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 // EmailData 注册的核心思想：
-// 1. 发送邮件的同时创建 code 存储在本地 redis 中
-// 2. 当用户点击验证链接时即向 sever 发出 code 数据，如果这个数据在 redis 中存在，则验证成功，否则失败
+// 1. 待验证的账号 (邮箱, 密码哈希) 保存在 redis 中, 发送的邮件中只包含随机生成的 token
+// 2. 当用户点击验证链接时即向 sever 发出 token，根据 token 找到待验证的账号，则验证成功，否则失败
 type EmailData struct {
 	URL      template.URL // 验证链接
 	UserName string       // 用户名即邮箱地址
 	Subject  string       // 邮箱主题
+	Template string       // 邮件模板文件名
 }
 
 // Format 将邮箱地址转换成小写，并去除空格
@@ -34,59 +33,28 @@ func Format(email string) string {
 	return strings.ToLower(strings.TrimSpace(email))
 }
 
-// GenEmailVerificationInfo 返回生成加密后的 base64 字符串
-func GenEmailVerificationInfo(email string, password string) string {
-	code := GetCode()
-	info := Encode(email + "|" + password + "|" + code)
-	return info
-}
-
-// ParseEmailVerificationInfo 返回解析base64字符串后的 邮箱地址和code
-func ParseEmailVerificationInfo(info string) (string, string, error) {
-	data, err := Decode(info)
-	if err != nil {
-		return "", "", err
-	}
-
-	str := strings.Split(data, "|")
-	if len(str) != 3 {
-		return "", "", errors.New("wrong verification info format")
-	}
-	return str[0], str[1], nil
-}
-
-// GetCode 生成随机字符串
-func GetCode() string {
-	code := randstr.String(24)
-	return code
-}
-
-// Encode 返回生成 base64 编码
-func Encode(s string) string {
-	data := base64.StdEncoding.EncodeToString([]byte(s))
-	return data
-}
-
-// Decode 返回解码 base64
-func Decode(s string) (string, error) {
-	data, err := base64.StdEncoding.DecodeString(s)
-	if err != nil {
-		return "", errors.New("email verify failed, decode error")
+// GetEmailData 生成邮件数据
+func GetEmailData(email string, token string) *EmailData {
+	return &EmailData{
+		URL:      template.URL(GetEmailVerifyURL(token)), // 验证链接
+		UserName: email,                                  // 用户邮箱地址
+		Subject:  "请完成账号注册",                              // 邮件主题
+		Template: "email-verify.tpl",                     // 邮件模板
 	}
-	return string(data), nil
 }
 
-// GetEmailData 生成邮件数据
-func GetEmailData(email string, info string) *EmailData {
+// GetPasswordResetEmailData 生成重置密码的邮件数据, link 为前台网站的重置密码页面
+func GetPasswordResetEmailData(email string, link string) *EmailData {
 	return &EmailData{
-		URL:      template.URL(GetEmailVerifyURL(info)), // 验证链接
-		UserName: email,                                 // 用户邮箱地址
-		Subject:  "请完成账号注册",                             // 邮件主题
+		URL:      template.URL(link),
+		UserName: email,
+		Subject:  "重置密码",
+		Template: "password-reset.tpl",
 	}
 }
 
 // GetEmailVerifyURL 生成验证链接
-func GetEmailVerifyURL(info string) string {
+func GetEmailVerifyURL(token string) string {
 	baseurl := global.GetConfig().Server.Port
 	if baseurl[0] == ':' {
 		baseurl = fmt.Sprintf("localhost%s", baseurl)
@@ -95,7 +63,7 @@ func GetEmailVerifyURL(info string) string {
 	// baseurl := "你的域名"   切记不需要加端口
 
 	// 点击该链接可以触发 api/email/verify -> 进一步将账号存储到对应数据库中，完成账号注册
-	return fmt.Sprintf("%s/api/email/verify?info=%s", baseurl, info)
+	return fmt.Sprintf("%s/api/email/verify?token=%s", baseurl, url.QueryEscape(token))
 }
 
 // SendEmail 发送邮件
@@ -114,16 +82,15 @@ func SendEmail(email string, data *EmailData) error {
 
 	var body bytes.Buffer
 	// 解析模版
-	template, err := ParseTemplateDir("./assets/templates")
+	template, err := ParseEmailTemplate("./assets/templates", data.Template)
 	if err != nil {
 		return errors.New("解析模版失败")
 	}
 	slog.Info("解析模版成功！")
 
-	fmt.Println("URL:", data.URL)
 	// 执行模版
 	// 把html数据存储在body中， 第二个参数是模板名称， 第三个参数是模板数据（把模板中的占位符换成data数据）
-	template.ExecuteTemplate(&body, "email-verify.tpl", &data)
+	template.ExecuteTemplate(&body, data.Template, &data)
 	//为了确保html文件在各个邮件客户端都能正常显示，把html转换成内联模式
 	htmlString := body.String()
 	prem, _ := premailer.NewPremailerFromString(htmlString, nil)
@@ -150,22 +117,15 @@ func SendEmail(email string, data *EmailData) error {
 	return nil
 }
 
-// ParseTemplateDir 解析模版目录
-func ParseTemplateDir(dir string) (*template.Template, error) {
-	var paths []string
-	// 遍历模版目录，将所有模版文件路径添加到 paths 中
-	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			paths = append(paths, path)
-		}
-		return nil
-	})
+// emailLayouts 所有邮件共用的布局模板
+var emailLayouts = []string{"base.tpl", "style.tpl"}
 
-	if err != nil {
-		return nil, err
+// ParseEmailTemplate 解析邮件模板 name 和共用的布局模板
+// 每个邮件模板都定义了 content, 需要分别解析, 避免互相覆盖
+func ParseEmailTemplate(dir string, name string) (*template.Template, error) {
+	paths := make([]string, 0, len(emailLayouts)+1)
+	for _, file := range append(emailLayouts, name) {
+		paths = append(paths, filepath.Join(dir, file))
 	}
 	return template.ParseFiles(paths...)
 }
@@ -1,9 +1,16 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
 )
 
 // BcryptHash 使用 bcrypt 算法将明文字符串进行哈希处理
@@ -58,3 +65,79 @@ func MD5(str string, b ...byte) string {
 	// 如果有附加字节，将它们添加到哈希计算中
 	return hex.EncodeToString(h.Sum(b))
 }
+
+// GenToken 生成随机的不透明 token (邮箱验证, 重置密码等), 返回 token 和它的摘要
+// token 只发给用户, 服务端只保存摘要, 通过摘要查找 token 对应的数据
+func GenToken() (token, hash string) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token)
+}
+
+// HashToken 返回 token 的摘要 (sha256)
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// 加密数据的前缀, 用于区分加密之前保存的明文数据
+const encryptedPrefix = "enc:v1:"
+
+// Encrypt 使用 AES-256-GCM 加密 plain, secret 经过 sha256 后作为密钥
+// 返回 "enc:v1:" + base64(nonce + 密文)
+func Encrypt(secret, plain string) (string, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的结果, 密钥错误或数据被篡改时返回错误
+func Decrypt(secret, data string) (string, error) {
+	if !IsEncrypted(data) {
+		return "", errors.New("数据未加密")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(data, encryptedPrefix))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("加密数据格式错误")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// IsEncrypted 判断 data 是否是 Encrypt 加密的数据
+func IsEncrypted(data string) bool {
+	return strings.HasPrefix(data, encryptedPrefix)
+}
+
+func newGCM(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, errors.New("加密密钥不能为空")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
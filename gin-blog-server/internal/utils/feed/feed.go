@@ -0,0 +1,259 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"time"
+)
+
+// 各格式的 Content-Type
+const (
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+	AtomContentType = "application/atom+xml; charset=utf-8"
+	JSONContentType = "application/feed+json; charset=utf-8"
+)
+
+// Feed 订阅源, 可以输出为 RSS 2.0, Atom 1.0 和 JSON Feed 1.1
+type Feed struct {
+	Title       string
+	Link        string // 网站地址
+	FeedLink    string // 订阅源自身的地址
+	Description string
+	Author      string
+	Language    string
+	Updated     time.Time
+	Items       []Item
+}
+
+// Item 订阅源中的一篇文章
+type Item struct {
+	Title      string
+	Link       string // 文章地址, 同时作为文章的唯一标识
+	Summary    string
+	Content    string // HTML 正文, 为空时只输出摘要
+	Image      string
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+}
+
+// cdata 以 CDATA 形式输出 HTML 正文
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+type rssFeed struct {
+	XMLName       xml.Name  `xml:"rss"`
+	Version       string    `xml:"version,attr"`
+	AtomNS        string    `xml:"xmlns:atom,attr"`
+	ContentNS     string    `xml:"xmlns:content,attr"`
+	DCNS          string    `xml:"xmlns:dc,attr"`
+	Title         string    `xml:"channel>title"`
+	Link          string    `xml:"channel>link"`
+	Description   string    `xml:"channel>description"`
+	Language      string    `xml:"channel>language,omitempty"`
+	LastBuildDate string    `xml:"channel>lastBuildDate"`
+	AtomLink      rssLink   `xml:"channel>atom:link"`
+	Items         []rssItem `xml:"channel>item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Guid        rssGuid  `xml:"guid"`
+	Description string   `xml:"description"`
+	Content     *cdata   `xml:"content:encoded,omitempty"`
+	Creator     string   `xml:"dc:creator,omitempty"` // RSS 的 author 要求是邮箱, 作者名使用 dc:creator
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+// RSS 输出 RSS 2.0 格式
+func (f *Feed) RSS() ([]byte, error) {
+	rss := rssFeed{
+		Version:       "2.0",
+		AtomNS:        "http://www.w3.org/2005/Atom",
+		ContentNS:     "http://purl.org/rss/1.0/modules/content/",
+		DCNS:          "http://purl.org/dc/elements/1.1/",
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		Language:      f.Language,
+		LastBuildDate: f.Updated.Format(time.RFC1123Z),
+		AtomLink:      rssLink{Href: f.FeedLink, Rel: "self", Type: "application/rss+xml"},
+	}
+	for _, item := range f.Items {
+		v := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Guid:        rssGuid{IsPermaLink: true, Value: item.Link},
+			Description: item.Summary,
+			Creator:     f.Author,
+			Categories:  item.Categories,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+		}
+		if item.Content != "" {
+			v.Content = &cdata{item.Content}
+		}
+		rss.Items = append(rss.Items, v)
+	}
+	return marshalXML(rss)
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string      `xml:"xml:lang,attr,omitempty"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Links    []atomLink  `xml:"link"`
+	Updated  string      `xml:"updated"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+// Atom 输出 Atom 1.0 格式
+func (f *Feed) Atom() ([]byte, error) {
+	atom := atomFeed{
+		Lang:     f.Language,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		ID:       f.FeedLink,
+		Links: []atomLink{
+			{Href: f.Link, Rel: "alternate"},
+			{Href: f.FeedLink, Rel: "self"},
+		},
+		Updated: f.Updated.Format(time.RFC3339),
+		Author:  atomAuthor{Name: f.Author},
+	}
+	for _, item := range f.Items {
+		v := atomEntry{
+			Title:     item.Title,
+			ID:        item.Link,
+			Link:      atomLink{Href: item.Link, Rel: "alternate"},
+			Published: item.Published.Format(time.RFC3339),
+			Updated:   item.Updated.Format(time.RFC3339),
+		}
+		if item.Summary != "" {
+			v.Summary = &atomText{Type: "text", Value: item.Summary}
+		}
+		if item.Content != "" {
+			v.Content = &atomText{Type: "html", Value: item.Content}
+		}
+		for _, category := range item.Categories {
+			v.Categories = append(v.Categories, atomCategory{Term: category})
+		}
+		atom.Entries = append(atom.Entries, v)
+	}
+	return marshalXML(atom)
+}
+
+type jsonFeed struct {
+	Version     string       `json:"version"`
+	Title       string       `json:"title"`
+	HomePageURL string       `json:"home_page_url"`
+	FeedURL     string       `json:"feed_url"`
+	Description string       `json:"description,omitempty"`
+	Language    string       `json:"language,omitempty"`
+	Authors     []jsonAuthor `json:"authors,omitempty"`
+	Items       []jsonItem   `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html,omitempty"`
+	ContentText   string   `json:"content_text,omitempty"`
+	Summary       string   `json:"summary,omitempty"`
+	Image         string   `json:"image,omitempty"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// JSON 输出 JSON Feed 1.1 格式
+func (f *Feed) JSON() ([]byte, error) {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedLink,
+		Description: f.Description,
+		Language:    f.Language,
+		Items:       make([]jsonItem, 0, len(f.Items)),
+	}
+	if f.Author != "" {
+		feed.Authors = []jsonAuthor{{Name: f.Author}}
+	}
+	for _, item := range f.Items {
+		v := jsonItem{
+			ID:            item.Link,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.Content,
+			Summary:       item.Summary,
+			Image:         item.Image,
+			DatePublished: item.Published.Format(time.RFC3339),
+			DateModified:  item.Updated.Format(time.RFC3339),
+			Tags:          item.Categories,
+		}
+		if v.ContentHTML == "" { // content_html 和 content_text 至少要有一个
+			v.ContentText = item.Summary
+		}
+		feed.Items = append(feed.Items, v)
+	}
+	return json.MarshalIndent(feed, "", "  ")
+}
+
+func marshalXML(v any) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
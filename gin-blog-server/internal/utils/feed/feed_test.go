@@ -0,0 +1,81 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	t1 := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	return &Feed{
+		Title:       "博客",
+		Link:        "https://blog.com",
+		FeedLink:    "https://blog.com/api/front/feed.xml",
+		Description: "介绍",
+		Author:      "阵雨",
+		Language:    "zh-CN",
+		Updated:     t1,
+		Items: []Item{
+			{
+				Title:      "Go & Gin",
+				Link:       "https://blog.com/article/go",
+				Summary:    "摘要",
+				Content:    "<p>正文</p>",
+				Categories: []string{"后端", "Go"},
+				Published:  t1,
+				Updated:    t1,
+			},
+			{
+				Title:     "私密文章",
+				Link:      "https://blog.com/article/secret",
+				Summary:   "摘要",
+				Published: t1,
+				Updated:   t1,
+			},
+		},
+	}
+}
+
+func TestRSS(t *testing.T) {
+	data, err := testFeed().RSS()
+	assert.Nil(t, err)
+	s := string(data)
+	assert.True(t, strings.HasPrefix(s, xml.Header))
+	assert.Contains(t, s, `<rss version="2.0"`)
+	assert.Contains(t, s, `<atom:link href="https://blog.com/api/front/feed.xml" rel="self" type="application/rss+xml"></atom:link>`)
+	assert.Contains(t, s, `<title>Go &amp; Gin</title>`)
+	assert.Contains(t, s, `<guid isPermaLink="true">https://blog.com/article/go</guid>`)
+	assert.Contains(t, s, `<content:encoded><![CDATA[<p>正文</p>]]></content:encoded>`)
+	assert.Contains(t, s, `<pubDate>Tue, 02 Jan 2024 15:04:05 +0000</pubDate>`)
+	assert.Equal(t, 1, strings.Count(s, "<content:encoded>"))
+	assert.Nil(t, xml.Unmarshal(data, new(any)))
+}
+
+func TestAtom(t *testing.T) {
+	data, err := testFeed().Atom()
+	assert.Nil(t, err)
+	s := string(data)
+	assert.Contains(t, s, `<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="zh-CN">`)
+	assert.Contains(t, s, `<updated>2024-01-02T15:04:05Z</updated>`)
+	assert.Contains(t, s, `<content type="html">&lt;p&gt;正文&lt;/p&gt;</content>`)
+	assert.Contains(t, s, `<category term="后端"></category>`)
+	assert.Nil(t, xml.Unmarshal(data, new(any)))
+}
+
+func TestJSON(t *testing.T) {
+	data, err := testFeed().JSON()
+	assert.Nil(t, err)
+
+	var v map[string]any
+	assert.Nil(t, json.Unmarshal(data, &v))
+	assert.Equal(t, "https://jsonfeed.org/version/1.1", v["version"])
+	items := v["items"].([]any)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "<p>正文</p>", items[0].(map[string]any)["content_html"])
+	assert.Equal(t, "摘要", items[1].(map[string]any)["content_text"])
+	assert.Equal(t, "2024-01-02T15:04:05Z", items[0].(map[string]any)["date_published"])
+}
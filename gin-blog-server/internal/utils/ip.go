@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net"
 	"strings"
+	"syscall"
 	"xojoc.pw/useragent"
 )
 
@@ -151,6 +152,29 @@ func (*ipUtil) GetUserAgent(c *gin.Context) *useragent.UserAgent {
 	return useragent.Parse(c.Request.UserAgent())
 }
 
+// 运营商级 NAT 地址 (RFC 6598), net.IP.IsPrivate 不包含这个范围
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublic 是否为公网地址: 排除环回, 内网, 链路本地, 组播和未指定地址
+func (*ipUtil) IsPublic(ip net.IP) bool {
+	return ip != nil && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// PublicDialControl 用作 net.Dialer.Control, 拒绝连接非公网地址 (防止 SSRF)
+// 在 DNS 解析之后, 建立连接之前检查, 重定向和 DNS rebinding 同样会被拦截
+func (i *ipUtil) PublicDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); !i.IsPublic(ip) {
+		return fmt.Errorf("禁止访问非公网地址: %s", host)
+	}
+	return nil
+}
+
 // externalIp 非 127.0.0.1 的局域网 IP
 // 返回:
 //   - net.IP：返回服务器的局域网 IP 地址（如：192.168.x.x）
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		assert.True(t, IP.IsPublic(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{
+		"127.0.0.1", "10.0.0.1", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1",
+		"0.0.0.0", "::1", "::", "fe80::1", "fc00::1", "::ffff:127.0.0.1", "224.0.0.1",
+	} {
+		assert.False(t, IP.IsPublic(net.ParseIP(ip)), ip)
+	}
+	assert.False(t, IP.IsPublic(nil))
+
+	assert.NotNil(t, IP.PublicDialControl("tcp", "127.0.0.1:80", nil))
+	assert.NotNil(t, IP.PublicDialControl("tcp", "[::1]:80", nil))
+	assert.Nil(t, IP.PublicDialControl("tcp", "8.8.8.8:443", nil))
+}
@@ -1,6 +1,10 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"github.com/golang-jwt/jwt/v4"
 	"time"
@@ -16,50 +20,79 @@ var (
 
 // MyClaims 自定义的 Claims 结构体，用于保存自定义的 payload 数据
 type MyClaims struct {
-	UserId               int   `json:"user_id"`  // 用户ID
-	RoleIds              []int `json:"role_ids"` // 用户角色ID列表
-	jwt.RegisteredClaims       // 内嵌 jwt.RegisteredClaims，包含 JWT 的标准注册字段（如过期时间、签发者等）
+	UserId               int    `json:"user_id"`       // 用户ID
+	RoleIds              []int  `json:"role_ids"`      // 用户角色ID列表
+	Family               string `json:"fid,omitempty"` // 令牌族: 同一次登录以及之后刷新得到的 Token 属于同一个令牌族, 用于整体吊销
+	jwt.RegisteredClaims        // 内嵌 jwt.RegisteredClaims，包含 JWT 的标准注册字段（如过期时间、签发者等）, ID (jti) 用于吊销单个 Token
 }
 
-// GenToken 生成一个新的 JWT Token
+// GenToken 生成一个新的 JWT Token (access token), 同时返回 Token 的 Claims
 // 参数解释：
-// secret：用于签名的密钥（通常是一个私钥或者密钥）
+// keys：签名密钥, 使用其中的当前密钥签名
 // issuer：签发者的标识
-// expireHour：Token 过期的小时数
+// expire：Token 的有效期
 // userId：用户ID
 // roleIds：用户的角色ID数组
-func GenToken(secret, issuer string, expireHour, userId int, roleIds []int) (string, error) {
+// family：Token 所属的令牌族
+func GenToken(keys *KeySet, issuer string, expire time.Duration, userId int, roleIds []int, family string) (string, *MyClaims, error) {
 	// 创建 MyClaims 实例，填充 JWT 的 Claims 数据
-	claims := MyClaims{
+	now := time.Now()
+	claims := &MyClaims{
 		UserId:  userId,  // 设置用户ID
 		RoleIds: roleIds, // 设置用户角色ID列表
+		Family:  family,  // 设置令牌族
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    issuer,                                                                    // 设置 Token 的签发者
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expireHour) * time.Hour)), // 设置 Token 的过期时间
-			IssuedAt:  jwt.NewNumericDate(time.Now()),                                            // 设置 Token 的签发时间
+			ID:        NewTokenId(),                        // 设置 Token 的唯一标识 jti
+			Issuer:    issuer,                              // 设置 Token 的签发者
+			ExpiresAt: jwt.NewNumericDate(now.Add(expire)), // 设置 Token 的过期时间
+			IssuedAt:  jwt.NewNumericDate(now),             // 设置 Token 的签发时间
 		},
 	}
 
-	// 使用 HS256 签名方法创建一个新的 JWT Token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	// 返回签名后的 token 字符串
-	return token.SignedString([]byte(secret)) // 使用 secret 对 Token 进行签名并返回
+	// 使用当前密钥的签名方法 (HS256, RS256, EdDSA) 签名, 非对称密钥在头部写入 kid
+	signed, err := keys.sign(claims)
+	return signed, claims, err
+}
+
+// NewTokenId 生成随机的 Token 标识, 用于 jti 和令牌族
+func NewTokenId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// GenRefreshToken 生成随机的 refresh token, refresh token 不是 JWT, 只保存在服务端
+func GenRefreshToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// HashToken refresh token 的摘要, 服务端只保存摘要
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // ParseToken 解析 JWT Token 并验证其合法性
 // 参数解释：
-// secret：用于验证签名的密钥（通常是一个私钥或公钥）
+// keys：验证签名时接受的密钥, 根据 token 头部的 kid 选择
 // token：要解析的 JWT Token 字符串
-func ParseToken(secret, token string) (*MyClaims, error) {
+// token 的签名密钥不在 keys 中时返回 ErrKeyNotFound, 调用方可以重新加载密钥后重试
+func ParseToken(keys *KeySet, token string) (*MyClaims, error) {
 	// 解析 Token，并将解析出来的 Claims 存入 MyClaims 结构体中
-	jwtToken, err := jwt.ParseWithClaims(token, &MyClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// 使用 secret 来验证 token 的签名
-		return []byte(secret), nil
-	})
+	jwtToken, err := jwt.ParseWithClaims(token, &MyClaims{}, keys.keyFunc)
 
 	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrKeyNotFound
+		}
 		// 错误类型判断
-		switch vError, ok := err.(jwt.ValidationError); ok {
+		var vError *jwt.ValidationError
+		if !errors.As(err, &vError) {
+			return nil, ErrTokenInvalid
+		}
+		switch {
 		case vError.Errors&jwt.ValidationErrorMalformed != 0:
 			// Token 格式错误
 			return nil, ErrTokenMalFormed
@@ -84,3 +117,46 @@ func ParseToken(secret, token string) (*MyClaims, error) {
 	// Token 无效，返回错误
 	return nil, ErrTokenInvalid
 }
+
+// ArticleClaims 私密文章的访问凭证, 只能用于访问指定的文章
+type ArticleClaims struct {
+	ArticleId int `json:"article_id"`
+	jwt.RegisteredClaims
+}
+
+// 文章访问凭证使用独立的签名密钥, 避免与登录 Token 混用
+func articleSecret(secret string) []byte {
+	return []byte(secret + ":article")
+}
+
+// GenArticleToken 生成私密文章的访问凭证, expire 为有效期
+func GenArticleToken(secret, issuer string, articleId int, expire time.Duration) (string, error) {
+	claims := ArticleClaims{
+		ArticleId: articleId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expire)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(articleSecret(secret))
+}
+
+// ParseArticleToken 校验私密文章的访问凭证, 凭证必须属于 articleId 对应的文章
+func ParseArticleToken(secret, token string, articleId int) error {
+	claims := &ArticleClaims{}
+	jwtToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return articleSecret(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return ErrTokenExpired
+		}
+		return ErrTokenInvalid
+	}
+	if !jwtToken.Valid || claims.ArticleId != articleId {
+		return ErrTokenInvalid
+	}
+	return nil
+}
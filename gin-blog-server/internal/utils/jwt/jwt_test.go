@@ -3,14 +3,15 @@ package jwt
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestGenAndParseToken(t *testing.T) {
-	secret := "secret"
+	secret := HMACKeySet("secret")
 	issuer := "issuer"
-	expire := 10
+	expire := 10 * time.Hour
 
-	token, err := GenToken(secret, issuer, expire, 1, []int{1, 2})
+	token, claims, err := GenToken(secret, issuer, expire, 1, []int{1, 2}, "family")
 	assert.Nil(t, err)
 	assert.NotEmpty(t, token)
 
@@ -18,11 +19,50 @@ func TestGenAndParseToken(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 1, mc.UserId)
 	assert.Len(t, mc.RoleIds, 2)
+	assert.Equal(t, "family", mc.Family)
+	assert.Equal(t, claims.ID, mc.ID)
+	assert.Len(t, mc.ID, 32)
+
+	// 每个 Token 的 jti 都不相同
+	_, other, _ := GenToken(secret, issuer, expire, 1, []int{1, 2}, "family")
+	assert.NotEqual(t, claims.ID, other.ID)
+
+	expired, _, _ := GenToken(secret, issuer, -time.Minute, 1, nil, "")
+	_, err = ParseToken(secret, expired)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
+
+func TestRefreshToken(t *testing.T) {
+	token := GenRefreshToken()
+	assert.Len(t, token, 43)
+	assert.NotEqual(t, token, GenRefreshToken())
+
+	assert.Equal(t, HashToken(token), HashToken(token))
+	assert.NotEqual(t, HashToken(token), HashToken(GenRefreshToken()))
+	assert.Len(t, HashToken(token), 64)
 }
 
 func TestParseTokenError(t *testing.T) {
 	tokenString := "tokenString"
 
-	_, err := ParseToken("secret", tokenString)
+	_, err := ParseToken(HMACKeySet("secret"), tokenString)
 	assert.ErrorIs(t, err, ErrTokenMalFormed)
 }
+
+func TestArticleToken(t *testing.T) {
+	token, err := GenArticleToken("secret", "issuer", 1, time.Hour)
+	assert.Nil(t, err)
+
+	assert.Nil(t, ParseArticleToken("secret", token, 1))
+	assert.ErrorIs(t, ParseArticleToken("secret", token, 2), ErrTokenInvalid)
+	assert.ErrorIs(t, ParseArticleToken("other", token, 1), ErrTokenInvalid)
+
+	// 文章访问凭证不能作为登录 Token 使用, 反之亦然
+	_, err = ParseToken(HMACKeySet("secret"), token)
+	assert.NotNil(t, err)
+	loginToken, _, _ := GenToken(HMACKeySet("secret"), "issuer", time.Hour, 1, []int{1}, "")
+	assert.ErrorIs(t, ParseArticleToken("secret", loginToken, 0), ErrTokenInvalid)
+
+	expired, _ := GenArticleToken("secret", "issuer", 1, -time.Minute)
+	assert.ErrorIs(t, ParseArticleToken("secret", expired, 1), ErrTokenExpired)
+}
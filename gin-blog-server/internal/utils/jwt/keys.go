@@ -0,0 +1,238 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// 签名算法
+const (
+	AlgHS256 = "HS256" // HMAC 共享密钥, 验证 token 需要持有密钥
+	AlgRS256 = "RS256" // RSA 私钥签名, 其他服务通过 JWKS 中的公钥验证
+	AlgEdDSA = "EdDSA" // Ed25519 私钥签名, 其他服务通过 JWKS 中的公钥验证
+)
+
+// rsaKeyBits 生成的 RSA 密钥长度
+const rsaKeyBits = 2048
+
+var (
+	ErrKeyNotFound  = errors.New("token 的签名密钥不存在，请重新登录")
+	ErrNoSigningKey = errors.New("没有可用的签名密钥")
+)
+
+// Key 签名密钥, HS256 为共享密钥, RS256/EdDSA 为私钥
+type Key struct {
+	Kid      string    // 密钥标识, 写入 token 头部的 kid
+	Alg      string    // 签名算法
+	NotAfter time.Time // 之后不再接受该密钥签名的 token, 为零值表示不限制
+
+	secret  []byte
+	private crypto.Signer
+}
+
+// GenerateKey 生成 RS256 或 EdDSA 签名密钥, kid 为随机生成
+func GenerateKey(alg string) (*Key, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("不支持生成 %s 签名密钥", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Key{Kid: NewTokenId(), Alg: alg, private: private}, nil
+}
+
+// ParseKey 解析 PKCS #8 PEM 格式的私钥, 私钥类型必须与签名算法一致
+func ParseKey(kid, alg, privatePEM string) (*Key, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, fmt.Errorf("密钥 %s 不是 PEM 格式", kid)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg == AlgRS256 {
+			return &Key{Kid: kid, Alg: alg, private: private}, nil
+		}
+	case ed25519.PrivateKey:
+		if alg == AlgEdDSA {
+			return &Key{Kid: kid, Alg: alg, private: private}, nil
+		}
+	}
+	return nil, fmt.Errorf("密钥 %s 的类型与签名算法 %s 不一致", kid, alg)
+}
+
+// MarshalPrivate 私钥的 PKCS #8 PEM 格式, 用于保存密钥
+func (k *Key) MarshalPrivate() (string, error) {
+	if k.private == nil {
+		return "", fmt.Errorf("密钥 %s 没有私钥", k.Kid)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	switch k.Alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+func (k *Key) signKey() any {
+	if k.private != nil {
+		return k.private
+	}
+	return k.secret
+}
+
+func (k *Key) verifyKey() any {
+	if k.private != nil {
+		return k.private.Public()
+	}
+	return k.secret
+}
+
+// KeySet 签名使用的密钥和验证时接受的所有密钥
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// NewKeySet 使用 active 签名, 接受 active 和 keys 签名的 token
+func NewKeySet(active *Key, keys ...*Key) *KeySet {
+	s := &KeySet{active: active, keys: make(map[string]*Key, len(keys)+1)}
+	for _, k := range append(keys, active) {
+		if k != nil {
+			s.keys[k.Kid] = k
+		}
+	}
+	return s
+}
+
+// HMACKeySet 使用共享密钥 (HS256) 签名和验证, token 头部没有 kid
+func HMACKeySet(secret string) *KeySet {
+	return NewKeySet(&Key{Alg: AlgHS256, secret: []byte(secret)})
+}
+
+// Active 当前用于签名的密钥
+func (s *KeySet) Active() *Key {
+	return s.active
+}
+
+// Has 是否接受 kid 对应的密钥签名的 token
+func (s *KeySet) Has(kid string) bool {
+	_, ok := s.keys[kid]
+	return ok
+}
+
+// sign 使用当前密钥签名
+func (s *KeySet) sign(claims jwt.Claims) (string, error) {
+	if s == nil || s.active == nil {
+		return "", ErrNoSigningKey
+	}
+	token := jwt.NewWithClaims(s.active.method(), claims)
+	if s.active.Kid != "" {
+		token.Header["kid"] = s.active.Kid
+	}
+	return token.SignedString(s.active.signKey())
+}
+
+// keyFunc 根据 token 头部的 kid 选择验证密钥, 签名算法必须与密钥一致, 防止算法混淆攻击
+func (s *KeySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if token.Method.Alg() != key.Alg {
+		return nil, fmt.Errorf("token 的签名算法 %s 与密钥 %s 不一致", token.Method.Alg(), kid)
+	}
+	if !key.NotAfter.IsZero() && time.Now().After(key.NotAfter) {
+		return nil, ErrKeyNotFound
+	}
+	return key.verifyKey(), nil
+}
+
+// JWK JSON Web Key (RFC 7517), 只包含公钥
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // OKP 曲线
+	X   string `json:"x,omitempty"`   // OKP 公钥
+}
+
+// JWKS JSON Web Key Set, 用于 /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 所有非对称密钥的公钥, 共享密钥不会公开
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	// 当前密钥在前
+	var activeKid string
+	if jwk, ok := s.active.jwk(); ok {
+		set.Keys = append(set.Keys, jwk)
+		activeKid = jwk.Kid
+	}
+	kids := make([]string, 0, len(s.keys))
+	for kid := range s.keys {
+		if kid != activeKid {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Strings(kids)
+	for _, kid := range kids {
+		if jwk, ok := s.keys[kid].jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func (k *Key) jwk() (JWK, bool) {
+	if k == nil || k.private == nil {
+		return JWK{}, false
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch public := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", Kid: k.Kid, Use: "sig", Alg: k.Alg,
+			N: b64(public.N.Bytes()), E: b64(big.NewInt(int64(public.E)).Bytes())}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: k.Kid, Use: "sig", Alg: k.Alg, Crv: "Ed25519", X: b64(public)}, true
+	}
+	return JWK{}, false
+}
@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAsymmetricToken(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		key, err := GenerateKey(alg)
+		assert.Nil(t, err)
+		keys := NewKeySet(key)
+
+		token, _, err := GenToken(keys, "issuer", time.Hour, 1, []int{1}, "")
+		assert.Nil(t, err)
+		mc, err := ParseToken(keys, token)
+		assert.Nil(t, err, alg)
+		assert.Equal(t, 1, mc.UserId)
+
+		// 其他密钥, 共享密钥都不能验证
+		other, _ := GenerateKey(alg)
+		_, err = ParseToken(NewKeySet(other), token)
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		_, err = ParseToken(HMACKeySet("secret"), token)
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, _ := GenerateKey(AlgRS256)
+	oldToken, _, _ := GenToken(NewKeySet(old), "issuer", time.Hour, 1, nil, "")
+
+	// 轮换后使用新密钥签名, 仍然接受旧密钥签名的 token
+	active, _ := GenerateKey(AlgEdDSA)
+	keys := NewKeySet(active, old)
+	assert.Equal(t, active, keys.Active())
+	assert.True(t, keys.Has(old.Kid))
+
+	_, err := ParseToken(keys, oldToken)
+	assert.Nil(t, err)
+	newToken, _, _ := GenToken(keys, "issuer", time.Hour, 1, nil, "")
+	_, err = ParseToken(keys, newToken)
+	assert.Nil(t, err)
+	_, err = ParseToken(NewKeySet(old), newToken)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 超过 NotAfter 后不再接受旧密钥
+	old.NotAfter = time.Now().Add(-time.Second)
+	_, err = ParseToken(keys, oldToken)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestParseKey(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		key, _ := GenerateKey(alg)
+		pem, err := key.MarshalPrivate()
+		assert.Nil(t, err)
+
+		parsed, err := ParseKey(key.Kid, alg, pem)
+		assert.Nil(t, err)
+		token, _, _ := GenToken(NewKeySet(key), "issuer", time.Hour, 1, nil, "")
+		_, err = ParseToken(NewKeySet(parsed), token)
+		assert.Nil(t, err)
+	}
+
+	key, _ := GenerateKey(AlgRS256)
+	pem, _ := key.MarshalPrivate()
+	_, err := ParseKey(key.Kid, AlgEdDSA, pem)
+	assert.NotNil(t, err)
+	_, err = ParseKey(key.Kid, AlgRS256, "not a pem")
+	assert.NotNil(t, err)
+	_, err = GenerateKey(AlgHS256)
+	assert.NotNil(t, err)
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, _ := GenerateKey(AlgRS256)
+	edKey, _ := GenerateKey(AlgEdDSA)
+
+	set := NewKeySet(edKey, rsaKey).JWKS()
+	assert.Len(t, set.Keys, 2)
+	assert.Equal(t, edKey.Kid, set.Keys[0].Kid)
+	assert.Equal(t, "OKP", set.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", set.Keys[0].Crv)
+	assert.Len(t, set.Keys[0].X, 43)
+	assert.Equal(t, "RSA", set.Keys[1].Kty)
+	assert.Equal(t, "AQAB", set.Keys[1].E)
+	assert.Equal(t, "sig", set.Keys[1].Use)
+
+	// 共享密钥不会公开
+	assert.Empty(t, HMACKeySet("secret").JWKS().Keys)
+}
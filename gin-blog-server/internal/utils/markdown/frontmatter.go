@@ -0,0 +1,180 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// front matter 的分隔符: YAML 使用 ---, TOML 使用 +++ (Hexo, Hugo 等静态博客通用格式)
+const (
+	yamlDelimiter = "---"
+	tomlDelimiter = "+++"
+)
+
+var ErrFrontMatterNotClosed = errors.New("front matter 缺少结束分隔符")
+
+// FrontMatter 解析后的 front matter 数据
+type FrontMatter map[string]any
+
+// ParseFrontMatter 拆分 Markdown 文本中的 front matter 和正文
+// 文本不以 --- 或 +++ 开头时, 认为没有 front matter, 返回空的 FrontMatter 和原文
+func ParseFrontMatter(content []byte) (FrontMatter, string, error) {
+	text := strings.TrimPrefix(string(content), "\uFEFF") // 去除 BOM
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	matter := make(FrontMatter)
+
+	var delimiter string
+	switch {
+	case strings.HasPrefix(text, yamlDelimiter+"\n"):
+		delimiter = yamlDelimiter
+	case strings.HasPrefix(text, tomlDelimiter+"\n"):
+		delimiter = tomlDelimiter
+	default:
+		return matter, text, nil
+	}
+
+	// 查找结束分隔符, 分隔符需要单独占一行
+	rest := text[len(delimiter)+1:]
+	var header, body string
+	if strings.HasPrefix(rest, delimiter+"\n") || rest == delimiter {
+		header, body = "", strings.TrimPrefix(rest, delimiter)
+	} else if end := strings.Index(rest, "\n"+delimiter+"\n"); end >= 0 {
+		header, body = rest[:end], rest[end+len(delimiter)+2:]
+	} else if strings.HasSuffix(rest, "\n"+delimiter) {
+		header, body = strings.TrimSuffix(rest, "\n"+delimiter), ""
+	} else {
+		return nil, "", ErrFrontMatterNotClosed
+	}
+
+	var err error
+	if delimiter == yamlDelimiter {
+		err = yaml.Unmarshal([]byte(header), &matter)
+	} else {
+		_, err = toml.Decode(header, &matter)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return matter, strings.TrimLeft(body, "\n"), nil
+}
+
+// String 按顺序查找第一个存在的 key, 返回其字符串值
+// 值为列表时取第一个元素 (例如 Hexo 的 categories)
+func (m FrontMatter) String(keys ...string) string {
+	for _, key := range keys {
+		val, ok := m[key]
+		if !ok || val == nil {
+			continue
+		}
+		if list, ok := val.([]any); ok {
+			if len(list) == 0 {
+				continue
+			}
+			val = list[0]
+		}
+		return strings.TrimSpace(fmt.Sprint(val))
+	}
+	return ""
+}
+
+// Strings 按顺序查找第一个存在的 key, 返回其字符串列表
+// 支持 YAML/TOML 列表, 以及逗号分隔的字符串: "Go, Gin"
+func (m FrontMatter) Strings(keys ...string) []string {
+	for _, key := range keys {
+		val, ok := m[key]
+		if !ok || val == nil {
+			continue
+		}
+
+		var items []string
+		switch v := val.(type) {
+		case []any:
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+		case string:
+			items = strings.Split(v, ",")
+		default:
+			items = []string{fmt.Sprint(v)}
+		}
+
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// Bool 获取 key 对应的布尔值, 支持 true/false 以及 "true"/"false" 字符串
+func (m FrontMatter) Bool(key string) (val bool, ok bool) {
+	switch v := m[key].(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return false, false
+}
+
+// 常见的 front matter 日期格式
+var timeLayouts = []string{
+	time.RFC3339,
+	time.DateTime,
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	time.DateOnly,
+	"2006/01/02",
+}
+
+// Time 按顺序查找第一个存在的 key, 解析为时间
+// YAML/TOML 中的日期可能已被解析为 time.Time, 也可能是字符串
+func (m FrontMatter) Time(keys ...string) (time.Time, bool) {
+	for _, key := range keys {
+		switch v := m[key].(type) {
+		case time.Time:
+			return v, true
+		case string:
+			for _, layout := range timeLayouts {
+				if t, err := time.ParseInLocation(layout, strings.TrimSpace(v), time.Local); err == nil {
+					return t, true
+				}
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// MarshalFrontMatter 将 front matter 数据序列化为 YAML, 并与正文拼接成完整的 Markdown 文本
+// 输出格式:
+//
+//	---
+//	title: xxx
+//	---
+//
+//	正文
+func MarshalFrontMatter(matter any, body string) ([]byte, error) {
+	data, err := yaml.Marshal(matter)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(yamlDelimiter + "\n")
+	buf.Write(data)
+	buf.WriteString(yamlDelimiter + "\n\n")
+	buf.WriteString(body)
+	return buf.Bytes(), nil
+}
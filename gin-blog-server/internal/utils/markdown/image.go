@@ -0,0 +1,51 @@
+package markdown
+
+import "regexp"
+
+var (
+	// Markdown 图片语法: ![alt](url "title")
+	mdImageRegexp = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^\s)>]+)>?(?:\s+["'][^"']*["'])?\s*\)`)
+	// HTML 图片标签: <img src="url">
+	htmlImageRegexp = regexp.MustCompile(`<img[^>]+src\s*=\s*["']([^"']+)["']`)
+)
+
+// ImageLinks 提取 Markdown 正文中引用的所有图片链接 (去重, 保持出现顺序)
+func ImageLinks(content string) []string {
+	links := make([]string, 0)
+	set := make(map[string]struct{})
+
+	for _, re := range []*regexp.Regexp{mdImageRegexp, htmlImageRegexp} {
+		for _, match := range re.FindAllStringSubmatch(content, -1) {
+			link := match[1]
+			if _, ok := set[link]; ok {
+				continue
+			}
+			set[link] = struct{}{}
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// ReplaceImageLinks 根据 old => new 的映射替换正文中的图片链接, 不在映射中的链接保持不变
+func ReplaceImageLinks(content string, replacer map[string]string) string {
+	if len(replacer) == 0 {
+		return content
+	}
+
+	replace := func(re *regexp.Regexp, s string) string {
+		return re.ReplaceAllStringFunc(s, func(match string) string {
+			sub := re.FindStringSubmatchIndex(match)
+			link := match[sub[2]:sub[3]]
+			newLink, ok := replacer[link]
+			if !ok {
+				return match
+			}
+			return match[:sub[2]] + newLink + match[sub[3]:]
+		})
+	}
+
+	content = replace(mdImageRegexp, content)
+	content = replace(htmlImageRegexp, content)
+	return content
+}
@@ -0,0 +1,96 @@
+package markdown
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMarshalFrontMatter(t *testing.T) {
+	data, err := MarshalFrontMatter(map[string]any{"title": "hello"}, "# Hello")
+	assert.Nil(t, err)
+	assert.Equal(t, "---\ntitle: hello\n---\n\n# Hello", string(data))
+}
+
+func TestImageLinks(t *testing.T) {
+	content := "![a](https://a.com/1.png)\n![b](/public/2.jpg \"title\")\n<img src='https://a.com/3.gif'>\n![a](https://a.com/1.png)"
+	links := ImageLinks(content)
+	assert.Equal(t, []string{"https://a.com/1.png", "/public/2.jpg", "https://a.com/3.gif"}, links)
+
+	replaced := ReplaceImageLinks(content, map[string]string{
+		"https://a.com/1.png": "images/1.png",
+		"https://a.com/3.gif": "images/3.gif",
+	})
+	assert.Equal(t, "![a](images/1.png)\n![b](/public/2.jpg \"title\")\n<img src='images/3.gif'>\n![a](images/1.png)", replaced)
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	// YAML
+	matter, body, err := ParseFrontMatter([]byte("---\r\ntitle: Hello\r\ntags: [Go, Gin]\r\ncategories:\r\n  - 后端\r\ndate: 2024-01-02 15:04:05\r\ndraft: true\r\n---\r\n\r\n# Hello\r\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "# Hello\n", body)
+	assert.Equal(t, "Hello", matter.String("title"))
+	assert.Equal(t, []string{"Go", "Gin"}, matter.Strings("tags"))
+	assert.Equal(t, "后端", matter.String("category", "categories"))
+	draft, ok := matter.Bool("draft")
+	assert.True(t, ok)
+	assert.True(t, draft)
+	date, ok := matter.Time("date")
+	assert.True(t, ok)
+	assert.Equal(t, 2024, date.Year())
+
+	// TOML
+	matter, body, err = ParseFrontMatter([]byte("+++\ntitle = \"Hugo\"\ntags = \"a, b\"\ndate = 2024-01-02\n+++\nbody"))
+	assert.Nil(t, err)
+	assert.Equal(t, "body", body)
+	assert.Equal(t, "Hugo", matter.String("title"))
+	assert.Equal(t, []string{"a", "b"}, matter.Strings("tags"))
+	_, ok = matter.Time("date")
+	assert.True(t, ok)
+
+	// 没有 front matter
+	matter, body, err = ParseFrontMatter([]byte("# Title"))
+	assert.Nil(t, err)
+	assert.Empty(t, matter)
+	assert.Equal(t, "# Title", body)
+
+	// 缺少结束分隔符
+	_, _, err = ParseFrontMatter([]byte("---\ntitle: a\n"))
+	assert.ErrorIs(t, err, ErrFrontMatterNotClosed)
+}
+
+func TestRender(t *testing.T) {
+	r, err := Render("# 你好 *世界*\n\n## Intro\n\n## Intro\n\nhello world\n\n```go\nfunc main() {}\n```\n")
+	assert.Nil(t, err)
+	assert.Equal(t, []Heading{
+		{Level: 1, ID: "ni-hao-shi-jie", Text: "你好 世界"},
+		{Level: 2, ID: "intro", Text: "Intro"},
+		{Level: 2, ID: "intro-1", Text: "Intro"},
+	}, r.TOC)
+	assert.Contains(t, r.HTML, `<h1 id="ni-hao-shi-jie">你好 <em>世界</em></h1>`)
+	assert.Contains(t, r.HTML, `<h2 id="intro-1">Intro</h2>`)
+	assert.Contains(t, r.HTML, `<span style="color: #000; font-weight: bold">func</span>`) // 代码高亮
+	assert.Equal(t, 10, r.WordCount)
+	assert.Equal(t, 1, r.ReadingMinutes)
+
+	// 过滤危险的 HTML
+	r, err = Render("<script>alert(1)</script><img src=\"a.png\" onerror=\"alert(1)\">\n\n[x](javascript:alert(1))")
+	assert.Nil(t, err)
+	assert.NotContains(t, r.HTML, "script")
+	assert.NotContains(t, r.HTML, "onerror")
+	assert.NotContains(t, r.HTML, "javascript")
+	assert.Contains(t, r.HTML, `<img src="a.png">`)
+
+	r, err = Render("")
+	assert.Nil(t, err)
+	assert.Empty(t, r.TOC)
+	assert.Equal(t, 0, r.ReadingMinutes)
+}
+
+func TestCountWords(t *testing.T) {
+	assert.Equal(t, 0, CountWords(""))
+	assert.Equal(t, 7, CountWords("Go 语言入门, hello-world"))
+	assert.Equal(t, 1, ReadingMinutes(1))
+	assert.Equal(t, 2, ReadingMinutes(WordsPerMinute+1))
+	assert.Equal(t, WordsPerMinute, CountWords(strings.Repeat("字", WordsPerMinute)))
+}
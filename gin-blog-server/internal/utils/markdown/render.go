@@ -0,0 +1,180 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"gin-blog-server/internal/utils/slug"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"html"
+	"math"
+	"regexp"
+	"unicode"
+)
+
+// WordsPerMinute 阅读速度: 每分钟阅读的字数 (中文按字, 英文按单词)
+const WordsPerMinute = 300
+
+// Heading 目录中的一个标题
+type Heading struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"` // 标题锚点, 对应 HTML 中标题的 id
+	Text  string `json:"text"`
+}
+
+// Rendered Markdown 渲染结果
+type Rendered struct {
+	HTML           string    `json:"html"`
+	TOC            []Heading `json:"toc"`
+	WordCount      int       `json:"word_count"`
+	ReadingMinutes int       `json:"reading_minutes"`
+}
+
+// 允许原始 HTML (兼容已有文章中的 <img> 等标签), 渲染后统一使用 sanitizer 过滤
+// 代码高亮使用内联样式, 邮件, RSS 等没有样式表的场景也能正常显示
+var converter = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle("github"),
+			highlighting.WithFormatOptions(chromahtml.WithClasses(false)),
+		),
+	),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+)
+
+var (
+	sanitizer = newSanitizer()
+	stripper  = bluemonday.StrictPolicy() // 去除所有标签, 用于统计字数
+)
+
+// newSanitizer 在 UGC 策略的基础上, 允许标题锚点和代码高亮的内联样式
+func newSanitizer() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[a-z0-9\-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowStyles("color", "background-color", "font-weight", "font-style", "text-decoration", "display").OnElements("pre", "span")
+	p.AllowAttrs("type", "checked", "disabled").OnElements("input") // GFM 任务列表
+	p.AllowElements("input")
+	return p
+}
+
+// Render 将 Markdown 渲染为过滤后的 HTML, 同时生成目录, 统计字数和阅读时长
+func Render(source string) (*Rendered, error) {
+	src := []byte(source)
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	doc := converter.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+
+	var buf bytes.Buffer
+	if err := converter.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, fmt.Errorf("渲染 Markdown 失败: %w", err)
+	}
+	content := sanitizer.Sanitize(buf.String())
+
+	words := CountWords(html.UnescapeString(stripper.Sanitize(content)))
+	return &Rendered{
+		HTML:           content,
+		TOC:            headings(doc, src),
+		WordCount:      words,
+		ReadingMinutes: ReadingMinutes(words),
+	}, nil
+}
+
+// headings 收集文档中的标题, 生成目录
+func headings(doc ast.Node, src []byte) []Heading {
+	toc := make([]Heading, 0)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		heading := Heading{Level: h.Level, Text: nodeText(h, src)}
+		if id, ok := h.AttributeString("id"); ok {
+			heading.ID = string(id.([]byte))
+		}
+		toc = append(toc, heading)
+		return ast.WalkSkipChildren, nil
+	})
+	return toc
+}
+
+// nodeText 获取节点中的纯文本 (去除强调, 链接等格式)
+func nodeText(n ast.Node, src []byte) string {
+	var buf bytes.Buffer
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			buf.Write(n.Segment.Value(src))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(n.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.String()
+}
+
+// headingIDs 标题锚点生成器, 中文转换为拼音 (goldmark 默认会丢弃非 ASCII 字符)
+// 重复的锚点添加数字后缀: intro, intro-1, intro-2
+type headingIDs struct {
+	values map[string]bool
+}
+
+func newHeadingIDs() parser.IDs {
+	return &headingIDs{values: make(map[string]bool)}
+}
+
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	base := slug.Make(string(value))
+	if base == "" {
+		base = "heading"
+	}
+	id := base
+	for i := 1; s.values[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	s.values[id] = true
+	return []byte(id)
+}
+
+func (s *headingIDs) Put(value []byte) {
+	s.values[string(value)] = true
+}
+
+// CountWords 统计字数: 中日韩文字按字计算, 其他连续的字母/数字按一个单词计算
+func CountWords(s string) int {
+	count := 0
+	inWord := false
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			count++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				count++
+			}
+			inWord = true
+		default:
+			inWord = false
+		}
+	}
+	return count
+}
+
+// ReadingMinutes 根据字数估算阅读时长 (分钟), 有内容时至少为 1 分钟
+func ReadingMinutes(words int) int {
+	return int(math.Ceil(float64(words) / WordsPerMinute))
+}
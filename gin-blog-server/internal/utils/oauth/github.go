@@ -0,0 +1,88 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// GitHub OAuth App: 不支持 OIDC, 用户信息通过 REST API 获取
+// https://docs.github.com/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps
+
+func init() {
+	Register("github", NewGitHub)
+}
+
+const (
+	githubAuthURL   = "https://github.com/login/oauth/authorize"
+	githubTokenURL  = "https://github.com/login/oauth/access_token"
+	githubUserURL   = "https://api.github.com/user"
+	githubEmailPath = "/emails" // 相对于用户信息端点
+)
+
+type githubProvider struct {
+	conf Config
+}
+
+// NewGitHub 创建 GitHub 登录, 端点可以在配置中覆盖 (例如 GitHub Enterprise)
+func NewGitHub(conf Config) (Provider, error) {
+	if conf.AuthURL == "" {
+		conf.AuthURL = githubAuthURL
+	}
+	if conf.TokenURL == "" {
+		conf.TokenURL = githubTokenURL
+	}
+	if conf.UserInfoURL == "" {
+		conf.UserInfoURL = githubUserURL
+	}
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"read:user", "user:email"}
+	}
+	return &githubProvider{conf: conf}, nil
+}
+
+func (p *githubProvider) AuthCodeURL(_ context.Context, req AuthRequest) (string, error) {
+	return authCodeURL(p.conf.AuthURL, p.conf, p.conf.Scopes, req, nil)
+}
+
+func (p *githubProvider) Profile(ctx context.Context, code string, req AuthRequest) (*Profile, error) {
+	token, err := exchange(ctx, p.conf.TokenURL, p.conf, code, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		Id        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(ctx, p.conf.UserInfoURL, token.AccessToken, &user); err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.Id == 0 {
+		return nil, ErrNoSubject
+	}
+
+	// 用户名可以修改, 使用数字 id 作为唯一标识
+	profile := &Profile{Subject: strconv.FormatInt(user.Id, 10), Nickname: user.Name, Avatar: user.AvatarURL}
+	if profile.Nickname == "" {
+		profile.Nickname = user.Login
+	}
+
+	// 公开资料中的邮箱不一定经过验证, 只使用邮箱列表中已验证的主邮箱, 获取失败时忽略
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, p.conf.UserInfoURL+githubEmailPath, token.AccessToken, &emails); err == nil {
+		for _, e := range emails {
+			if e.Primary && e.Verified {
+				profile.Email = e.Email
+				break
+			}
+		}
+	}
+	return profile, nil
+}
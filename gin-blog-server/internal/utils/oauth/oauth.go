@@ -0,0 +1,200 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 第三方登录: OAuth 2.0 授权码模式, 使用 PKCE (RFC 7636) 防止授权码被截获后使用
+// OIDC 额外校验 id_token 的签名, 签发者, 受众和 nonce
+// 新的登录类型实现 Provider 接口, 并通过 Register 注册
+
+var (
+	ErrUnknownType = errors.New("不支持的第三方登录类型")
+	ErrNoSubject   = errors.New("第三方平台没有返回用户标识")
+)
+
+// Config 第三方登录的配置
+type Config struct {
+	Type         string   // 类型: github | oidc
+	ClientId     string   // 在第三方平台注册应用获得
+	ClientSecret string   // 在第三方平台注册应用获得
+	Issuer       string   // OIDC 签发者, 端点通过 <Issuer>/.well-known/openid-configuration 获取
+	AuthURL      string   // 授权端点, 为空时使用默认值或者 OIDC 发现的端点
+	TokenURL     string   // token 端点, 同上
+	UserInfoURL  string   // 用户信息端点, 同上
+	Scopes       []string // 为空时使用默认值
+}
+
+// Profile 第三方平台的用户信息
+type Profile struct {
+	Subject  string // 第三方平台中的用户唯一标识
+	Nickname string
+	Avatar   string
+	Email    string // 只包含已验证的邮箱
+}
+
+// AuthRequest 一次授权的参数, 发起授权时生成, 回调时用于校验和换取 token
+type AuthRequest struct {
+	State       string `json:"state"`
+	Verifier    string `json:"verifier"`     // PKCE code_verifier
+	Nonce       string `json:"nonce"`        // OIDC nonce, 写入 id_token 防止重放
+	RedirectURL string `json:"redirect_url"` // 回调地址, 换取 token 时必须与授权时一致
+}
+
+// Provider 第三方登录提供方
+type Provider interface {
+	// AuthCodeURL 返回跳转到第三方授权页面的地址
+	AuthCodeURL(ctx context.Context, req AuthRequest) (string, error)
+	// Profile 使用回调中的授权码换取 token, 并获取用户信息
+	Profile(ctx context.Context, code string, req AuthRequest) (*Profile, error)
+}
+
+// Factory 根据配置创建 Provider
+type Factory func(conf Config) (Provider, error)
+
+var factories = map[string]Factory{}
+
+// Register 注册第三方登录类型, 重复注册时覆盖
+func Register(typ string, f Factory) {
+	factories[typ] = f
+}
+
+// New 根据配置中的类型创建 Provider
+func New(conf Config) (Provider, error) {
+	f, ok := factories[conf.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, conf.Type)
+	}
+	return f(conf)
+}
+
+// HTTPClient 请求第三方平台使用的客户端
+var HTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewAuthRequest 生成随机的 state, PKCE verifier 和 nonce
+func NewAuthRequest(redirectURL string) AuthRequest {
+	return AuthRequest{
+		State:       randomString(),
+		Verifier:    randomString(),
+		Nonce:       randomString(),
+		RedirectURL: redirectURL,
+	}
+}
+
+// CodeChallenge PKCE S256: BASE64URL(SHA256(verifier))
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString 32 字节随机数的 base64url 编码, 43 个字符, 满足 PKCE verifier 的长度要求
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// authCodeURL 授权码模式的授权地址, extra 为额外的参数
+func authCodeURL(endpoint string, conf Config, scopes []string, req AuthRequest, extra url.Values) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", conf.ClientId)
+	q.Set("redirect_uri", req.RedirectURL)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", req.State)
+	q.Set("code_challenge", CodeChallenge(req.Verifier))
+	q.Set("code_challenge_method", "S256")
+	for k, v := range extra {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// tokenResponse token 端点的响应 (RFC 6749 5.1, 5.2)
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IdToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchange 使用授权码和 PKCE verifier 换取 token
+func exchange(ctx context.Context, tokenURL string, conf Config, code string, req AuthRequest) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {req.RedirectURL},
+		"client_id":     {conf.ClientId},
+		"client_secret": {conf.ClientSecret},
+		"code_verifier": {req.Verifier},
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "application/json") // GitHub 默认返回表单格式
+
+	var token tokenResponse
+	if err := do(r, &token); err != nil && token.Error == "" {
+		return nil, fmt.Errorf("换取 token 失败: %w", err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("换取 token 失败: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("换取 token 失败: 没有返回 access_token")
+	}
+	return &token, nil
+}
+
+// getJSON 使用 access token 请求 JSON 接口
+func getJSON(ctx context.Context, endpoint, accessToken string, v any) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		r.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return do(r, v)
+}
+
+// do 发送请求并解析 JSON 响应, 非 2xx 状态码时返回错误, 同时尽量解析响应
+func do(r *http.Request, v any) error {
+	resp, err := HTTPClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	jsonErr := json.Unmarshal(body, v)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s", r.Method, r.URL.Redacted(), resp.Status)
+	}
+	return jsonErr
+}
@@ -0,0 +1,201 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 附录 B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+
+	req := NewAuthRequest("http://localhost/callback")
+	assert.Len(t, req.Verifier, 43)
+	assert.NotEqual(t, req.State, req.Nonce)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Type: "unknown"})
+	assert.ErrorIs(t, err, ErrUnknownType)
+	_, err = New(Config{Type: "oidc"})
+	assert.NotNil(t, err)
+	_, err = New(Config{Type: "github"})
+	assert.Nil(t, err)
+}
+
+// mockOIDC 本地 OIDC 签发者, 授权时记录 PKCE challenge 和 nonce, 换取 token 时校验 verifier
+type mockOIDC struct {
+	*httptest.Server
+	key    *ecdsa.PrivateKey
+	claims jwt.MapClaims // 额外写入 id_token 的字段
+	codes  map[string]url.Values
+}
+
+func newMockOIDC(t *testing.T) *mockOIDC {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	m := &mockOIDC{key: key, codes: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "EC", "kid": "k1", "use": "sig", "crv": "P-256", "x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "oct", "kid": "k2", "k": "c2VjcmV0"},
+		}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		m.codes["code"] = q
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=code&state="+q.Get("state"), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		q, ok := m.codes[r.FormValue("code")]
+		delete(m.codes, r.FormValue("code"))
+		if !ok || CodeChallenge(r.FormValue("code_verifier")) != q.Get("code_challenge") || r.FormValue("redirect_uri") != q.Get("redirect_uri") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss": m.URL, "aud": "client", "sub": "user-1", "nonce": q.Get("nonce"),
+			"exp": time.Now().Add(time.Minute).Unix(), "name": "Alice", "picture": "http://img/a.png",
+			"email": "alice@example.com", "email_verified": true,
+		}
+		for k, v := range m.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		token.Header["kid"] = "k1"
+		idToken, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": idToken})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize 模拟浏览器访问授权地址, 返回回调中的 code 和 state
+func authorize(t *testing.T, authURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	assert.Nil(t, err)
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestOIDC(t *testing.T) {
+	m := newMockOIDC(t)
+	p, err := New(Config{Type: "oidc", Issuer: m.URL + "/", ClientId: "client"})
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	req := NewAuthRequest("http://localhost/api/oauth/mock/callback")
+	authURL, err := p.AuthCodeURL(ctx, req)
+	assert.Nil(t, err)
+	code, state := authorize(t, authURL)
+	assert.Equal(t, req.State, state)
+
+	profile, err := p.Profile(ctx, code, req)
+	assert.Nil(t, err)
+	assert.Equal(t, &Profile{Subject: "user-1", Nickname: "Alice", Avatar: "http://img/a.png", Email: "alice@example.com"}, profile)
+
+	// 授权码只能使用一次
+	_, err = p.Profile(ctx, code, req)
+	assert.NotNil(t, err)
+
+	// PKCE verifier 不一致
+	authURL, _ = p.AuthCodeURL(ctx, req)
+	code, _ = authorize(t, authURL)
+	other := req
+	other.Verifier = NewAuthRequest("").Verifier
+	_, err = p.Profile(ctx, code, other)
+	assert.ErrorContains(t, err, "invalid_grant")
+
+	// nonce 不一致
+	authURL, _ = p.AuthCodeURL(ctx, req)
+	code, _ = authorize(t, authURL)
+	other = req
+	other.Nonce = "other"
+	_, err = p.Profile(ctx, code, other)
+	assert.ErrorContains(t, err, "nonce")
+
+	// 受众, 签发者不一致, 已过期, 邮箱未验证
+	for name, claims := range map[string]jwt.MapClaims{
+		"受众":      {"aud": "other"},
+		"签发者":     {"iss": "http://evil"},
+		"expired": {"exp": time.Now().Add(-time.Hour).Unix()},
+	} {
+		m.claims = claims
+		authURL, _ = p.AuthCodeURL(ctx, req)
+		code, _ = authorize(t, authURL)
+		_, err = p.Profile(ctx, code, req)
+		assert.ErrorContains(t, err, name)
+	}
+	m.claims = jwt.MapClaims{"email_verified": false}
+	authURL, _ = p.AuthCodeURL(ctx, req)
+	code, _ = authorize(t, authURL)
+	profile, err = p.Profile(ctx, code, req)
+	assert.Nil(t, err)
+	assert.Empty(t, profile.Email)
+}
+
+func TestGitHub(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "code" || r.FormValue("code_verifier") == "" || r.Header.Get("Accept") != "application/json" {
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer at", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "login": "octocat", "avatar_url": "http://img/o.png"})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"email": "unverified@example.com", "primary": true, "verified": false},
+			{"email": "other@example.com", "primary": false, "verified": true},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	p, _ := New(Config{Type: "github", ClientId: "client", AuthURL: srv.URL + "/authorize", TokenURL: srv.URL + "/access_token", UserInfoURL: srv.URL + "/user"})
+	req := NewAuthRequest("http://localhost/callback")
+	authURL, err := p.AuthCodeURL(context.Background(), req)
+	assert.Nil(t, err)
+	u, _ := url.Parse(authURL)
+	assert.Equal(t, "read:user user:email", u.Query().Get("scope"))
+	assert.Equal(t, CodeChallenge(req.Verifier), u.Query().Get("code_challenge"))
+
+	profile, err := p.Profile(context.Background(), "code", req)
+	assert.Nil(t, err)
+	// 没有已验证的主邮箱
+	assert.Equal(t, &Profile{Subject: "42", Nickname: "octocat", Avatar: "http://img/o.png"}, profile)
+
+	_, err = p.Profile(context.Background(), "wrong", req)
+	assert.ErrorContains(t, err, "bad_verification_code")
+}
@@ -0,0 +1,317 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenID Connect: 通过 <Issuer>/.well-known/openid-configuration 发现端点
+// 用户信息来自 id_token, 签名使用签发者 jwks_uri 中的公钥验证
+
+func init() {
+	Register("oidc", NewOIDC)
+}
+
+// idTokenAlgs 接受的 id_token 签名算法, 不接受 none 和 HMAC
+var idTokenAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// leeway 校验 id_token 有效期时允许的时钟误差
+const leeway = time.Minute
+
+// discovery OIDC 发现文档中使用的字段
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type oidcProvider struct {
+	conf Config
+
+	mu   sync.Mutex
+	meta *discovery                  // 发现文档, 第一次使用时获取
+	keys map[string]crypto.PublicKey // kid -> 公钥
+}
+
+// NewOIDC 创建 OIDC 登录, 必须配置 Issuer
+func NewOIDC(conf Config) (Provider, error) {
+	if conf.Issuer == "" {
+		return nil, errors.New("OIDC 登录必须配置 Issuer")
+	}
+	conf.Issuer = strings.TrimSuffix(conf.Issuer, "/")
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"openid", "profile", "email"}
+	}
+	return &oidcProvider{conf: conf}, nil
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, req AuthRequest) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return authCodeURL(meta.AuthorizationEndpoint, p.conf, p.conf.Scopes, req, url.Values{"nonce": {req.Nonce}})
+}
+
+func (p *oidcProvider) Profile(ctx context.Context, code string, req AuthRequest) (*Profile, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := exchange(ctx, meta.TokenEndpoint, p.conf, code, req)
+	if err != nil {
+		return nil, err
+	}
+	if token.IdToken == "" {
+		return nil, errors.New("OIDC 登录没有返回 id_token")
+	}
+	claims, err := p.verify(ctx, meta, token.IdToken, req.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("id_token 校验失败: %w", err)
+	}
+
+	// id_token 中没有用户资料时从用户信息端点获取, sub 必须一致 (OIDC Core 5.3.2)
+	if claims.Name == "" && claims.PreferredUsername == "" && meta.UserinfoEndpoint != "" {
+		var info userClaims
+		if err := getJSON(ctx, meta.UserinfoEndpoint, token.AccessToken, &info); err != nil {
+			return nil, fmt.Errorf("获取用户信息失败: %w", err)
+		}
+		if info.Subject != claims.Subject {
+			return nil, errors.New("用户信息与 id_token 的 sub 不一致")
+		}
+		claims.userClaims = info
+	}
+	return claims.profile(), nil
+}
+
+// userClaims OIDC 标准用户信息 (OIDC Core 5.1)
+type userClaims struct {
+	Subject           string `json:"sub"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Picture           string `json:"picture"`
+	Email             string `json:"email"`
+	EmailVerified     any    `json:"email_verified"` // 部分实现返回字符串 "true"
+}
+
+// idTokenClaims id_token 中校验和使用的字段
+type idTokenClaims struct {
+	userClaims
+	Issuer    string           `json:"iss"`
+	Audience  jwt.ClaimStrings `json:"aud"`
+	ExpiresAt *jwt.NumericDate `json:"exp"`
+	Nonce     string           `json:"nonce"`
+}
+
+// Valid 实现 jwt.Claims, 检查有效期, 允许 leeway 的时钟误差
+func (c *idTokenClaims) Valid() error {
+	if c.ExpiresAt == nil {
+		return errors.New("缺少过期时间")
+	}
+	if time.Now().After(c.ExpiresAt.Add(leeway)) {
+		return jwt.ErrTokenExpired
+	}
+	return nil
+}
+
+func (c *userClaims) profile() *Profile {
+	p := &Profile{Subject: c.Subject, Nickname: c.Name, Avatar: c.Picture}
+	if p.Nickname == "" {
+		p.Nickname = c.PreferredUsername
+	}
+	if v := c.EmailVerified; v == true || v == "true" {
+		p.Email = c.Email
+	}
+	return p
+}
+
+// verify 校验 id_token 的签名, 签发者, 受众, 有效期和 nonce (OIDC Core 3.1.3.7)
+func (p *oidcProvider) verify(ctx context.Context, meta *discovery, raw, nonce string) (*idTokenClaims, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(ctx, meta, kid)
+	}, jwt.WithValidMethods(idTokenAlgs))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case claims.Issuer != meta.Issuer:
+		return nil, fmt.Errorf("签发者不一致: %s", claims.Issuer)
+	case !slices.Contains(claims.Audience, p.conf.ClientId):
+		return nil, errors.New("受众不包含当前应用")
+	case claims.Nonce != nonce:
+		return nil, errors.New("nonce 不一致")
+	case claims.Subject == "":
+		return nil, ErrNoSubject
+	}
+	return &claims, nil
+}
+
+// discover 获取并缓存发现文档, 获取失败时下次重试
+func (p *oidcProvider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var meta discovery
+	if err := getJSON(ctx, p.conf.Issuer+"/.well-known/openid-configuration", "", &meta); err != nil {
+		return nil, fmt.Errorf("获取 OIDC 配置失败: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != p.conf.Issuer {
+		return nil, fmt.Errorf("OIDC 配置中的签发者 %s 与 %s 不一致", meta.Issuer, p.conf.Issuer)
+	}
+	if p.conf.AuthURL != "" {
+		meta.AuthorizationEndpoint = p.conf.AuthURL
+	}
+	if p.conf.TokenURL != "" {
+		meta.TokenEndpoint = p.conf.TokenURL
+	}
+	if p.conf.UserInfoURL != "" {
+		meta.UserinfoEndpoint = p.conf.UserInfoURL
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JwksURI == "" {
+		return nil, errors.New("OIDC 配置缺少必要的端点")
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// publicKey 返回 kid 对应的公钥, 没有缓存时重新获取 JWKS, 以支持签发者轮换密钥
+func (p *oidcProvider) publicKey(ctx context.Context, meta *discovery, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := getJSON(ctx, meta.JwksURI, "", &set); err != nil {
+		return nil, fmt.Errorf("获取 JWKS 失败: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, raw := range set.Keys {
+		id, key, err := parseJWK(raw)
+		if err != nil {
+			continue // 跳过不支持的密钥类型
+		}
+		keys[id] = key
+	}
+	p.keys = keys
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("签名密钥 %q 不存在", kid)
+}
+
+// lookup 查找公钥, token 没有 kid 时只有唯一的公钥才能使用
+func (p *oidcProvider) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// jwk JSON Web Key (RFC 7517) 中的公钥字段
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWK 解析 RSA, EC 和 OKP (Ed25519) 签名公钥
+func parseJWK(raw []byte) (string, crypto.PublicKey, error) {
+	var k jwk
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return "", nil, err
+	}
+	if k.Use != "" && k.Use != "sig" {
+		return "", nil, errors.New("不是签名密钥")
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err1 := decodeInt(k.N)
+		e, err2 := decodeInt(k.E)
+		if err := errors.Join(err1, err2); err != nil {
+			return "", nil, err
+		}
+		if !e.IsInt64() {
+			return "", nil, errors.New("RSA 指数过大")
+		}
+		return k.Kid, &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return "", nil, fmt.Errorf("不支持的曲线 %s", k.Crv)
+		}
+		x, err1 := decodeInt(k.X)
+		y, err2 := decodeInt(k.Y)
+		if err := errors.Join(err1, err2); err != nil {
+			return "", nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return "", nil, errors.New("EC 公钥不在曲线上")
+		}
+		return k.Kid, &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return "", nil, err
+		}
+		if k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return "", nil, fmt.Errorf("不支持的曲线 %s", k.Crv)
+		}
+		return k.Kid, ed25519.PublicKey(x), nil
+	}
+	return "", nil, fmt.Errorf("不支持的密钥类型 %s", k.Kty)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("JWK 缺少必要的字段")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
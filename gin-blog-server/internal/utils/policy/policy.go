@@ -0,0 +1,129 @@
+package policy
+
+// 数据权限范围, 按照从大到小的顺序
+const (
+	ScopeAll  = "all"  // 所有数据
+	ScopeOwn  = "own"  // 只有自己创建的数据
+	ScopeNone = "none" // 没有数据权限
+)
+
+var scopeRank = map[string]int{ScopeNone: 0, ScopeOwn: 1, ScopeAll: 2}
+
+// ValidScope 判断是否是有效的数据权限范围
+func ValidScope(scope string) bool {
+	_, ok := scopeRank[scope]
+	return ok
+}
+
+// Ancestors 角色及其所有上级角色, parents 为 role id => 上级角色 id, 遇到循环时停止
+func Ancestors(parents map[int]int, roleIds []int) []int {
+	seen := make(map[int]bool, len(roleIds))
+	result := make([]int, 0, len(roleIds))
+	for _, id := range roleIds {
+		for id != 0 && !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+			id = parents[id]
+		}
+	}
+	return result
+}
+
+// HasCycle 判断将 id 的上级角色设置为 parentId 后是否会形成循环继承
+func HasCycle(parents map[int]int, id, parentId int) bool {
+	seen := make(map[int]bool)
+	for p := parentId; p != 0 && !seen[p]; p = parents[p] {
+		if p == id {
+			return true
+		}
+		seen[p] = true
+	}
+	return false
+}
+
+// Policy 角色 => 接口权限, 数据权限表, 只包含启用的角色, 构建后只读, 可以并发使用
+type Policy struct {
+	roles   map[int]bool              // 启用的角色
+	parents map[int]int               // role id => 上级角色 id
+	grants  map[int]map[string]bool   // role id => "METHOD url"
+	scopes  map[int]map[string]string // role id => 数据类型 => 数据权限范围
+}
+
+func New() *Policy {
+	return &Policy{
+		roles:   make(map[int]bool),
+		parents: make(map[int]int),
+		grants:  make(map[int]map[string]bool),
+		scopes:  make(map[int]map[string]string),
+	}
+}
+
+func key(method, url string) string {
+	return method + " " + url
+}
+
+// AddRole 添加启用的角色, 只有添加的角色才拥有数据权限
+func (p *Policy) AddRole(roleId int) {
+	p.roles[roleId] = true
+}
+
+// SetParent 设置角色的上级角色, 角色继承上级角色 (以及更上级) 的接口权限, 数据权限不继承
+func (p *Policy) SetParent(roleId, parentId int) {
+	p.parents[roleId] = parentId
+}
+
+// Grant 授予角色访问接口的权限
+func (p *Policy) Grant(roleId int, method, url string) {
+	if p.grants[roleId] == nil {
+		p.grants[roleId] = make(map[string]bool)
+	}
+	p.grants[roleId][key(method, url)] = true
+}
+
+// GrantedBy 角色是否可以访问接口, 以及授予权限的角色 (角色自己或者最近的上级角色)
+func (p *Policy) GrantedBy(roleId int, method, url string) (int, bool) {
+	k := key(method, url)
+	for _, id := range Ancestors(p.parents, []int{roleId}) {
+		if p.grants[id][k] {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// SetScope 设置角色对某类数据的权限范围, 没有设置时为 ScopeAll
+func (p *Policy) SetScope(roleId int, data, scope string) {
+	if p.scopes[roleId] == nil {
+		p.scopes[roleId] = make(map[string]string)
+	}
+	p.scopes[roleId][data] = scope
+}
+
+// Allow 判断是否可以访问接口: 取所有角色及其上级角色权限的并集, 任一角色拥有权限即可访问
+func (p *Policy) Allow(roleIds []int, method, url string) bool {
+	k := key(method, url)
+	for _, id := range Ancestors(p.parents, roleIds) {
+		if p.grants[id][k] {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope 对某类数据的权限范围: 取所有角色中最大的范围, 没有启用的角色时为 ScopeNone
+func (p *Policy) Scope(roleIds []int, data string) string {
+	result := ScopeNone
+	for _, id := range roleIds {
+		if !p.roles[id] {
+			continue
+		}
+		scope, ok := p.scopes[id][data]
+		if !ok || !ValidScope(scope) {
+			scope = ScopeAll
+		}
+		if scopeRank[scope] > scopeRank[result] {
+			result = scope
+		}
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAllow(t *testing.T) {
+	p := New()
+	p.Grant(1, "GET", "/article/list")
+	p.Grant(2, "POST", "/article")
+	p.Grant(2, "DELETE", "/article/:id")
+
+	assert.True(t, p.Allow([]int{1}, "GET", "/article/list"))
+	assert.False(t, p.Allow([]int{1}, "POST", "/article"))
+	assert.False(t, p.Allow([]int{1}, "GET", "/article"))
+
+	// 多个角色取并集, 与角色顺序无关
+	assert.True(t, p.Allow([]int{1, 2}, "POST", "/article"))
+	assert.True(t, p.Allow([]int{2, 1}, "GET", "/article/list"))
+
+	assert.False(t, p.Allow([]int{3}, "GET", "/article/list"))
+	assert.False(t, p.Allow(nil, "GET", "/article/list"))
+}
+
+func TestAllowInherit(t *testing.T) {
+	// 编辑 (1) ⊂ 高级编辑 (2) ⊂ 管理员 (3)
+	p := New()
+	p.SetParent(2, 1)
+	p.SetParent(3, 2)
+	p.Grant(1, "GET", "/article/list")
+	p.Grant(2, "POST", "/article")
+	p.Grant(3, "DELETE", "/article/:id")
+
+	assert.True(t, p.Allow([]int{3}, "GET", "/article/list"))
+	assert.True(t, p.Allow([]int{3}, "POST", "/article"))
+	assert.True(t, p.Allow([]int{2}, "GET", "/article/list"))
+	assert.False(t, p.Allow([]int{2}, "DELETE", "/article/:id"))
+	assert.False(t, p.Allow([]int{1}, "POST", "/article"))
+
+	id, ok := p.GrantedBy(3, "GET", "/article/list")
+	assert.True(t, ok)
+	assert.Equal(t, 1, id) // 继承自编辑
+	id, ok = p.GrantedBy(2, "POST", "/article")
+	assert.True(t, ok)
+	assert.Equal(t, 2, id)
+	_, ok = p.GrantedBy(2, "DELETE", "/article/:id")
+	assert.False(t, ok)
+
+	// 数据中存在循环时不会死循环
+	p.SetParent(1, 3)
+	assert.True(t, p.Allow([]int{1}, "DELETE", "/article/:id"))
+	assert.False(t, p.Allow([]int{1}, "PUT", "/article"))
+}
+
+func TestAncestors(t *testing.T) {
+	parents := map[int]int{2: 1, 3: 2, 5: 4}
+
+	assert.Equal(t, []int{3, 2, 1}, Ancestors(parents, []int{3}))
+	assert.Equal(t, []int{3, 2, 1, 5, 4}, Ancestors(parents, []int{3, 5}))
+	assert.Equal(t, []int{2, 1, 3}, Ancestors(parents, []int{2, 3})) // 去重
+	assert.Equal(t, []int{6}, Ancestors(parents, []int{6}))
+	assert.Empty(t, Ancestors(parents, nil))
+
+	parents[1] = 3
+	assert.Equal(t, []int{1, 3, 2}, Ancestors(parents, []int{1}))
+}
+
+func TestHasCycle(t *testing.T) {
+	parents := map[int]int{2: 1, 3: 2}
+
+	assert.True(t, HasCycle(parents, 1, 1))
+	assert.True(t, HasCycle(parents, 1, 3))
+	assert.True(t, HasCycle(parents, 2, 3))
+	assert.False(t, HasCycle(parents, 3, 1))
+	assert.False(t, HasCycle(parents, 4, 3))
+	assert.False(t, HasCycle(parents, 1, 0))
+
+	// 已有的循环不影响判断其他角色
+	parents[5], parents[6] = 6, 5
+	assert.False(t, HasCycle(parents, 1, 5))
+}
+
+func TestScope(t *testing.T) {
+	p := New()
+	p.AddRole(1)
+	p.AddRole(2)
+	p.AddRole(3)
+	p.SetScope(2, "article", ScopeOwn)
+	p.SetScope(3, "article", ScopeNone)
+	p.SetScope(3, "comment", ScopeOwn)
+
+	assert.Equal(t, ScopeAll, p.Scope([]int{1}, "article")) // 没有设置时为 all
+	assert.Equal(t, ScopeOwn, p.Scope([]int{2}, "article"))
+	assert.Equal(t, ScopeNone, p.Scope([]int{3}, "article"))
+	assert.Equal(t, ScopeAll, p.Scope([]int{2}, "comment"))
+
+	// 多个角色取最大的范围
+	assert.Equal(t, ScopeOwn, p.Scope([]int{3, 2}, "article"))
+	assert.Equal(t, ScopeAll, p.Scope([]int{2, 1}, "article"))
+
+	// 禁用 (未添加) 的角色没有数据权限
+	assert.Equal(t, ScopeNone, p.Scope([]int{4}, "article"))
+	assert.Equal(t, ScopeNone, p.Scope(nil, "article"))
+}
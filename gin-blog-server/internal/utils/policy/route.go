@@ -0,0 +1,65 @@
+package policy
+
+import "strings"
+
+// 路由中各段的类型, 与 gin 的匹配优先级一致: 静态 > 参数 (:id) > 通配 (*path)
+const (
+	segStatic = iota
+	segParam
+	segCatchAll
+)
+
+// MatchRoute 在路由列表中查找与请求路径匹配的路由, 多个路由匹配时返回最具体的路由
+// 例如 /article/list 同时匹配 /article/list 和 /article/:id 时返回 /article/list
+func MatchRoute(routes []string, path string) (string, bool) {
+	var best string
+	var bestRank []int
+	for _, route := range routes {
+		rank, ok := matchRoute(route, path)
+		if !ok {
+			continue
+		}
+		if bestRank == nil || lessRank(rank, bestRank) {
+			best, bestRank = route, rank
+		}
+	}
+	return best, bestRank != nil
+}
+
+// matchRoute 判断路由是否匹配请求路径, 返回路由中各段的类型
+func matchRoute(route, path string) ([]int, bool) {
+	rs, ps := split(route), split(path)
+	rank := make([]int, 0, len(rs))
+	for i, seg := range rs {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			return append(rank, segCatchAll), i == len(rs)-1
+		case i >= len(ps):
+			return nil, false
+		case strings.HasPrefix(seg, ":"):
+			if ps[i] == "" {
+				return nil, false
+			}
+			rank = append(rank, segParam)
+		case seg == ps[i]:
+			rank = append(rank, segStatic)
+		default:
+			return nil, false
+		}
+	}
+	return rank, len(rs) == len(ps)
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// lessRank 比较两个路由的优先级, 第一个不同的段类型较小的优先
+func lessRank(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) > len(b)
+}
@@ -0,0 +1,45 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatchRoute(t *testing.T) {
+	routes := []string{
+		"/article/list",
+		"/article/:id",
+		"/article/:id/revision/:rid",
+		"/comment/review",
+		"/file/*path",
+		"/",
+	}
+
+	cases := []struct {
+		path  string
+		route string
+		ok    bool
+	}{
+		{"/article/list", "/article/list", true}, // 静态路由优先
+		{"/article/12", "/article/:id", true},
+		{"/article/12/", "/article/:id", true},
+		{"/article/12/revision/3", "/article/:id/revision/:rid", true},
+		{"/article/:id", "/article/:id", true}, // 直接传入路由
+		{"/article", "", false},
+		{"/article/12/revision", "", false},
+		{"/comment/review", "/comment/review", true},
+		{"/comment/12", "", false},
+		{"/file/a/b/c.png", "/file/*path", true},
+		{"/file", "/file/*path", true},
+		{"/", "/", true},
+		{"/unknown", "", false},
+	}
+	for _, c := range cases {
+		route, ok := MatchRoute(routes, c.path)
+		assert.Equal(t, c.ok, ok, c.path)
+		assert.Equal(t, c.route, route, c.path)
+	}
+
+	_, ok := MatchRoute(nil, "/article/list")
+	assert.False(t, ok)
+}
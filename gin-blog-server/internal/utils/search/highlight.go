@@ -0,0 +1,81 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// snippetContext 摘要中命中位置之前保留的字数 (不超过摘要长度的 1/4)
+const snippetContext = 25
+
+// Highlight 转义 HTML 后, 使用 pre, post 包裹文本中所有查询词出现的位置 (不区分大小写)
+func Highlight(text, query, pre, post string) string {
+	runes := []rune(text)
+	return mark(runes, matches(runes, QueryTerms(query)), pre, post)
+}
+
+// Snippet 截取文本中第一个命中查询词的片段, 长度为 length 个字, 并高亮查询词
+// 没有命中时截取开头部分
+func Snippet(text, query string, length int, pre, post string) string {
+	runes := []rune(normalize(text))
+	marked := matches(runes, QueryTerms(query))
+
+	start := 0
+	for i, m := range marked {
+		if m {
+			start = max(i-min(snippetContext, length/4), 0)
+			break
+		}
+	}
+	end := min(start+length, len(runes))
+
+	s := mark(runes[start:end], marked[start:end], pre, post)
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < len(runes) {
+		s += "..."
+	}
+	return s
+}
+
+// matches 标记文本中被查询词覆盖的字
+func matches(text []rune, terms []string) []bool {
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				for j := i; j < i+len(t); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+	return marked
+}
+
+// mark 转义 HTML, 并使用 pre, post 包裹连续的被标记的字
+func mark(text []rune, marked []bool, pre, post string) string {
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(text[i:j]))
+		if marked[i] {
+			sb.WriteString(pre + segment + post)
+		} else {
+			sb.WriteString(segment)
+		}
+		i = j
+	}
+	return sb.String()
+}
@@ -0,0 +1,149 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25 参数
+const (
+	k1 = 1.2
+	b  = 0.75
+
+	titleBoost = 3 // 标题中的词按出现 3 次计算
+)
+
+// Result 搜索结果, 按 Score 从高到低排序
+type Result struct {
+	ID    int
+	Score float64
+}
+
+type document struct {
+	length int            // 文档长度 (词数)
+	terms  map[string]int // 词 => 词频
+}
+
+// Index 进程内的倒排索引, 使用 BM25 算法对搜索结果评分, 并发安全
+type Index struct {
+	mu          sync.RWMutex
+	docs        map[int]*document
+	postings    map[string]map[int]int // 词 => 文档 id => 词频
+	totalLength int
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[int]*document),
+		postings: make(map[string]map[int]int),
+	}
+}
+
+// Add 添加文档到索引中, 文档已存在时替换
+func (idx *Index) Add(id int, title, content string) {
+	doc := &document{terms: make(map[string]int)}
+	for _, term := range Tokenize(title) {
+		doc.terms[term] += titleBoost
+		doc.length += titleBoost
+	}
+	for _, term := range Tokenize(content) {
+		doc.terms[term]++
+		doc.length++
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+	idx.docs[id] = doc
+	idx.totalLength += doc.length
+	for term, tf := range doc.terms {
+		posting, ok := idx.postings[term]
+		if !ok {
+			posting = make(map[int]int)
+			idx.postings[term] = posting
+		}
+		posting[id] = tf
+	}
+}
+
+// Remove 从索引中删除文档
+func (idx *Index) Remove(id int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id int) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		posting := idx.postings[term]
+		delete(posting, id)
+		if len(posting) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLength -= doc.length
+	delete(idx.docs, id)
+}
+
+// Reset 清空索引
+func (idx *Index) Reset() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs = make(map[int]*document)
+	idx.postings = make(map[string]map[int]int)
+	idx.totalLength = 0
+}
+
+// Len 索引中的文档数量
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search 搜索包含任意查询词的文档, 命中的查询词越多, 词频越高, 得分越高
+func (idx *Index) Search(query string) []Result {
+	terms := QueryTerms(query)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := float64(len(idx.docs))
+	if n == 0 || len(terms) == 0 {
+		return nil
+	}
+	avgLength := float64(idx.totalLength) / n
+
+	scores := make(map[int]float64)
+	for _, term := range terms {
+		posting := idx.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range posting {
+			length := float64(idx.docs[id].length)
+			f := float64(tf)
+			scores[id] += idf * f * (k1 + 1) / (f + k1*(1-b+b*length/avgLength))
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{ID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID > results[j].ID // 得分相同时, 新文章在前
+	})
+	return results
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"go", "语言", "语", "言"}, Tokenize("Go 语言"))
+	assert.Equal(t, []string{"gin", "v1", "框架", "框", "架"}, Tokenize("Gin-v1框架!"))
+}
+
+func TestQueryTerms(t *testing.T) {
+	assert.Equal(t, []string{"gin", "数据", "据库"}, QueryTerms("Gin 数据库 gin"))
+	assert.Equal(t, []string{"书"}, QueryTerms("书"))
+	assert.Empty(t, QueryTerms(" !? "))
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(1, "Gin 入门", "使用 Gin 框架开发 Web 应用")
+	idx.Add(2, "Redis 缓存", "在 Gin 项目中使用 Redis 做缓存")
+	idx.Add(3, "Vue 组件", "前端组件开发")
+
+	results := idx.Search("gin")
+	assert.Len(t, results, 2)
+	assert.Equal(t, 1, results[0].ID) // 标题命中的得分更高
+
+	// 多个查询词, 同时命中的文档排在前面
+	results = idx.Search("gin redis")
+	assert.Equal(t, 2, results[0].ID)
+	assert.Len(t, results, 2)
+
+	assert.Equal(t, 3, idx.Search("前端组件")[0].ID)
+	assert.Empty(t, idx.Search("python"))
+
+	// 更新 / 删除
+	idx.Add(3, "Vue 组件", "Gin 后端配合 Vue")
+	assert.Len(t, idx.Search("gin"), 3)
+	idx.Remove(1)
+	assert.Len(t, idx.Search("gin"), 2)
+	assert.Equal(t, 2, idx.Len())
+
+	idx.Reset()
+	assert.Empty(t, idx.Search("gin"))
+}
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, "<b>Gin</b> &amp; <b>gin</b>", Highlight("Gin & gin", "GIN", "<b>", "</b>"))
+	assert.Equal(t, "使用<b>数据库</b>", Highlight("使用数据库", "数据库", "<b>", "</b>"))
+}
+
+func TestSnippet(t *testing.T) {
+	text := "开头的一段很长很长很长很长很长很长很长很长很长的文字, 这里提到了 Redis 缓存, 后面还有很多内容"
+	s := Snippet(text, "redis", 20, "<b>", "</b>")
+	assert.Contains(t, s, "<b>Redis</b>")
+	assert.True(t, len([]rune(s)) < len([]rune(text)))
+
+	assert.Equal(t, "abc...", Snippet("abcdef", "xyz", 3, "<b>", "</b>"))
+}
@@ -0,0 +1,94 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isCJK 判断是否为中日韩文字, 这些文字之间没有空格分隔, 需要单独切分
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// split 将文本切分为 英文单词/数字 和 连续的中日韩文字 两类片段, 英文转为小写
+func split(text string, fn func(word []rune, cjk bool)) {
+	var word []rune
+	cjk := false
+
+	flush := func() {
+		if len(word) > 0 {
+			fn(word, cjk)
+			word = nil
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+			word = append(word, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			word = append(word, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+}
+
+// Tokenize 索引时的分词
+// 英文/数字按单词切分并转为小写; 中文使用二元切分 (bigram), 同时保留单字以支持单字查询
+// 例如: "Go 语言" => go, 语言, 语, 言
+func Tokenize(text string) []string {
+	tokens := make([]string, 0)
+	split(text, func(word []rune, cjk bool) {
+		if !cjk {
+			tokens = append(tokens, string(word))
+			return
+		}
+		for i := range word {
+			if i+1 < len(word) {
+				tokens = append(tokens, string(word[i:i+2]))
+			}
+			tokens = append(tokens, string(word[i]))
+		}
+	})
+	return tokens
+}
+
+// QueryTerms 查询时的分词 (去重)
+// 中文只使用二元切分, 单个汉字才使用单字, 以提高查询的准确度
+// 例如: "Gin 数据库" => gin, 数据, 据库
+func QueryTerms(query string) []string {
+	terms := make([]string, 0)
+	set := make(map[string]struct{})
+	add := func(term string) {
+		if _, ok := set[term]; !ok {
+			set[term] = struct{}{}
+			terms = append(terms, term)
+		}
+	}
+
+	split(query, func(word []rune, cjk bool) {
+		if !cjk || len(word) == 1 {
+			add(string(word))
+			return
+		}
+		for i := 0; i+1 < len(word); i++ {
+			add(string(word[i : i+2]))
+		}
+	})
+	return terms
+}
+
+// normalize 合并连续的空白字符, 用于生成摘要
+func normalize(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,87 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// MaxURLs 单个 sitemap 文件最多包含的 URL 数量 (sitemaps.org 协议限制)
+const MaxURLs = 50000
+
+const (
+	ContentType = "application/xml; charset=utf-8"
+	xmlns       = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// URL sitemap 中的一个地址, LastMod 为零值时不输出
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type xmlURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	URLs    []xmlURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Sitemaps []xmlURL `xml:"sitemap"`
+}
+
+// URLSet 生成 <urlset> 格式的 sitemap
+func URLSet(urls []URL) ([]byte, error) {
+	return marshal(urlSet{Xmlns: xmlns, URLs: convert(urls)})
+}
+
+// Index 生成 <sitemapindex> 格式的 sitemap 索引, sitemaps 为各个 sitemap 文件的地址
+func Index(sitemaps []URL) ([]byte, error) {
+	return marshal(sitemapIndex{Xmlns: xmlns, Sitemaps: convert(sitemaps)})
+}
+
+// Split 将 URL 按每 size 个切分, 用于生成 sitemap 索引
+func Split(urls []URL, size int) [][]URL {
+	parts := make([][]URL, 0, (len(urls)+size-1)/size)
+	for start := 0; start < len(urls); start += size {
+		parts = append(parts, urls[start:min(start+size, len(urls))])
+	}
+	return parts
+}
+
+// LastMod 获取一组 URL 中最新的修改时间
+func LastMod(urls []URL) time.Time {
+	var t time.Time
+	for _, u := range urls {
+		if u.LastMod.After(t) {
+			t = u.LastMod
+		}
+	}
+	return t
+}
+
+func convert(urls []URL) []xmlURL {
+	list := make([]xmlURL, 0, len(urls))
+	for _, u := range urls {
+		v := xmlURL{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			v.LastMod = u.LastMod.Format(time.RFC3339)
+		}
+		list = append(list, v)
+	}
+	return list
+}
+
+func marshal(v any) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
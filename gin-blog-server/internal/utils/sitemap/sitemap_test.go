@@ -0,0 +1,57 @@
+package sitemap
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestURLSet(t *testing.T) {
+	t1 := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	data, err := URLSet([]URL{
+		{Loc: "https://blog.com/article/go?a=1&b=2", LastMod: t1},
+		{Loc: "https://blog.com/about"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://blog.com/article/go?a=1&amp;b=2</loc>
+    <lastmod>2024-01-02T15:04:05Z</lastmod>
+  </url>
+  <url>
+    <loc>https://blog.com/about</loc>
+  </url>
+</urlset>`, string(data))
+}
+
+func TestIndex(t *testing.T) {
+	data, err := Index([]URL{{Loc: "https://blog.com/sitemap/1.xml"}})
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	assert.Contains(t, string(data), `<sitemap>
+    <loc>https://blog.com/sitemap/1.xml</loc>
+  </sitemap>`)
+}
+
+func TestSplit(t *testing.T) {
+	urls := make([]URL, 5)
+	assert.Equal(t, []int{2, 2, 1}, lens(Split(urls, 2)))
+	assert.Equal(t, []int{5}, lens(Split(urls, 5)))
+	assert.Empty(t, Split(nil, 2))
+}
+
+func TestLastMod(t *testing.T) {
+	t1 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	assert.Equal(t, t2, LastMod([]URL{{LastMod: t1}, {LastMod: t2}, {}}))
+	assert.True(t, LastMod(nil).IsZero())
+}
+
+func lens(parts [][]URL) []int {
+	n := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n = append(n, len(p))
+	}
+	return n
+}
@@ -0,0 +1,69 @@
+package slug
+
+import (
+	"github.com/mozillazg/go-pinyin"
+	"strings"
+	"unicode"
+)
+
+// MaxLength slug 的最大长度
+const MaxLength = 80
+
+var pinyinArgs = pinyin.NewArgs() // 默认配置: 不带声调, 不启用多音字
+
+// Make 生成 URL 友好的 slug, 只包含小写字母, 数字和 -
+// 中文转换为拼音, 每个字之间使用 - 分隔, 其他字符视为分隔符
+// 例如: "Go 语言入门" => "go-yu-yan-ru-men"
+func Make(s string) string {
+	words := make([]string, 0)
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word.WriteRune(unicode.ToLower(r))
+		case unicode.Is(unicode.Han, r):
+			flush()
+			if py := pinyin.SinglePinyin(r, pinyinArgs); len(py) > 0 {
+				words = append(words, strings.ReplaceAll(py[0], "ü", "v"))
+			}
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return truncate(strings.Join(words, "-"), MaxLength)
+}
+
+// truncate 截断 slug 到不超过 n 个字符, 尽量在 - 处截断
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	if i := strings.LastIndex(s, "-"); i > 0 {
+		s = s[:i]
+	}
+	return strings.Trim(s, "-")
+}
+
+// IsNumeric 判断 slug 是否为纯数字, 纯数字的 slug 会与 id 混淆
+func IsNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
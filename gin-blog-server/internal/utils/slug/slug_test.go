@@ -0,0 +1,25 @@
+package slug
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	assert.Equal(t, "hello-world", Make("Hello, World!"))
+	assert.Equal(t, "go-yu-yan-ru-men", Make("Go 语言入门"))
+	assert.Equal(t, "gin-kuang-jia-2024", Make("Gin框架 2024"))
+	assert.Equal(t, "lv-xing", Make("旅行"))
+	assert.Equal(t, "", Make("！？ 🎉"))
+
+	long := Make(strings.Repeat("golang ", 20))
+	assert.LessOrEqual(t, len(long), MaxLength)
+	assert.False(t, strings.HasSuffix(long, "-"))
+}
+
+func TestIsNumeric(t *testing.T) {
+	assert.True(t, IsNumeric("2024"))
+	assert.False(t, IsNumeric("go-2024"))
+	assert.False(t, IsNumeric(""))
+}
@@ -0,0 +1,47 @@
+package totp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// 恢复码: 无法使用认证器时代替验证码登录, 每个恢复码只能使用一次
+// 格式为 xxxxx-xxxxx, 只包含不容易混淆的小写字母和数字, 服务端只保存摘要
+
+const (
+	RecoveryCodeCount = 10
+
+	recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryLength   = 10
+)
+
+// GenerateRecoveryCodes 生成 n 个随机恢复码
+func GenerateRecoveryCodes(n int) []string {
+	codes := make([]string, n)
+	b := make([]byte, recoveryLength)
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		var sb strings.Builder
+		for j, c := range b {
+			if j == recoveryLength/2 {
+				sb.WriteByte('-')
+			}
+			// 256 不是字母表长度的整数倍, 轻微的分布偏差对恢复码可以接受
+			sb.WriteByte(recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+		}
+		codes[i] = sb.String()
+	}
+	return codes
+}
+
+// HashRecoveryCode 恢复码的摘要 (sha256), 忽略大小写, 空格和分隔符
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
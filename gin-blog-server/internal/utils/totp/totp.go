@@ -0,0 +1,120 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 基于时间的一次性密码 (TOTP, RFC 6238), 使用与 Google Authenticator 等应用兼容的参数:
+// HMAC-SHA1, 6 位数字, 时间步长 30 秒
+
+const (
+	Digits = 6
+	Period = 30 // 时间步长 (秒)
+	Skew   = 1  // 验证时允许前后偏差的时间步数, 容忍客户端时钟误差
+
+	secretSize = 20 // 密钥长度 (字节), 与 HMAC-SHA1 的输出长度一致
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret 生成随机密钥, 返回 base32 编码 (无填充), 用于手动输入和 otpauth URI
+func GenerateSecret() string {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b32.EncodeToString(b)
+}
+
+// URI 生成 otpauth URI, 认证器应用扫描该 URI 生成的二维码添加账号
+// 格式: otpauth://totp/<issuer>:<account>?secret=...&issuer=...
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(account)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+	q := url.Values{}
+	q.Set("secret", secret)
+	if issuer != "" {
+		q.Set("issuer", issuer)
+	}
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(Period))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// Step 时间 t 所在的时间步
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code 计算密钥在时间步 step 的验证码
+func Code(secret string, step int64) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(step)), nil
+}
+
+// Validate 验证 t 时刻输入的验证码, 允许前后 Skew 个时间步的偏差
+// 验证通过时返回验证码所在的时间步, 调用方需要拒绝已经使用过的时间步, 防止验证码被重放
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	current := Step(t)
+	for i := -Skew; i <= Skew; i++ {
+		step := current + int64(i)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, uint64(step))), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// hotp HMAC-based 一次性密码 (RFC 4226)
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// 动态截断
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
+
+// decodeSecret 解码 base32 密钥, 忽略大小写, 空格和填充
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("TOTP 密钥格式错误: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,83 @@
+package totp
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 中 SHA1 的测试数据, 取后 6 位
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for ts, want := range cases {
+		code, err := Code(secret, Step(time.Unix(ts, 0)))
+		assert.Nil(t, err)
+		assert.Equal(t, want, code, ts)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := GenerateSecret()
+	now := time.Unix(1700000000, 0)
+
+	code, _ := Code(secret, Step(now))
+	step, ok := Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// 允许前后一个时间步的偏差
+	prev, _ := Code(secret, Step(now)-1)
+	step, ok = Validate(secret, prev, now)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now)-1, step)
+
+	old, _ := Code(secret, Step(now)-2)
+	_, ok = Validate(secret, old, now)
+	assert.False(t, ok)
+
+	// 小写和空格分隔的密钥
+	lower := strings.ToLower(secret[:4] + " " + secret[4:])
+	_, ok = Validate(lower, code, now)
+	assert.True(t, ok)
+
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+	_, ok = Validate("not base32!", code, now)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Gin Blog", "admin@qq.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Gin%20Blog:admin@qq.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Gin+Blog")
+	assert.Contains(t, uri, "digits=6")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes := GenerateRecoveryCodes(RecoveryCodeCount)
+	assert.Len(t, codes, RecoveryCodeCount)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, byte('-'), code[5])
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+
+	// 输入时忽略大小写和分隔符
+	code := codes[0]
+	assert.Equal(t, HashRecoveryCode(code), HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))))
+	assert.NotEqual(t, HashRecoveryCode(codes[0]), HashRecoveryCode(codes[1]))
+}
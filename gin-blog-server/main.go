@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	ginblog "gin-blog-server/internal"
 	"gin-blog-server/internal/global"
 	"gin-blog-server/internal/middleware"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"log"
+	"log/slog"
 	"strings"
 )
 
 func main() {
 	configPath := flag.String("c", "./config.yml", "配置文件路径")
+	syncResource := flag.Bool("sync-resource", false, "同步需要登录的接口路由到资源表, 输出同步结果后退出")
+	rbacExport := flag.String("rbac-export", "", "导出权限配置 (YAML) 到文件, - 表示标准输出, 导出后退出")
+	rbacImport := flag.String("rbac-import", "", "从文件导入权限配置 (YAML), 输出变化后退出")
+	dryRun := flag.Bool("dry-run", false, "配合 -rbac-import 使用, 只输出变化, 不修改数据库")
+	prune := flag.Bool("prune", false, "配合 -rbac-import 使用, 删除配置中没有的菜单, 资源, 角色")
+	rotateJWTKey := flag.Bool("rotate-jwt-key", false, "立即轮换 JWT 签名密钥 (RS256/EdDSA), 输出新密钥的 kid 后退出")
 	flag.Parse()
 
 	// 根据文件路径读取配置文件
@@ -19,8 +30,58 @@ func main() {
 
 	_ = ginblog.InitLogger(conf)
 	db := ginblog.InitDatabase(conf)
+
+	// RS256, EdDSA 签名私钥需要加密保存, 没有安全的加密密钥时无法签发和验证 token
+	if _, err := conf.KeySecret(); err != nil && conf.SigningAlg() != "HS256" {
+		slog.Error("!!! JWT 签名私钥的加密密钥不安全, 拒绝生成和加载签名密钥, 登录将不可用 !!!", "alg", conf.SigningAlg(), "err", err)
+	}
+
+	if *syncResource {
+		gin.SetMode(gin.ReleaseMode)
+		ginblog.RegisterHandlers(gin.New())
+		data, _ := json.MarshalIndent(ginblog.SyncResources(db), "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if *rbacExport != "" {
+		if err := ginblog.ExportRBAC(db, *rbacExport); err != nil {
+			log.Fatal("导出权限配置失败: ", err)
+		}
+		return
+	}
+
+	if *rbacImport != "" {
+		var rdb *redis.Client
+		if !*dryRun {
+			rdb = ginblog.InitRedis(conf)
+		}
+		result, err := ginblog.ImportRBAC(db, rdb, *rbacImport, *dryRun, *prune)
+		if err != nil {
+			log.Fatal("导入权限配置失败: ", err)
+		}
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if *rotateJWTKey {
+		kid, err := ginblog.RotateJWTKey(db)
+		if err != nil {
+			log.Fatal("轮换签名密钥失败: ", err)
+		}
+		fmt.Println(kid)
+		return
+	}
+
+	ginblog.InitSearchIndex(db)
 	rdb := ginblog.InitRedis(conf)
 
+	// 后台定时任务: 文章定时发布/下线等
+	ginblog.StartScheduler(context.Background(), db, rdb)
+	// 订阅权限变化通知
+	ginblog.StartPolicyWatcher(context.Background(), rdb)
+
 	// 初始化 gin 服务
 	gin.SetMode(conf.Server.Mode)
 	r := gin.New()
@@ -39,6 +100,7 @@ func main() {
 	r.Use(middleware.WithCookieStore(conf.Session.Name, conf.Session.Salt))
 
 	ginblog.RegisterHandlers(r)
+	ginblog.SyncResources(db) // 路由没有对应的资源时会跳过鉴权, 启动时同步
 
 	// 使用本地文件上传, 需要静态文件服务, 使用七牛云不需要
 	if conf.Upload.OssType == "local" {
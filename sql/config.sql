@@ -14,3 +14,5 @@ INSERT INTO `config` (`id`, `created_at`, `updated_at`, `key`, `value`, `desc`)
 INSERT INTO `config` (`id`, `created_at`, `updated_at`, `key`, `value`, `desc`) VALUES (14, '2023-12-27 22:40:22.813', '2023-12-27 23:01:35.039', 'is_comment_review', 'true', '评论默认审核');
 INSERT INTO `config` (`id`, `created_at`, `updated_at`, `key`, `value`, `desc`) VALUES (15, '2023-12-27 22:40:22.813', '2023-12-27 23:01:35.017', 'is_message_review', 'true', '留言默认审核');
 INSERT INTO `config` (`id`, `created_at`, `updated_at`, `key`, `value`, `desc`) VALUES (16, '2023-12-27 22:59:20.110', '2023-12-27 23:01:35.035', 'about', '```javascript\nconsole.log(\"Hello World\")\n```\n\n我就是我，不一样的烟火！', '');
+INSERT INTO `config` (`id`, `created_at`, `updated_at`, `key`, `value`, `desc`) VALUES (17, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 'website_url', 'http://localhost:3333', '网站地址');
+INSERT INTO `config` (`id`, `created_at`, `updated_at`, `key`, `value`, `desc`) VALUES (18, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 'robots_txt', 'User-agent: *\nDisallow: /user', 'robots.txt');
@@ -70,3 +70,23 @@ INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `m
 INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (107, '2022-12-16 11:54:20.891', '2022-12-16 11:54:20.891', 106, '/upload', 'POST', '文件上传', 0);
 INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (108, '2022-12-18 01:34:47.800', '2022-12-18 01:34:47.800', 3, '/article/export', 'POST', '导出文章', 0);
 INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (109, '2022-12-18 01:34:59.255', '2022-12-18 01:34:59.255', 3, '/article/import', 'POST', '导入文章', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (110, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 3, '/article/revision/list', 'GET', '文章版本列表', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (111, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 3, '/article/revision/:id', 'GET', '文章版本详情', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (112, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 3, '/article/revision/diff', 'GET', '比较文章版本', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (113, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 3, '/article/revision/restore/:id', 'POST', '恢复文章版本', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (114, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 3, '/article/search-index/rebuild', 'POST', '重建文章全文索引', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (115, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 10, '/resource/sync', 'POST', '同步接口到资源表', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (116, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 8, '/role/explain', 'GET', '权限诊断', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (117, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 8, '/role/menu', 'GET', '模拟用户菜单', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (118, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 8, '/role/export', 'GET', '导出权限配置', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (119, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 8, '/role/import', 'POST', '导入权限配置', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (120, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 74, '/user/2fa', 'GET', '两步验证状态', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (121, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 74, '/user/2fa/setup', 'POST', '生成两步验证密钥', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (122, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 74, '/user/2fa/enable', 'POST', '开启两步验证', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (123, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 74, '/user/2fa/disable', 'POST', '关闭两步验证', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (124, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 74, '/user/2fa/recovery-codes', 'POST', '重新生成恢复码', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (125, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 74, '/user/unlock/:id', 'POST', '解除登录锁定', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (126, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 74, '/user/oauth', 'GET', '第三方账号列表', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (127, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 74, '/user/oauth/:provider', 'POST', '绑定第三方账号', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (128, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 74, '/user/oauth/:provider', 'DELETE', '解绑第三方账号', 0);
+INSERT INTO `resource` (`id`, `created_at`, `updated_at`, `parent_id`, `url`, `method`, `name`, `anonymous`) VALUES (129, '2026-10-18 10:00:00.000', '2026-10-18 10:00:00.000', 11, '/config', 'PATCH', '修改配置', 0);
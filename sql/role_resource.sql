@@ -124,3 +124,32 @@ INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (108, 1);
 INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (108, 2);
 INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (108, 3);
 INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (109, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (110, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (110, 2);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (110, 3);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (111, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (111, 2);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (111, 3);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (112, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (112, 2);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (112, 3);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (113, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (114, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (115, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (116, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (117, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (118, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (119, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (120, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (121, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (122, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (123, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (124, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (125, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (126, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (126, 2);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (127, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (127, 2);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (128, 1);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (128, 2);
+INSERT INTO `role_resource` (`resource_id`, `role_id`) VALUES (129, 1);